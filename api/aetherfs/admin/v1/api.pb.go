@@ -0,0 +1,541 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.17.3
+// source: aetherfs/admin/v1/api.proto
+
+package adminv1
+
+import (
+	v1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// FsckRequest checks the integrity of the hub's storage. Every manifest is read and the blocks they reference are
+// checked for existence and size. Since the check can run against production, requests made to storage are throttled.
+type FsckRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// rehash downloads every referenced block and recomputes its signature.
+	Rehash bool `protobuf:"varint,1,opt,name=rehash,proto3" json:"rehash,omitempty"`
+	// requests_per_second limits the number of requests made to storage. Unlimited when zero.
+	RequestsPerSecond int32 `protobuf:"varint,2,opt,name=requests_per_second,json=requestsPerSecond,proto3" json:"requests_per_second,omitempty"`
+	// bytes_per_second limits the number of bytes read from storage when rehashing. Unlimited when zero.
+	BytesPerSecond int64 `protobuf:"varint,3,opt,name=bytes_per_second,json=bytesPerSecond,proto3" json:"bytes_per_second,omitempty"`
+}
+
+func (x *FsckRequest) Reset() {
+	*x = FsckRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_admin_v1_api_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FsckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FsckRequest) ProtoMessage() {}
+
+func (x *FsckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_admin_v1_api_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FsckRequest.ProtoReflect.Descriptor instead.
+func (*FsckRequest) Descriptor() ([]byte, []int) {
+	return file_aetherfs_admin_v1_api_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FsckRequest) GetRehash() bool {
+	if x != nil {
+		return x.Rehash
+	}
+	return false
+}
+
+func (x *FsckRequest) GetRequestsPerSecond() int32 {
+	if x != nil {
+		return x.RequestsPerSecond
+	}
+	return 0
+}
+
+func (x *FsckRequest) GetBytesPerSecond() int64 {
+	if x != nil {
+		return x.BytesPerSecond
+	}
+	return 0
+}
+
+// BrokenBlock is a block referenced by a manifest that can't be used.
+type BrokenBlock struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Signature string `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+	Reason    string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"` // one of missing, size, or signature
+}
+
+func (x *BrokenBlock) Reset() {
+	*x = BrokenBlock{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_admin_v1_api_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BrokenBlock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BrokenBlock) ProtoMessage() {}
+
+func (x *BrokenBlock) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_admin_v1_api_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BrokenBlock.ProtoReflect.Descriptor instead.
+func (*BrokenBlock) Descriptor() ([]byte, []int) {
+	return file_aetherfs_admin_v1_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BrokenBlock) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *BrokenBlock) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// BrokenDataset is a manifest that can't be read or references blocks that can't be used.
+type BrokenDataset struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Tag    *v1.Tag        `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`       // the version is a digest for manifests that are only addressable by digest
+	Reason string         `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"` // set when the manifest itself can't be read
+	Blocks []*BrokenBlock `protobuf:"bytes,3,rep,name=blocks,proto3" json:"blocks,omitempty"`
+}
+
+func (x *BrokenDataset) Reset() {
+	*x = BrokenDataset{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_admin_v1_api_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BrokenDataset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BrokenDataset) ProtoMessage() {}
+
+func (x *BrokenDataset) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_admin_v1_api_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BrokenDataset.ProtoReflect.Descriptor instead.
+func (*BrokenDataset) Descriptor() ([]byte, []int) {
+	return file_aetherfs_admin_v1_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BrokenDataset) GetTag() *v1.Tag {
+	if x != nil {
+		return x.Tag
+	}
+	return nil
+}
+
+func (x *BrokenDataset) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *BrokenDataset) GetBlocks() []*BrokenBlock {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+// OrphanedBlock is a block that isn't referenced by any manifest. Blocks uploaded by a publish that's still in progress
+// are reported as orphaned until the publish completes.
+type OrphanedBlock struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Signature string `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+	Size      int64  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+}
+
+func (x *OrphanedBlock) Reset() {
+	*x = OrphanedBlock{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_admin_v1_api_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OrphanedBlock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrphanedBlock) ProtoMessage() {}
+
+func (x *OrphanedBlock) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_admin_v1_api_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrphanedBlock.ProtoReflect.Descriptor instead.
+func (*OrphanedBlock) Descriptor() ([]byte, []int) {
+	return file_aetherfs_admin_v1_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *OrphanedBlock) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *OrphanedBlock) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type FsckResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Datasets int64            `protobuf:"varint,1,opt,name=datasets,proto3" json:"datasets,omitempty"` // the number of manifests checked
+	Blocks   int64            `protobuf:"varint,2,opt,name=blocks,proto3" json:"blocks,omitempty"`     // the number of distinct blocks referenced by the manifests
+	Broken   []*BrokenDataset `protobuf:"bytes,3,rep,name=broken,proto3" json:"broken,omitempty"`
+	Orphaned []*OrphanedBlock `protobuf:"bytes,4,rep,name=orphaned,proto3" json:"orphaned,omitempty"`
+	// the patterns of the routes whose storage doesn't support checking, so their datasets and blocks weren't checked.
+	// the default route is reported as "*".
+	Unchecked []string `protobuf:"bytes,5,rep,name=unchecked,proto3" json:"unchecked,omitempty"`
+}
+
+func (x *FsckResponse) Reset() {
+	*x = FsckResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_admin_v1_api_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FsckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FsckResponse) ProtoMessage() {}
+
+func (x *FsckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_admin_v1_api_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FsckResponse.ProtoReflect.Descriptor instead.
+func (*FsckResponse) Descriptor() ([]byte, []int) {
+	return file_aetherfs_admin_v1_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FsckResponse) GetDatasets() int64 {
+	if x != nil {
+		return x.Datasets
+	}
+	return 0
+}
+
+func (x *FsckResponse) GetBlocks() int64 {
+	if x != nil {
+		return x.Blocks
+	}
+	return 0
+}
+
+func (x *FsckResponse) GetBroken() []*BrokenDataset {
+	if x != nil {
+		return x.Broken
+	}
+	return nil
+}
+
+func (x *FsckResponse) GetOrphaned() []*OrphanedBlock {
+	if x != nil {
+		return x.Orphaned
+	}
+	return nil
+}
+
+func (x *FsckResponse) GetUnchecked() []string {
+	if x != nil {
+		return x.Unchecked
+	}
+	return nil
+}
+
+var File_aetherfs_admin_v1_api_proto protoreflect.FileDescriptor
+
+var file_aetherfs_admin_v1_api_proto_rawDesc = []byte{
+	0x0a, 0x1b, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2f, 0x61, 0x64, 0x6d, 0x69, 0x6e,
+	0x2f, 0x76, 0x31, 0x2f, 0x61, 0x70, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x11, 0x61,
+	0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x76, 0x31,
+	0x1a, 0x1d, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x73,
+	0x65, 0x74, 0x2f, 0x76, 0x31, 0x2f, 0x74, 0x61, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
+	0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x6e, 0x6e, 0x6f,
+	0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x7f, 0x0a,
+	0x0b, 0x46, 0x73, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06,
+	0x72, 0x65, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x72, 0x65,
+	0x68, 0x61, 0x73, 0x68, 0x12, 0x2e, 0x0a, 0x13, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73,
+	0x5f, 0x70, 0x65, 0x72, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x11, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x50, 0x65, 0x72, 0x53, 0x65,
+	0x63, 0x6f, 0x6e, 0x64, 0x12, 0x28, 0x0a, 0x10, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x70, 0x65,
+	0x72, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x50, 0x65, 0x72, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x22, 0x43,
+	0x0a, 0x0b, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x6e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x1c, 0x0a,
+	0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x72,
+	0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61,
+	0x73, 0x6f, 0x6e, 0x22, 0x8b, 0x01, 0x0a, 0x0d, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x6e, 0x44, 0x61,
+	0x74, 0x61, 0x73, 0x65, 0x74, 0x12, 0x2a, 0x0a, 0x03, 0x74, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61,
+	0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x67, 0x52, 0x03, 0x74, 0x61,
+	0x67, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x36, 0x0a, 0x06, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x61, 0x65, 0x74, 0x68,
+	0x65, 0x72, 0x66, 0x73, 0x2e, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x72,
+	0x6f, 0x6b, 0x65, 0x6e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x73, 0x22, 0x41, 0x0a, 0x0d, 0x4f, 0x72, 0x70, 0x68, 0x61, 0x6e, 0x65, 0x64, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x22, 0xd8, 0x01, 0x0a, 0x0c, 0x46, 0x73, 0x63, 0x6b, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x38, 0x0a, 0x06, 0x62, 0x72, 0x6f,
+	0x6b, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x61, 0x65, 0x74, 0x68,
+	0x65, 0x72, 0x66, 0x73, 0x2e, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x72,
+	0x6f, 0x6b, 0x65, 0x6e, 0x44, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x52, 0x06, 0x62, 0x72, 0x6f,
+	0x6b, 0x65, 0x6e, 0x12, 0x3c, 0x0a, 0x08, 0x6f, 0x72, 0x70, 0x68, 0x61, 0x6e, 0x65, 0x64, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73,
+	0x2e, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x4f, 0x72, 0x70, 0x68, 0x61, 0x6e,
+	0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x08, 0x6f, 0x72, 0x70, 0x68, 0x61, 0x6e, 0x65,
+	0x64, 0x12, 0x1c, 0x0a, 0x09, 0x75, 0x6e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x65, 0x64, 0x18, 0x05,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x75, 0x6e, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x65, 0x64, 0x32,
+	0x72, 0x0a, 0x08, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x41, 0x50, 0x49, 0x12, 0x66, 0x0a, 0x04, 0x46,
+	0x73, 0x63, 0x6b, 0x12, 0x1e, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x61,
+	0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x73, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x61,
+	0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x73, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x1d, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x17, 0x22, 0x12, 0x2f, 0x61,
+	0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2f, 0x66, 0x73, 0x63, 0x6b,
+	0x3a, 0x01, 0x2a, 0x42, 0x75, 0x0a, 0x16, 0x74, 0x65, 0x63, 0x68, 0x2e, 0x61, 0x65, 0x74, 0x68,
+	0x65, 0x72, 0x66, 0x73, 0x2e, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x76, 0x31, 0x42, 0x08, 0x41,
+	0x50, 0x49, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x6a, 0x70, 0x69, 0x74, 0x7a, 0x2f, 0x61, 0x65, 0x74,
+	0x68, 0x65, 0x72, 0x66, 0x73, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72,
+	0x66, 0x73, 0x2f, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x2f, 0x76, 0x31, 0x3b, 0x61, 0x64, 0x6d, 0x69,
+	0x6e, 0x76, 0x31, 0xa0, 0x01, 0x01, 0xaa, 0x02, 0x11, 0x41, 0x65, 0x74, 0x68, 0x65, 0x72, 0x46,
+	0x53, 0x2e, 0x41, 0x64, 0x6d, 0x69, 0x6e, 0x2e, 0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_aetherfs_admin_v1_api_proto_rawDescOnce sync.Once
+	file_aetherfs_admin_v1_api_proto_rawDescData = file_aetherfs_admin_v1_api_proto_rawDesc
+)
+
+func file_aetherfs_admin_v1_api_proto_rawDescGZIP() []byte {
+	file_aetherfs_admin_v1_api_proto_rawDescOnce.Do(func() {
+		file_aetherfs_admin_v1_api_proto_rawDescData = protoimpl.X.CompressGZIP(file_aetherfs_admin_v1_api_proto_rawDescData)
+	})
+	return file_aetherfs_admin_v1_api_proto_rawDescData
+}
+
+var file_aetherfs_admin_v1_api_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_aetherfs_admin_v1_api_proto_goTypes = []interface{}{
+	(*FsckRequest)(nil),   // 0: aetherfs.admin.v1.FsckRequest
+	(*BrokenBlock)(nil),   // 1: aetherfs.admin.v1.BrokenBlock
+	(*BrokenDataset)(nil), // 2: aetherfs.admin.v1.BrokenDataset
+	(*OrphanedBlock)(nil), // 3: aetherfs.admin.v1.OrphanedBlock
+	(*FsckResponse)(nil),  // 4: aetherfs.admin.v1.FsckResponse
+	(*v1.Tag)(nil),        // 5: aetherfs.dataset.v1.Tag
+}
+var file_aetherfs_admin_v1_api_proto_depIdxs = []int32{
+	5, // 0: aetherfs.admin.v1.BrokenDataset.tag:type_name -> aetherfs.dataset.v1.Tag
+	1, // 1: aetherfs.admin.v1.BrokenDataset.blocks:type_name -> aetherfs.admin.v1.BrokenBlock
+	2, // 2: aetherfs.admin.v1.FsckResponse.broken:type_name -> aetherfs.admin.v1.BrokenDataset
+	3, // 3: aetherfs.admin.v1.FsckResponse.orphaned:type_name -> aetherfs.admin.v1.OrphanedBlock
+	0, // 4: aetherfs.admin.v1.AdminAPI.Fsck:input_type -> aetherfs.admin.v1.FsckRequest
+	4, // 5: aetherfs.admin.v1.AdminAPI.Fsck:output_type -> aetherfs.admin.v1.FsckResponse
+	5, // [5:6] is the sub-list for method output_type
+	4, // [4:5] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_aetherfs_admin_v1_api_proto_init() }
+func file_aetherfs_admin_v1_api_proto_init() {
+	if File_aetherfs_admin_v1_api_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_aetherfs_admin_v1_api_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FsckRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aetherfs_admin_v1_api_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BrokenBlock); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aetherfs_admin_v1_api_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BrokenDataset); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aetherfs_admin_v1_api_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OrphanedBlock); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aetherfs_admin_v1_api_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FsckResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_aetherfs_admin_v1_api_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_aetherfs_admin_v1_api_proto_goTypes,
+		DependencyIndexes: file_aetherfs_admin_v1_api_proto_depIdxs,
+		MessageInfos:      file_aetherfs_admin_v1_api_proto_msgTypes,
+	}.Build()
+	File_aetherfs_admin_v1_api_proto = out.File
+	file_aetherfs_admin_v1_api_proto_rawDesc = nil
+	file_aetherfs_admin_v1_api_proto_goTypes = nil
+	file_aetherfs_admin_v1_api_proto_depIdxs = nil
+}
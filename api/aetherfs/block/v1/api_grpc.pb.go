@@ -19,6 +19,7 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BlockAPIClient interface {
 	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	LookupBatch(ctx context.Context, in *LookupBatchRequest, opts ...grpc.CallOption) (*LookupBatchResponse, error)
 	Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (BlockAPI_DownloadClient, error)
 	Upload(ctx context.Context, opts ...grpc.CallOption) (BlockAPI_UploadClient, error)
 }
@@ -40,6 +41,15 @@ func (c *blockAPIClient) Lookup(ctx context.Context, in *LookupRequest, opts ...
 	return out, nil
 }
 
+func (c *blockAPIClient) LookupBatch(ctx context.Context, in *LookupBatchRequest, opts ...grpc.CallOption) (*LookupBatchResponse, error) {
+	out := new(LookupBatchResponse)
+	err := c.cc.Invoke(ctx, "/aetherfs.block.v1.BlockAPI/LookupBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *blockAPIClient) Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (BlockAPI_DownloadClient, error) {
 	stream, err := c.cc.NewStream(ctx, &BlockAPI_ServiceDesc.Streams[0], "/aetherfs.block.v1.BlockAPI/Download", opts...)
 	if err != nil {
@@ -111,6 +121,7 @@ func (x *blockAPIUploadClient) CloseAndRecv() (*UploadResponse, error) {
 // for forward compatibility
 type BlockAPIServer interface {
 	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	LookupBatch(context.Context, *LookupBatchRequest) (*LookupBatchResponse, error)
 	Download(*DownloadRequest, BlockAPI_DownloadServer) error
 	Upload(BlockAPI_UploadServer) error
 	mustEmbedUnimplementedBlockAPIServer()
@@ -123,6 +134,9 @@ type UnimplementedBlockAPIServer struct {
 func (UnimplementedBlockAPIServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
 }
+func (UnimplementedBlockAPIServer) LookupBatch(context.Context, *LookupBatchRequest) (*LookupBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupBatch not implemented")
+}
 func (UnimplementedBlockAPIServer) Download(*DownloadRequest, BlockAPI_DownloadServer) error {
 	return status.Errorf(codes.Unimplemented, "method Download not implemented")
 }
@@ -160,6 +174,24 @@ func _BlockAPI_Lookup_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BlockAPI_LookupBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockAPIServer).LookupBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aetherfs.block.v1.BlockAPI/LookupBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockAPIServer).LookupBatch(ctx, req.(*LookupBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _BlockAPI_Download_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(DownloadRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -218,6 +250,10 @@ var BlockAPI_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Lookup",
 			Handler:    _BlockAPI_Lookup_Handler,
 		},
+		{
+			MethodName: "LookupBatch",
+			Handler:    _BlockAPI_LookupBatch_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
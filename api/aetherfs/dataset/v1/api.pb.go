@@ -254,7 +254,8 @@ func (x *ListTagsResponse) GetTags() []*Tag {
 	return nil
 }
 
-// LookupRequest resolves the dataset information for a given tag.
+// LookupRequest resolves the dataset information for a given tag. The version of the tag may also be a digest
+// (sha256:<hex>) to resolve an immutable version of the dataset.
 type LookupRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -308,6 +309,7 @@ type LookupResponse struct {
 	unknownFields protoimpl.UnknownFields
 
 	Dataset *Dataset `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`
+	Digest  string   `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"` // the content digest of the dataset manifest (sha256:<hex>).
 }
 
 func (x *LookupResponse) Reset() {
@@ -349,7 +351,14 @@ func (x *LookupResponse) GetDataset() *Dataset {
 	return nil
 }
 
-// PublishRequest updates all provided tags to use the new version of dataset.
+func (x *LookupResponse) GetDigest() string {
+	if x != nil {
+		return x.Digest
+	}
+	return ""
+}
+
+// PublishRequest updates all provided tags to use the new version of dataset. Either every tag is updated or none are.
 type PublishRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -357,6 +366,12 @@ type PublishRequest struct {
 
 	Dataset *Dataset `protobuf:"bytes,1,opt,name=dataset,proto3" json:"dataset,omitempty"`
 	Tags    []*Tag   `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	// the digests the tags (name:version) are expected to point at before the publish. the publish fails without
+	// updating any tags when one has moved on. an empty digest requires the tag to not exist yet. the s3 driver can only
+	// check and update tags atomically within a single hub process: hubs sharing a bucket can each pass the check and
+	// overwrite one another, and a hub that crashes part way through a publish can leave some of its tags updated. run a
+	// single hub per bucket when relying on this.
+	ExpectedPrevious map[string]string `protobuf:"bytes,3,rep,name=expected_previous,json=expectedPrevious,proto3" json:"expected_previous,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (x *PublishRequest) Reset() {
@@ -405,6 +420,13 @@ func (x *PublishRequest) GetTags() []*Tag {
 	return nil
 }
 
+func (x *PublishRequest) GetExpectedPrevious() map[string]string {
+	if x != nil {
+		return x.ExpectedPrevious
+	}
+	return nil
+}
+
 // PublishResponse
 type PublishResponse struct {
 	state         protoimpl.MessageState
@@ -444,6 +466,336 @@ func (*PublishResponse) Descriptor() ([]byte, []int) {
 	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{7}
 }
 
+// DiffRequest compares the dataset tagged by base against the dataset tagged by target. Either version may be a digest.
+type DiffRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Base   *Tag `protobuf:"bytes,1,opt,name=base,proto3" json:"base,omitempty"`
+	Target *Tag `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+}
+
+func (x *DiffRequest) Reset() {
+	*x = DiffRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DiffRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffRequest) ProtoMessage() {}
+
+func (x *DiffRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffRequest.ProtoReflect.Descriptor instead.
+func (*DiffRequest) Descriptor() ([]byte, []int) {
+	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DiffRequest) GetBase() *Tag {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+func (x *DiffRequest) GetTarget() *Tag {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+// ResizedFile describes a file whose size differs between two datasets.
+type ResizedFile struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name       string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	BaseSize   int64  `protobuf:"varint,2,opt,name=base_size,json=baseSize,proto3" json:"base_size,omitempty"`
+	TargetSize int64  `protobuf:"varint,3,opt,name=target_size,json=targetSize,proto3" json:"target_size,omitempty"`
+}
+
+func (x *ResizedFile) Reset() {
+	*x = ResizedFile{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResizedFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResizedFile) ProtoMessage() {}
+
+func (x *ResizedFile) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResizedFile.ProtoReflect.Descriptor instead.
+func (*ResizedFile) Descriptor() ([]byte, []int) {
+	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ResizedFile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ResizedFile) GetBaseSize() int64 {
+	if x != nil {
+		return x.BaseSize
+	}
+	return 0
+}
+
+func (x *ResizedFile) GetTargetSize() int64 {
+	if x != nil {
+		return x.TargetSize
+	}
+	return 0
+}
+
+// BlockOverlap describes how many blocks two datasets have in common. Blocks are counted once, no matter how many times
+// they're referenced.
+type BlockOverlap struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Shared       int64   `protobuf:"varint,1,opt,name=shared,proto3" json:"shared,omitempty"`   // the number of blocks in both datasets
+	Added        int64   `protobuf:"varint,2,opt,name=added,proto3" json:"added,omitempty"`     // the number of blocks only in the target
+	Removed      int64   `protobuf:"varint,3,opt,name=removed,proto3" json:"removed,omitempty"` // the number of blocks only in the base
+	SharedBytes  int64   `protobuf:"varint,4,opt,name=shared_bytes,json=sharedBytes,proto3" json:"shared_bytes,omitempty"`
+	AddedBytes   int64   `protobuf:"varint,5,opt,name=added_bytes,json=addedBytes,proto3" json:"added_bytes,omitempty"`
+	RemovedBytes int64   `protobuf:"varint,6,opt,name=removed_bytes,json=removedBytes,proto3" json:"removed_bytes,omitempty"`
+	Ratio        float64 `protobuf:"fixed64,7,opt,name=ratio,proto3" json:"ratio,omitempty"` // the fraction of the target's bytes already in the base (0.0 - 1.0)
+}
+
+func (x *BlockOverlap) Reset() {
+	*x = BlockOverlap{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockOverlap) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockOverlap) ProtoMessage() {}
+
+func (x *BlockOverlap) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockOverlap.ProtoReflect.Descriptor instead.
+func (*BlockOverlap) Descriptor() ([]byte, []int) {
+	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BlockOverlap) GetShared() int64 {
+	if x != nil {
+		return x.Shared
+	}
+	return 0
+}
+
+func (x *BlockOverlap) GetAdded() int64 {
+	if x != nil {
+		return x.Added
+	}
+	return 0
+}
+
+func (x *BlockOverlap) GetRemoved() int64 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+func (x *BlockOverlap) GetSharedBytes() int64 {
+	if x != nil {
+		return x.SharedBytes
+	}
+	return 0
+}
+
+func (x *BlockOverlap) GetAddedBytes() int64 {
+	if x != nil {
+		return x.AddedBytes
+	}
+	return 0
+}
+
+func (x *BlockOverlap) GetRemovedBytes() int64 {
+	if x != nil {
+		return x.RemovedBytes
+	}
+	return 0
+}
+
+func (x *BlockOverlap) GetRatio() float64 {
+	if x != nil {
+		return x.Ratio
+	}
+	return 0
+}
+
+// DiffResponse reports the differences between two datasets. Blocks span file boundaries, so when a file shares blocks
+// with other files or moved relative to the block boundaries, its contents can't be compared from the manifests alone.
+type DiffResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BaseDigest   string         `protobuf:"bytes,1,opt,name=base_digest,json=baseDigest,proto3" json:"base_digest,omitempty"`
+	TargetDigest string         `protobuf:"bytes,2,opt,name=target_digest,json=targetDigest,proto3" json:"target_digest,omitempty"`
+	Added        []string       `protobuf:"bytes,3,rep,name=added,proto3" json:"added,omitempty"`           // files only in the target
+	Removed      []string       `protobuf:"bytes,4,rep,name=removed,proto3" json:"removed,omitempty"`       // files only in the base
+	Resized      []*ResizedFile `protobuf:"bytes,5,rep,name=resized,proto3" json:"resized,omitempty"`       // files whose size changed
+	Changed      []string       `protobuf:"bytes,6,rep,name=changed,proto3" json:"changed,omitempty"`       // files of the same size whose contents changed
+	Unverified   []string       `protobuf:"bytes,7,rep,name=unverified,proto3" json:"unverified,omitempty"` // files of the same size whose contents could not be compared
+	Unchanged    int64          `protobuf:"varint,8,opt,name=unchanged,proto3" json:"unchanged,omitempty"`  // the number of files whose contents are identical
+	Blocks       *BlockOverlap  `protobuf:"bytes,9,opt,name=blocks,proto3" json:"blocks,omitempty"`
+}
+
+func (x *DiffResponse) Reset() {
+	*x = DiffResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DiffResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffResponse) ProtoMessage() {}
+
+func (x *DiffResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffResponse.ProtoReflect.Descriptor instead.
+func (*DiffResponse) Descriptor() ([]byte, []int) {
+	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DiffResponse) GetBaseDigest() string {
+	if x != nil {
+		return x.BaseDigest
+	}
+	return ""
+}
+
+func (x *DiffResponse) GetTargetDigest() string {
+	if x != nil {
+		return x.TargetDigest
+	}
+	return ""
+}
+
+func (x *DiffResponse) GetAdded() []string {
+	if x != nil {
+		return x.Added
+	}
+	return nil
+}
+
+func (x *DiffResponse) GetRemoved() []string {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+func (x *DiffResponse) GetResized() []*ResizedFile {
+	if x != nil {
+		return x.Resized
+	}
+	return nil
+}
+
+func (x *DiffResponse) GetChanged() []string {
+	if x != nil {
+		return x.Changed
+	}
+	return nil
+}
+
+func (x *DiffResponse) GetUnverified() []string {
+	if x != nil {
+		return x.Unverified
+	}
+	return nil
+}
+
+func (x *DiffResponse) GetUnchanged() int64 {
+	if x != nil {
+		return x.Unchanged
+	}
+	return 0
+}
+
+func (x *DiffResponse) GetBlocks() *BlockOverlap {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
 // SubscribeRequest instructs the agent to subscribe to a dataset at some scope.
 type SubscribeRequest struct {
 	state         protoimpl.MessageState
@@ -457,7 +809,7 @@ type SubscribeRequest struct {
 func (x *SubscribeRequest) Reset() {
 	*x = SubscribeRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[8]
+		mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -470,7 +822,7 @@ func (x *SubscribeRequest) String() string {
 func (*SubscribeRequest) ProtoMessage() {}
 
 func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[8]
+	mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -483,7 +835,7 @@ func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
 func (*SubscribeRequest) Descriptor() ([]byte, []int) {
-	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{8}
+	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *SubscribeRequest) GetTag() *Tag {
@@ -513,7 +865,7 @@ type SubscribeResponse struct {
 func (x *SubscribeResponse) Reset() {
 	*x = SubscribeResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[9]
+		mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -526,7 +878,7 @@ func (x *SubscribeResponse) String() string {
 func (*SubscribeResponse) ProtoMessage() {}
 
 func (x *SubscribeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[9]
+	mi := &file_aetherfs_dataset_v1_api_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -539,7 +891,7 @@ func (x *SubscribeResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SubscribeResponse.ProtoReflect.Descriptor instead.
 func (*SubscribeResponse) Descriptor() ([]byte, []int) {
-	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{9}
+	return file_aetherfs_dataset_v1_api_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *SubscribeResponse) GetTag() *Tag {
@@ -596,79 +948,146 @@ var file_aetherfs_dataset_v1_api_proto_rawDesc = []byte{
 	0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2a, 0x0a, 0x03, 0x74,
 	0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65,
 	0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54,
-	0x61, 0x67, 0x52, 0x03, 0x74, 0x61, 0x67, 0x22, 0x48, 0x0a, 0x0e, 0x4c, 0x6f, 0x6f, 0x6b, 0x75,
+	0x61, 0x67, 0x52, 0x03, 0x74, 0x61, 0x67, 0x22, 0x60, 0x0a, 0x0e, 0x4c, 0x6f, 0x6f, 0x6b, 0x75,
 	0x70, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x36, 0x0a, 0x07, 0x64, 0x61, 0x74,
 	0x61, 0x73, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x61, 0x65, 0x74,
 	0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31,
 	0x2e, 0x44, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65,
-	0x74, 0x22, 0x76, 0x0a, 0x0e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x12, 0x36, 0x0a, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e,
-	0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x73,
-	0x65, 0x74, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x12, 0x2c, 0x0a, 0x04, 0x74,
-	0x61, 0x67, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x65, 0x74, 0x68,
-	0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e,
-	0x54, 0x61, 0x67, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x22, 0x11, 0x0a, 0x0f, 0x50, 0x75, 0x62,
-	0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x56, 0x0a, 0x10,
-	0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x12, 0x2a, 0x0a, 0x03, 0x74, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e,
+	0x74, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x22, 0xa3, 0x02, 0x0a, 0x0e, 0x50, 0x75,
+	0x62, 0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x36, 0x0a, 0x07,
+	0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e,
 	0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74,
-	0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x67, 0x52, 0x03, 0x74, 0x61, 0x67, 0x12, 0x16, 0x0a, 0x06,
-	0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x63, 0x61,
-	0x6e, 0x63, 0x65, 0x6c, 0x22, 0x77, 0x0a, 0x11, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62,
-	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a, 0x03, 0x74, 0x61, 0x67,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66,
-	0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x67,
-	0x52, 0x03, 0x74, 0x61, 0x67, 0x12, 0x36, 0x0a, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66,
-	0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x61, 0x74,
-	0x61, 0x73, 0x65, 0x74, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x32, 0xd3, 0x04,
-	0x0a, 0x0a, 0x44, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x41, 0x50, 0x49, 0x12, 0x65, 0x0a, 0x04,
-	0x4c, 0x69, 0x73, 0x74, 0x12, 0x20, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e,
-	0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66,
-	0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73,
-	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x18, 0x82, 0xd3, 0xe4, 0x93, 0x02,
-	0x12, 0x12, 0x10, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x73,
-	0x65, 0x74, 0x73, 0x12, 0x7d, 0x0a, 0x08, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x61, 0x67, 0x73, 0x12,
-	0x24, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73,
-	0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x61, 0x67, 0x73, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73,
-	0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74,
-	0x54, 0x61, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x24, 0x82, 0xd3,
-	0xe4, 0x93, 0x02, 0x1e, 0x12, 0x1c, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x61,
-	0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x2f, 0x7b, 0x6e, 0x61, 0x6d, 0x65, 0x7d, 0x2f, 0x74, 0x61,
-	0x67, 0x73, 0x12, 0x89, 0x01, 0x0a, 0x06, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x12, 0x22, 0x2e,
+	0x2e, 0x76, 0x31, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x52, 0x07, 0x64, 0x61, 0x74,
+	0x61, 0x73, 0x65, 0x74, 0x12, 0x2c, 0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61,
+	0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x67, 0x52, 0x04, 0x74, 0x61,
+	0x67, 0x73, 0x12, 0x66, 0x0a, 0x11, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x70,
+	0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x39, 0x2e,
 	0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74,
-	0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x23, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74,
-	0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x36, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x30, 0x12, 0x2e,
-	0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73,
-	0x2f, 0x7b, 0x74, 0x61, 0x67, 0x2e, 0x6e, 0x61, 0x6d, 0x65, 0x7d, 0x2f, 0x74, 0x61, 0x67, 0x73,
-	0x2f, 0x7b, 0x74, 0x61, 0x67, 0x2e, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x7d, 0x12, 0x71,
-	0x0a, 0x07, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x12, 0x23, 0x2e, 0x61, 0x65, 0x74, 0x68,
+	0x2e, 0x76, 0x31, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x2e, 0x45, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x50, 0x72, 0x65, 0x76, 0x69,
+	0x6f, 0x75, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x10, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74,
+	0x65, 0x64, 0x50, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x1a, 0x43, 0x0a, 0x15, 0x45, 0x78,
+	0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x50, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22,
+	0x11, 0x0a, 0x0f, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x6d, 0x0a, 0x0b, 0x44, 0x69, 0x66, 0x66, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x2c, 0x0a, 0x04, 0x62, 0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x18, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73,
+	0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x67, 0x52, 0x04, 0x62, 0x61, 0x73, 0x65, 0x12,
+	0x30, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x18, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73,
+	0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x67, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x22, 0x5f, 0x0a, 0x0b, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x62, 0x61, 0x73, 0x65, 0x53, 0x69, 0x7a,
+	0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x73, 0x69, 0x7a, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x53, 0x69,
+	0x7a, 0x65, 0x22, 0xd5, 0x01, 0x0a, 0x0c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x4f, 0x76, 0x65, 0x72,
+	0x6c, 0x61, 0x70, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x72, 0x65, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x06, 0x73, 0x68, 0x61, 0x72, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x61,
+	0x64, 0x64, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x61, 0x64, 0x64, 0x65,
+	0x64, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x73,
+	0x68, 0x61, 0x72, 0x65, 0x64, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0b, 0x73, 0x68, 0x61, 0x72, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1f,
+	0x0a, 0x0b, 0x61, 0x64, 0x64, 0x65, 0x64, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0a, 0x61, 0x64, 0x64, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12,
+	0x23, 0x0a, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x42,
+	0x79, 0x74, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x05, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x22, 0xd3, 0x02, 0x0a, 0x0c, 0x44,
+	0x69, 0x66, 0x66, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x62,
+	0x61, 0x73, 0x65, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x62, 0x61, 0x73, 0x65, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x12, 0x23, 0x0a, 0x0d,
+	0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0c, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x44, 0x69, 0x67, 0x65, 0x73,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x64, 0x64, 0x65, 0x64, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x05, 0x61, 0x64, 0x64, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76,
+	0x65, 0x64, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65,
+	0x64, 0x12, 0x3a, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x64, 0x18, 0x05, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x20, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61,
+	0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x64,
+	0x46, 0x69, 0x6c, 0x65, 0x52, 0x07, 0x72, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x64, 0x12, 0x18, 0x0a,
+	0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x18, 0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07,
+	0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x75, 0x6e, 0x76, 0x65, 0x72,
+	0x69, 0x66, 0x69, 0x65, 0x64, 0x18, 0x07, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x75, 0x6e, 0x76,
+	0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x75, 0x6e, 0x63, 0x68, 0x61,
+	0x6e, 0x67, 0x65, 0x64, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x75, 0x6e, 0x63, 0x68,
+	0x61, 0x6e, 0x67, 0x65, 0x64, 0x12, 0x39, 0x0a, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73,
+	0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f, 0x63,
+	0x6b, 0x4f, 0x76, 0x65, 0x72, 0x6c, 0x61, 0x70, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73,
+	0x22, 0x56, 0x0a, 0x10, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x2a, 0x0a, 0x03, 0x74, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x18, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74,
+	0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x61, 0x67, 0x52, 0x03, 0x74, 0x61, 0x67,
+	0x12, 0x16, 0x0a, 0x06, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x06, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x22, 0x77, 0x0a, 0x11, 0x53, 0x75, 0x62, 0x73,
+	0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a,
+	0x03, 0x74, 0x61, 0x67, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x61, 0x65, 0x74,
+	0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x54, 0x61, 0x67, 0x52, 0x03, 0x74, 0x61, 0x67, 0x12, 0x36, 0x0a, 0x07, 0x64, 0x61, 0x74,
+	0x61, 0x73, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x61, 0x65, 0x74,
+	0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x44, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x52, 0x07, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65,
+	0x74, 0x32, 0xc2, 0x05, 0x0a, 0x0a, 0x44, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x41, 0x50, 0x49,
+	0x12, 0x65, 0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x20, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65,
+	0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c,
+	0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x61, 0x65, 0x74,
+	0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x18, 0x82,
+	0xd3, 0xe4, 0x93, 0x02, 0x12, 0x12, 0x10, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x64,
+	0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x12, 0x7d, 0x0a, 0x08, 0x4c, 0x69, 0x73, 0x74, 0x54,
+	0x61, 0x67, 0x73, 0x12, 0x24, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64,
+	0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x54, 0x61,
+	0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x61, 0x65, 0x74, 0x68,
 	0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e,
-	0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x24,
-	0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65,
-	0x74, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x1b, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x15, 0x22, 0x10, 0x2f, 0x61,
-	0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x3a, 0x01,
-	0x2a, 0x12, 0x60, 0x0a, 0x09, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x12, 0x25,
-	0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65,
-	0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73,
-	0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x75, 0x62, 0x73,
-	0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x28,
-	0x01, 0x30, 0x01, 0x42, 0x7d, 0x0a, 0x18, 0x74, 0x65, 0x63, 0x68, 0x2e, 0x61, 0x65, 0x74, 0x68,
-	0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x42,
-	0x08, 0x41, 0x50, 0x49, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x3c, 0x67, 0x69, 0x74,
-	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x6a, 0x70, 0x69, 0x74, 0x7a, 0x2f, 0x61,
-	0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x65, 0x74, 0x68,
-	0x65, 0x72, 0x66, 0x73, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2f, 0x76, 0x31, 0x3b,
-	0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x76, 0x31, 0xa0, 0x01, 0x01, 0xaa, 0x02, 0x13, 0x41,
-	0x65, 0x74, 0x68, 0x65, 0x72, 0x46, 0x53, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e,
-	0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x4c, 0x69, 0x73, 0x74, 0x54, 0x61, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x24, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x1e, 0x12, 0x1c, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76,
+	0x31, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x2f, 0x7b, 0x6e, 0x61, 0x6d, 0x65,
+	0x7d, 0x2f, 0x74, 0x61, 0x67, 0x73, 0x12, 0x89, 0x01, 0x0a, 0x06, 0x4c, 0x6f, 0x6f, 0x6b, 0x75,
+	0x70, 0x12, 0x22, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74,
+	0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x6f, 0x6b, 0x75, 0x70, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73,
+	0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x6f, 0x6f, 0x6b,
+	0x75, 0x70, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x36, 0x82, 0xd3, 0xe4, 0x93,
+	0x02, 0x30, 0x12, 0x2e, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x61, 0x74, 0x61,
+	0x73, 0x65, 0x74, 0x73, 0x2f, 0x7b, 0x74, 0x61, 0x67, 0x2e, 0x6e, 0x61, 0x6d, 0x65, 0x7d, 0x2f,
+	0x74, 0x61, 0x67, 0x73, 0x2f, 0x7b, 0x74, 0x61, 0x67, 0x2e, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x7d, 0x12, 0x6d, 0x0a, 0x04, 0x44, 0x69, 0x66, 0x66, 0x12, 0x20, 0x2e, 0x61, 0x65, 0x74,
+	0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31,
+	0x2e, 0x44, 0x69, 0x66, 0x66, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x61,
+	0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e,
+	0x76, 0x31, 0x2e, 0x44, 0x69, 0x66, 0x66, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x20, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x1a, 0x22, 0x15, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31,
+	0x2f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x73, 0x2f, 0x64, 0x69, 0x66, 0x66, 0x3a, 0x01,
+	0x2a, 0x12, 0x71, 0x0a, 0x07, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x12, 0x23, 0x2e, 0x61,
+	0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e,
+	0x76, 0x31, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x24, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74,
+	0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x1b, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x15, 0x22,
+	0x10, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74,
+	0x73, 0x3a, 0x01, 0x2a, 0x12, 0x60, 0x0a, 0x09, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62,
+	0x65, 0x12, 0x25, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74,
+	0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x61, 0x65, 0x74, 0x68, 0x65,
+	0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x28, 0x01, 0x30, 0x01, 0x42, 0x7d, 0x0a, 0x18, 0x74, 0x65, 0x63, 0x68, 0x2e, 0x61,
+	0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2e, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2e,
+	0x76, 0x31, 0x42, 0x08, 0x41, 0x50, 0x49, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x3c,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x6a, 0x70, 0x69, 0x74,
+	0x7a, 0x2f, 0x61, 0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61,
+	0x65, 0x74, 0x68, 0x65, 0x72, 0x66, 0x73, 0x2f, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x2f,
+	0x76, 0x31, 0x3b, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65, 0x74, 0x76, 0x31, 0xa0, 0x01, 0x01, 0xaa,
+	0x02, 0x13, 0x41, 0x65, 0x74, 0x68, 0x65, 0x72, 0x46, 0x53, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x73,
+	0x65, 0x74, 0x2e, 0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -683,7 +1102,7 @@ func file_aetherfs_dataset_v1_api_proto_rawDescGZIP() []byte {
 	return file_aetherfs_dataset_v1_api_proto_rawDescData
 }
 
-var file_aetherfs_dataset_v1_api_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_aetherfs_dataset_v1_api_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_aetherfs_dataset_v1_api_proto_goTypes = []interface{}{
 	(*ListRequest)(nil),       // 0: aetherfs.dataset.v1.ListRequest
 	(*ListResponse)(nil),      // 1: aetherfs.dataset.v1.ListResponse
@@ -693,36 +1112,48 @@ var file_aetherfs_dataset_v1_api_proto_goTypes = []interface{}{
 	(*LookupResponse)(nil),    // 5: aetherfs.dataset.v1.LookupResponse
 	(*PublishRequest)(nil),    // 6: aetherfs.dataset.v1.PublishRequest
 	(*PublishResponse)(nil),   // 7: aetherfs.dataset.v1.PublishResponse
-	(*SubscribeRequest)(nil),  // 8: aetherfs.dataset.v1.SubscribeRequest
-	(*SubscribeResponse)(nil), // 9: aetherfs.dataset.v1.SubscribeResponse
-	(*Tag)(nil),               // 10: aetherfs.dataset.v1.Tag
-	(*Dataset)(nil),           // 11: aetherfs.dataset.v1.Dataset
+	(*DiffRequest)(nil),       // 8: aetherfs.dataset.v1.DiffRequest
+	(*ResizedFile)(nil),       // 9: aetherfs.dataset.v1.ResizedFile
+	(*BlockOverlap)(nil),      // 10: aetherfs.dataset.v1.BlockOverlap
+	(*DiffResponse)(nil),      // 11: aetherfs.dataset.v1.DiffResponse
+	(*SubscribeRequest)(nil),  // 12: aetherfs.dataset.v1.SubscribeRequest
+	(*SubscribeResponse)(nil), // 13: aetherfs.dataset.v1.SubscribeResponse
+	nil,                       // 14: aetherfs.dataset.v1.PublishRequest.ExpectedPreviousEntry
+	(*Tag)(nil),               // 15: aetherfs.dataset.v1.Tag
+	(*Dataset)(nil),           // 16: aetherfs.dataset.v1.Dataset
 }
 var file_aetherfs_dataset_v1_api_proto_depIdxs = []int32{
-	10, // 0: aetherfs.dataset.v1.ListResponse.datasets:type_name -> aetherfs.dataset.v1.Tag
-	10, // 1: aetherfs.dataset.v1.ListTagsResponse.tags:type_name -> aetherfs.dataset.v1.Tag
-	10, // 2: aetherfs.dataset.v1.LookupRequest.tag:type_name -> aetherfs.dataset.v1.Tag
-	11, // 3: aetherfs.dataset.v1.LookupResponse.dataset:type_name -> aetherfs.dataset.v1.Dataset
-	11, // 4: aetherfs.dataset.v1.PublishRequest.dataset:type_name -> aetherfs.dataset.v1.Dataset
-	10, // 5: aetherfs.dataset.v1.PublishRequest.tags:type_name -> aetherfs.dataset.v1.Tag
-	10, // 6: aetherfs.dataset.v1.SubscribeRequest.tag:type_name -> aetherfs.dataset.v1.Tag
-	10, // 7: aetherfs.dataset.v1.SubscribeResponse.tag:type_name -> aetherfs.dataset.v1.Tag
-	11, // 8: aetherfs.dataset.v1.SubscribeResponse.dataset:type_name -> aetherfs.dataset.v1.Dataset
-	0,  // 9: aetherfs.dataset.v1.DatasetAPI.List:input_type -> aetherfs.dataset.v1.ListRequest
-	2,  // 10: aetherfs.dataset.v1.DatasetAPI.ListTags:input_type -> aetherfs.dataset.v1.ListTagsRequest
-	4,  // 11: aetherfs.dataset.v1.DatasetAPI.Lookup:input_type -> aetherfs.dataset.v1.LookupRequest
-	6,  // 12: aetherfs.dataset.v1.DatasetAPI.Publish:input_type -> aetherfs.dataset.v1.PublishRequest
-	8,  // 13: aetherfs.dataset.v1.DatasetAPI.Subscribe:input_type -> aetherfs.dataset.v1.SubscribeRequest
-	1,  // 14: aetherfs.dataset.v1.DatasetAPI.List:output_type -> aetherfs.dataset.v1.ListResponse
-	3,  // 15: aetherfs.dataset.v1.DatasetAPI.ListTags:output_type -> aetherfs.dataset.v1.ListTagsResponse
-	5,  // 16: aetherfs.dataset.v1.DatasetAPI.Lookup:output_type -> aetherfs.dataset.v1.LookupResponse
-	7,  // 17: aetherfs.dataset.v1.DatasetAPI.Publish:output_type -> aetherfs.dataset.v1.PublishResponse
-	9,  // 18: aetherfs.dataset.v1.DatasetAPI.Subscribe:output_type -> aetherfs.dataset.v1.SubscribeResponse
-	14, // [14:19] is the sub-list for method output_type
-	9,  // [9:14] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+	15, // 0: aetherfs.dataset.v1.ListResponse.datasets:type_name -> aetherfs.dataset.v1.Tag
+	15, // 1: aetherfs.dataset.v1.ListTagsResponse.tags:type_name -> aetherfs.dataset.v1.Tag
+	15, // 2: aetherfs.dataset.v1.LookupRequest.tag:type_name -> aetherfs.dataset.v1.Tag
+	16, // 3: aetherfs.dataset.v1.LookupResponse.dataset:type_name -> aetherfs.dataset.v1.Dataset
+	16, // 4: aetherfs.dataset.v1.PublishRequest.dataset:type_name -> aetherfs.dataset.v1.Dataset
+	15, // 5: aetherfs.dataset.v1.PublishRequest.tags:type_name -> aetherfs.dataset.v1.Tag
+	14, // 6: aetherfs.dataset.v1.PublishRequest.expected_previous:type_name -> aetherfs.dataset.v1.PublishRequest.ExpectedPreviousEntry
+	15, // 7: aetherfs.dataset.v1.DiffRequest.base:type_name -> aetherfs.dataset.v1.Tag
+	15, // 8: aetherfs.dataset.v1.DiffRequest.target:type_name -> aetherfs.dataset.v1.Tag
+	9,  // 9: aetherfs.dataset.v1.DiffResponse.resized:type_name -> aetherfs.dataset.v1.ResizedFile
+	10, // 10: aetherfs.dataset.v1.DiffResponse.blocks:type_name -> aetherfs.dataset.v1.BlockOverlap
+	15, // 11: aetherfs.dataset.v1.SubscribeRequest.tag:type_name -> aetherfs.dataset.v1.Tag
+	15, // 12: aetherfs.dataset.v1.SubscribeResponse.tag:type_name -> aetherfs.dataset.v1.Tag
+	16, // 13: aetherfs.dataset.v1.SubscribeResponse.dataset:type_name -> aetherfs.dataset.v1.Dataset
+	0,  // 14: aetherfs.dataset.v1.DatasetAPI.List:input_type -> aetherfs.dataset.v1.ListRequest
+	2,  // 15: aetherfs.dataset.v1.DatasetAPI.ListTags:input_type -> aetherfs.dataset.v1.ListTagsRequest
+	4,  // 16: aetherfs.dataset.v1.DatasetAPI.Lookup:input_type -> aetherfs.dataset.v1.LookupRequest
+	8,  // 17: aetherfs.dataset.v1.DatasetAPI.Diff:input_type -> aetherfs.dataset.v1.DiffRequest
+	6,  // 18: aetherfs.dataset.v1.DatasetAPI.Publish:input_type -> aetherfs.dataset.v1.PublishRequest
+	12, // 19: aetherfs.dataset.v1.DatasetAPI.Subscribe:input_type -> aetherfs.dataset.v1.SubscribeRequest
+	1,  // 20: aetherfs.dataset.v1.DatasetAPI.List:output_type -> aetherfs.dataset.v1.ListResponse
+	3,  // 21: aetherfs.dataset.v1.DatasetAPI.ListTags:output_type -> aetherfs.dataset.v1.ListTagsResponse
+	5,  // 22: aetherfs.dataset.v1.DatasetAPI.Lookup:output_type -> aetherfs.dataset.v1.LookupResponse
+	11, // 23: aetherfs.dataset.v1.DatasetAPI.Diff:output_type -> aetherfs.dataset.v1.DiffResponse
+	7,  // 24: aetherfs.dataset.v1.DatasetAPI.Publish:output_type -> aetherfs.dataset.v1.PublishResponse
+	13, // 25: aetherfs.dataset.v1.DatasetAPI.Subscribe:output_type -> aetherfs.dataset.v1.SubscribeResponse
+	20, // [20:26] is the sub-list for method output_type
+	14, // [14:20] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_aetherfs_dataset_v1_api_proto_init() }
@@ -830,7 +1261,7 @@ func file_aetherfs_dataset_v1_api_proto_init() {
 			}
 		}
 		file_aetherfs_dataset_v1_api_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SubscribeRequest); i {
+			switch v := v.(*DiffRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -842,6 +1273,54 @@ func file_aetherfs_dataset_v1_api_proto_init() {
 			}
 		}
 		file_aetherfs_dataset_v1_api_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResizedFile); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aetherfs_dataset_v1_api_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockOverlap); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aetherfs_dataset_v1_api_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DiffResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aetherfs_dataset_v1_api_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubscribeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_aetherfs_dataset_v1_api_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*SubscribeResponse); i {
 			case 0:
 				return &v.state
@@ -860,7 +1339,7 @@ func file_aetherfs_dataset_v1_api_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_aetherfs_dataset_v1_api_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
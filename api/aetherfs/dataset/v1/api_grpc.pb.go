@@ -21,6 +21,7 @@ type DatasetAPIClient interface {
 	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
 	ListTags(ctx context.Context, in *ListTagsRequest, opts ...grpc.CallOption) (*ListTagsResponse, error)
 	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	Diff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*DiffResponse, error)
 	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
 	Subscribe(ctx context.Context, opts ...grpc.CallOption) (DatasetAPI_SubscribeClient, error)
 }
@@ -60,6 +61,15 @@ func (c *datasetAPIClient) Lookup(ctx context.Context, in *LookupRequest, opts .
 	return out, nil
 }
 
+func (c *datasetAPIClient) Diff(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*DiffResponse, error) {
+	out := new(DiffResponse)
+	err := c.cc.Invoke(ctx, "/aetherfs.dataset.v1.DatasetAPI/Diff", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *datasetAPIClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
 	out := new(PublishResponse)
 	err := c.cc.Invoke(ctx, "/aetherfs.dataset.v1.DatasetAPI/Publish", in, out, opts...)
@@ -107,6 +117,7 @@ type DatasetAPIServer interface {
 	List(context.Context, *ListRequest) (*ListResponse, error)
 	ListTags(context.Context, *ListTagsRequest) (*ListTagsResponse, error)
 	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	Diff(context.Context, *DiffRequest) (*DiffResponse, error)
 	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
 	Subscribe(DatasetAPI_SubscribeServer) error
 	mustEmbedUnimplementedDatasetAPIServer()
@@ -125,6 +136,9 @@ func (UnimplementedDatasetAPIServer) ListTags(context.Context, *ListTagsRequest)
 func (UnimplementedDatasetAPIServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
 }
+func (UnimplementedDatasetAPIServer) Diff(context.Context, *DiffRequest) (*DiffResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Diff not implemented")
+}
 func (UnimplementedDatasetAPIServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
 }
@@ -198,6 +212,24 @@ func _DatasetAPI_Lookup_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DatasetAPI_Diff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatasetAPIServer).Diff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aetherfs.dataset.v1.DatasetAPI/Diff",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatasetAPIServer).Diff(ctx, req.(*DiffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DatasetAPI_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PublishRequest)
 	if err := dec(in); err != nil {
@@ -261,6 +293,10 @@ var DatasetAPI_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Lookup",
 			Handler:    _DatasetAPI_Lookup_Handler,
 		},
+		{
+			MethodName: "Diff",
+			Handler:    _DatasetAPI_Diff_Handler,
+		},
 		{
 			MethodName: "Publish",
 			Handler:    _DatasetAPI_Publish_Handler,
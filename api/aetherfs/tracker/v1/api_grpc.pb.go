@@ -0,0 +1,137 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package trackerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// TrackerAPIClient is the client API for TrackerAPI service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TrackerAPIClient interface {
+	Announce(ctx context.Context, in *AnnounceRequest, opts ...grpc.CallOption) (*AnnounceResponse, error)
+	Locate(ctx context.Context, in *LocateRequest, opts ...grpc.CallOption) (*LocateResponse, error)
+}
+
+type trackerAPIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTrackerAPIClient(cc grpc.ClientConnInterface) TrackerAPIClient {
+	return &trackerAPIClient{cc}
+}
+
+func (c *trackerAPIClient) Announce(ctx context.Context, in *AnnounceRequest, opts ...grpc.CallOption) (*AnnounceResponse, error) {
+	out := new(AnnounceResponse)
+	err := c.cc.Invoke(ctx, "/aetherfs.tracker.v1.TrackerAPI/Announce", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trackerAPIClient) Locate(ctx context.Context, in *LocateRequest, opts ...grpc.CallOption) (*LocateResponse, error) {
+	out := new(LocateResponse)
+	err := c.cc.Invoke(ctx, "/aetherfs.tracker.v1.TrackerAPI/Locate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TrackerAPIServer is the server API for TrackerAPI service.
+// All implementations must embed UnimplementedTrackerAPIServer
+// for forward compatibility
+type TrackerAPIServer interface {
+	Announce(context.Context, *AnnounceRequest) (*AnnounceResponse, error)
+	Locate(context.Context, *LocateRequest) (*LocateResponse, error)
+	mustEmbedUnimplementedTrackerAPIServer()
+}
+
+// UnimplementedTrackerAPIServer must be embedded to have forward compatible implementations.
+type UnimplementedTrackerAPIServer struct {
+}
+
+func (UnimplementedTrackerAPIServer) Announce(context.Context, *AnnounceRequest) (*AnnounceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Announce not implemented")
+}
+func (UnimplementedTrackerAPIServer) Locate(context.Context, *LocateRequest) (*LocateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Locate not implemented")
+}
+func (UnimplementedTrackerAPIServer) mustEmbedUnimplementedTrackerAPIServer() {}
+
+// UnsafeTrackerAPIServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TrackerAPIServer will
+// result in compilation errors.
+type UnsafeTrackerAPIServer interface {
+	mustEmbedUnimplementedTrackerAPIServer()
+}
+
+func RegisterTrackerAPIServer(s grpc.ServiceRegistrar, srv TrackerAPIServer) {
+	s.RegisterService(&TrackerAPI_ServiceDesc, srv)
+}
+
+func _TrackerAPI_Announce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnnounceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerAPIServer).Announce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aetherfs.tracker.v1.TrackerAPI/Announce",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerAPIServer).Announce(ctx, req.(*AnnounceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TrackerAPI_Locate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LocateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrackerAPIServer).Locate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aetherfs.tracker.v1.TrackerAPI/Locate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrackerAPIServer).Locate(ctx, req.(*LocateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TrackerAPI_ServiceDesc is the grpc.ServiceDesc for TrackerAPI service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TrackerAPI_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "aetherfs.tracker.v1.TrackerAPI",
+	HandlerType: (*TrackerAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Announce",
+			Handler:    _TrackerAPI_Announce_Handler,
+		},
+		{
+			MethodName: "Locate",
+			Handler:    _TrackerAPI_Locate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "aetherfs/tracker/v1/api.proto",
+}
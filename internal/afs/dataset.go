@@ -0,0 +1,18 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package afs
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mjpitz/aetherfs/internal/headers"
+)
+
+// WithDataset tells the server which dataset the block calls made using the context are for. Blocks aren't tied to a
+// dataset, but hubs that route datasets to different storage need to know where to read and write them.
+func WithDataset(ctx context.Context, name string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, headers.AetherFSDataset, name)
+}
@@ -47,17 +47,15 @@ func (f *DatasetFile) Read(p []byte) (n int, err error) {
 	}
 
 	blockSize := int64(f.Dataset.BlockSize)
+	if blockSize <= 0 {
+		return 0, errors.New("daemons.DatasetFile.Read: invalid block size")
+	}
+
 	fileOffset := f.fileOffset
 
 	// factor in fileOffset which can reduce the total number of bytes that can be read
 	numBytesToRead := min(int64(len(p)), f.File.Size-fileOffset)
 
-	var numBlocksToRead int64
-	if numBytesToRead%blockSize > 0 {
-		numBlocksToRead = 1
-	}
-	numBlocksToRead += numBytesToRead / blockSize
-
 	var datasetFileOffset int64
 	for _, file := range f.Dataset.Files {
 		if file.Name == f.File.Name {
@@ -71,36 +69,34 @@ func (f *DatasetFile) Read(p []byte) (n int, err error) {
 	readOffset := datasetFileOffset + fileOffset
 
 	startingBlock := readOffset / blockSize
+	endingBlock := (readOffset + numBytesToRead - 1) / blockSize
 	blockOffset := readOffset % blockSize
 
+	// manifests published before they were validated may not have enough blocks to hold their files
+	if endingBlock >= int64(len(f.Dataset.Blocks)) {
+		return 0, errors.New("daemons.DatasetFile.Read: dataset is missing blocks")
+	}
+
 	bytesRead := 0
-	for i := startingBlock; i < startingBlock+numBlocksToRead; i++ {
-		stream, err := f.BlockAPI.Download(f.Context, &blockv1.DownloadRequest{
+	defer func() {
+		f.fileOffset += int64(bytesRead)
+	}()
+
+	// only the portions of the blocks that overlap the requested range are downloaded
+	for i := startingBlock; i <= endingBlock; i++ {
+		size := min(blockSize-blockOffset, numBytesToRead-int64(bytesRead))
+
+		n, err := Download(f.Context, f.BlockAPI, &blockv1.DownloadRequest{
 			Signature: f.Dataset.Blocks[i],
 			Offset:    blockOffset,
-			Size:      min(blockSize, numBytesToRead-int64(bytesRead)),
-		})
+			Size:      size,
+		}, p[bytesRead:int64(bytesRead)+size])
+
+		bytesRead += n
 		if err != nil {
 			return bytesRead, translateError(err)
 		}
 
-		var resp *blockv1.DownloadResponse
-
-	LOOP:
-		for {
-			resp, err = stream.Recv()
-			copy(p[bytesRead:], resp.GetPart())
-			bytesRead += len(resp.GetPart())
-
-			switch {
-			case err == io.EOF:
-				break LOOP
-			case err != nil:
-				// translate err
-				return bytesRead, translateError(err)
-			}
-		}
-
 		// every subsequent block should be read from the start
 		blockOffset = 0
 	}
@@ -0,0 +1,128 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package afs
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/myago/clocks"
+)
+
+const (
+	// downloadAttempts is the number of consecutive attempts that can fail without receiving any data before giving up.
+	downloadAttempts = 5
+	// downloadBackoff is how long to wait before the first retry. It doubles with every consecutive failure.
+	downloadBackoff = 100 * time.Millisecond
+	// maxDownloadBackoff caps how long to wait between retries.
+	maxDownloadBackoff = 5 * time.Second
+)
+
+// retryable returns true when the error is likely the result of a transient problem (dropped connections, overloaded
+// servers, etc) and the download should be resumed.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+		return true
+	}
+
+	return false
+}
+
+// downloadOnce streams the requested range of the block into p until the stream ends or fails.
+func downloadOnce(ctx context.Context, blockAPI blockv1.BlockAPIClient, request *blockv1.DownloadRequest, p []byte) (int, error) {
+	stream, err := blockAPI.Download(ctx, request)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for {
+		resp, err := stream.Recv()
+		n += copy(p[n:], resp.GetPart())
+
+		switch {
+		case err == io.EOF:
+			return n, nil
+		case err != nil:
+			return n, err
+		}
+	}
+}
+
+// Download reads the requested range of a block into p and returns the number of bytes read. When the stream is
+// interrupted, the download resumes from the last byte received using the request offset. Consecutive failures back
+// off exponentially and the download is abandoned once too many attempts fail without making progress.
+func Download(ctx context.Context, blockAPI blockv1.BlockAPIClient, request *blockv1.DownloadRequest, p []byte) (int, error) {
+	clock := clocks.Extract(ctx)
+	logger := ctxzap.Extract(ctx).With(zap.String("signature", request.Signature))
+
+	if request.Size > 0 && int64(len(p)) > request.Size {
+		p = p[:request.Size]
+	}
+
+	bytesRead := 0
+	attempt := 0
+	backoff := downloadBackoff
+
+	for {
+		next := &blockv1.DownloadRequest{
+			Signature: request.Signature,
+			Offset:    request.Offset + int64(bytesRead),
+		}
+
+		if request.Size > 0 {
+			next.Size = request.Size - int64(bytesRead)
+		}
+
+		n, err := downloadOnce(ctx, blockAPI, next, p[bytesRead:])
+		bytesRead += n
+
+		switch {
+		case err == nil:
+			return bytesRead, nil
+		case bytesRead == len(p):
+			// we have everything we asked for, even if the stream didn't close cleanly
+			return bytesRead, nil
+		case !retryable(ctx, err):
+			return bytesRead, err
+		}
+
+		if n > 0 {
+			// progress was made, so the connection is likely usable again
+			attempt = 0
+			backoff = downloadBackoff
+		}
+
+		attempt++
+		if attempt >= downloadAttempts {
+			return bytesRead, err
+		}
+
+		logger.Warn("download interrupted, resuming",
+			zap.Int("attempt", attempt), zap.Int("offset", bytesRead), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return bytesRead, ctx.Err()
+		case <-clock.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxDownloadBackoff {
+			backoff = maxDownloadBackoff
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package afs
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/headers"
+)
+
+// MissingBlocks returns the set of signatures that the server does not have. Older servers that do not support
+// LookupBatch report every block as missing and rely on the upload being rejected for blocks that already exist.
+func MissingBlocks(ctx context.Context, blockAPI blockv1.BlockAPIClient, signatures []string) (map[string]bool, error) {
+	missing := make(map[string]bool, len(signatures))
+
+	for start := 0; start < len(signatures); start += blocks.LookupBatchSize {
+		end := start + blocks.LookupBatchSize
+		if end > len(signatures) {
+			end = len(signatures)
+		}
+
+		resp, err := blockAPI.LookupBatch(ctx, &blockv1.LookupBatchRequest{
+			Signatures: signatures[start:end],
+		})
+
+		switch {
+		case status.Code(err) == codes.Unimplemented:
+			for _, signature := range signatures {
+				missing[signature] = true
+			}
+
+			return missing, nil
+		case err != nil:
+			return nil, err
+		}
+
+		for _, signature := range resp.Missing {
+			missing[signature] = true
+		}
+	}
+
+	return missing, nil
+}
+
+// Upload streams the block data to the server. A block that already exists on the server is not an error.
+func Upload(ctx context.Context, blockAPI blockv1.BlockAPIClient, signature string, data []byte) error {
+	logger := ctxzap.Extract(ctx)
+	size := int64(len(data))
+
+	uploadContext := metadata.AppendToOutgoingContext(ctx,
+		headers.AetherFSBlockSignature, signature,
+		headers.AetherFSBlockSize, strconv.FormatInt(size, 10),
+	)
+
+	call, err := blockAPI.Upload(uploadContext)
+
+	st, ok := status.FromError(err)
+	if err == io.EOF || (ok && st.Code() == codes.AlreadyExists) {
+		logger.Info("block already exists", zap.String("signature", signature))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for i := int64(0); i < size; i += int64(blocks.PartSize) {
+		end := i + int64(blocks.PartSize)
+		if end > size {
+			end = size
+		}
+
+		err = call.Send(&blockv1.UploadRequest{
+			Part: data[i:end],
+		})
+
+		st, ok := status.FromError(err)
+		if err == io.EOF || (ok && st.Code() == codes.AlreadyExists) {
+			logger.Info("block already exists", zap.String("signature", signature))
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	_, err = call.CloseAndRecv()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		st, ok := status.FromError(err)
+		if ok && st.Code() == codes.AlreadyExists {
+			logger.Info("block already exists", zap.String("signature", signature))
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
+}
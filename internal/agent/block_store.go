@@ -0,0 +1,371 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+)
+
+const (
+	// blocksDirName is the directory within .aetherfs where verified blocks are stored by signature.
+	blocksDirName = "blocks"
+
+	// filesDirName is the directory within .aetherfs where assembled files are stored for hardlinking.
+	filesDirName = "files"
+)
+
+// fileKey identifies the contents of a file using the blocks that contain it. Files with the same key in different
+// versions of a dataset are identical and can share storage.
+func fileKey(ds *datasetv1.Dataset, file *datasetv1.File) string {
+	start := datasetOffset(ds, file)
+	blockSize := int64(ds.BlockSize)
+
+	h := sha256.New()
+
+	header := make([]byte, 16)
+	binary.BigEndian.PutUint64(header, uint64(start%blockSize))
+	binary.BigEndian.PutUint64(header[8:], uint64(file.Size))
+	_, _ = h.Write(header)
+
+	if file.Size > 0 {
+		for i := start / blockSize; i <= (start+file.Size-1)/blockSize && i < int64(len(ds.Blocks)); i++ {
+			_, _ = h.Write([]byte(ds.Blocks[i]))
+			_, _ = h.Write([]byte{0})
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fullBlocks returns the blocks whose contents are entirely covered by the files.
+func fullBlocks(ds *datasetv1.Dataset, files []*datasetv1.File) map[string]bool {
+	blockSize := int64(ds.BlockSize)
+
+	datasetSize := int64(0)
+	for _, file := range ds.Files {
+		datasetSize += file.Size
+	}
+
+	needed := make(map[int64]int64)
+	for _, file := range files {
+		pos := datasetOffset(ds, file)
+		end := pos + file.Size
+
+		for pos < end {
+			size := min(blockSize-pos%blockSize, end-pos)
+			needed[pos/blockSize] += size
+			pos += size
+		}
+	}
+
+	full := make(map[string]bool)
+	for idx, size := range needed {
+		if idx < int64(len(ds.Blocks)) && size == min(blockSize, datasetSize-idx*blockSize) {
+			full[ds.Blocks[idx]] = true
+		}
+	}
+
+	return full
+}
+
+// datasetOffset returns where the file starts within the dataset.
+func datasetOffset(ds *datasetv1.Dataset, file *datasetv1.File) int64 {
+	offset := int64(0)
+	for _, f := range ds.Files {
+		if f.Name == file.Name {
+			break
+		}
+
+		offset += f.Size
+	}
+
+	return offset
+}
+
+// keyedLocks serializes work on the same key.
+type keyedLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock acquires the lock for the key, returning a func that releases it.
+func (k *keyedLocks) lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*sync.Mutex)
+	}
+
+	l, ok := k.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		k.locks[key] = l
+	}
+	k.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// storeLocks is shared by every blockStore since subscriptions for different hosts can write to the same path.
+var storeLocks = &keyedLocks{}
+
+// blockStore is a node-local, content addressed store of verified blocks shared by every dataset pulled to a path.
+// Files are assembled from the blocks it holds, so blocks shared between versions are only downloaded once. When the
+// hardlink layout is used, assembled files are kept alongside the blocks so identical files share a single, read-only
+// copy.
+type blockStore struct {
+	Dir      string // the .aetherfs directory of the path
+	BlockAPI blockv1.BlockAPIClient
+
+	// Full contains the blocks whose contents are needed in their entirety. Only these are downloaded into the store,
+	// the portions of other blocks needed by sparse pulls are downloaded directly and never cached.
+	Full map[string]bool
+
+	// Peers are asked for blocks in Full before the hub. Portions of blocks can't be verified, so they always come from
+	// the hub.
+	Peers *peerBlocks
+}
+
+func (b *blockStore) blockPath(signature string) string {
+	return filepath.Join(b.Dir, blocksDirName, signature)
+}
+
+func (b *blockStore) filePath(key string) string {
+	return filepath.Join(b.Dir, filesDirName, key)
+}
+
+// fetch ensures the block is available locally, downloading and verifying it when it's not. Blocks are downloaded to a
+// partial file first so an interrupted download can be resumed and unverified data is never trusted.
+func (b *blockStore) fetch(ctx context.Context, signature string, blockSize int32) error {
+	path := b.blockPath(signature)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	defer storeLocks.lock(b.Dir + "|" + signature)()
+
+	// another download may have finished while we were waiting
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	_ = os.MkdirAll(filepath.Dir(path), dirPermissions)
+
+	partialPath := path + partialSuffix
+
+	partial, err := os.OpenFile(partialPath, os.O_CREATE|os.O_RDWR, filePermissions)
+	if err != nil {
+		return err
+	}
+	defer partial.Close()
+
+	data := make([]byte, blockSize)
+
+	offset, err := io.ReadFull(partial, data)
+	switch {
+	case err == nil, errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+	default:
+		return err
+	}
+
+	if offset > 0 {
+		ctxzap.Extract(ctx).Info("resuming block download", zap.String("signature", signature), zap.Int("offset", offset))
+	} else if verified, ok := b.Peers.fetch(ctx, signature); ok {
+		if _, err = partial.Write(verified); err != nil {
+			return err
+		}
+
+		if err = partial.Close(); err != nil {
+			return err
+		}
+
+		return os.Rename(partialPath, path)
+	}
+
+	n, err := afs.Download(ctx, b.BlockAPI, &blockv1.DownloadRequest{
+		Signature: signature,
+		Offset:    int64(offset),
+	}, data[offset:])
+
+	// keep whatever was received so the next attempt can pick up from there
+	if _, werr := partial.Write(data[offset : offset+n]); werr != nil {
+		return werr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	data = data[:offset+n]
+	if err = blocks.VerifySignature(signature, data); err != nil {
+		_ = os.Remove(partialPath)
+		return err
+	}
+
+	if err = partial.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(partialPath, path)
+}
+
+// copyRange writes size bytes of the block starting at offset to out.
+func (b *blockStore) copyRange(ctx context.Context, out io.Writer, signature string, blockSize int32, offset, size int64) (int64, error) {
+	_, err := os.Stat(b.blockPath(signature))
+	switch {
+	case err == nil:
+	case b.Full[signature]:
+		if err = b.fetch(ctx, signature, blockSize); err != nil {
+			return 0, err
+		}
+	default:
+		data := make([]byte, size)
+
+		n, err := afs.Download(ctx, b.BlockAPI, &blockv1.DownloadRequest{
+			Signature: signature,
+			Offset:    offset,
+			Size:      size,
+		}, data)
+		if err != nil {
+			return 0, err
+		}
+
+		m, err := out.Write(data[:n])
+		return int64(m), err
+	}
+
+	f, err := os.Open(b.blockPath(signature))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return io.Copy(out, io.NewSectionReader(f, offset, size))
+}
+
+// assemble writes the contents of the file to out, starting from offset. The written func is called as bytes are
+// written.
+func (b *blockStore) assemble(ctx context.Context, ds *datasetv1.Dataset, file *datasetv1.File, out io.Writer, offset int64, written func(n int64)) error {
+	blockSize := int64(ds.BlockSize)
+	start := datasetOffset(ds, file)
+
+	pos := start + offset
+	end := start + file.Size
+
+	for pos < end {
+		idx := pos / blockSize
+		if idx >= int64(len(ds.Blocks)) {
+			return fmt.Errorf("dataset is missing block %d", idx)
+		}
+
+		blockOffset := pos % blockSize
+		size := min(blockSize-blockOffset, end-pos)
+
+		n, err := b.copyRange(ctx, out, ds.Blocks[idx], ds.BlockSize, blockOffset, size)
+		pos += n
+		written(n)
+
+		switch {
+		case err != nil:
+			return err
+		case n < size:
+			return fmt.Errorf("block %s is shorter than expected", ds.Blocks[idx])
+		}
+	}
+
+	return nil
+}
+
+// link hardlinks the file into place from the file store, assembling it first if no other version has. Stored files are
+// read-only since every version linking to them shares their contents. When a hardlink can't be created (e.g. the
+// filesystem doesn't support them), the file is copied instead.
+func (b *blockStore) link(ctx context.Context, ds *datasetv1.Dataset, file *datasetv1.File, filePath string, written func(n int64)) error {
+	key := fileKey(ds, file)
+	stored := b.filePath(key)
+
+	defer storeLocks.lock(b.Dir + "|" + key)()
+
+	switch _, err := os.Stat(stored); {
+	case errors.Is(err, fs.ErrNotExist):
+		_ = os.MkdirAll(filepath.Dir(stored), dirPermissions)
+
+		// partial files are keyed by their contents, so they can always be resumed
+		err = downloadFile(ctx, b, ds, file, stored, true, written)
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		written(file.Size)
+	}
+
+	err := os.Chmod(stored, storedPermissions)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if os.Link(stored, filePath) == nil {
+		return nil
+	}
+
+	return copyFile(stored, filePath)
+}
+
+// copyFile copies src to dst using a partial file so dst is never left half written.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst+partialSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePermissions)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err = io.Copy(out, in); err != nil {
+		return err
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(dst+partialSuffix, dst)
+}
+
+// min returns the smaller of a and b.
+func min(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
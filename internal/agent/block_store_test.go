@@ -0,0 +1,73 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+)
+
+func TestLinkIsolatesSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir()
+
+	data := []byte("hello world")
+	signature, err := blocks.ComputeSignature("sha256", data)
+	require.NoError(t, err)
+
+	file := &datasetv1.File{Name: "hello.txt", Size: int64(len(data))}
+	ds := &datasetv1.Dataset{
+		BlockSize: 16,
+		Blocks:    []string{signature},
+		Files:     []*datasetv1.File{file},
+	}
+
+	store := &blockStore{
+		Dir:  filepath.Join(path, aetherFSDirName),
+		Full: fullBlocks(ds, ds.Files),
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(store.blockPath(signature)), dirPermissions))
+	require.NoError(t, ioutil.WriteFile(store.blockPath(signature), data, filePermissions))
+
+	first := filepath.Join(path, "first", file.Name)
+	second := filepath.Join(path, "second", file.Name)
+	written := func(n int64) {}
+
+	for _, filePath := range []string{first, second} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), dirPermissions))
+		require.NoError(t, store.link(ctx, ds, file, filePath, written))
+
+		info, err := os.Stat(filePath)
+		require.NoError(t, err)
+		require.Equal(t, storedPermissions, info.Mode().Perm())
+	}
+
+	if os.Geteuid() != 0 {
+		// root can write to read-only files
+		_, err = os.OpenFile(first, os.O_WRONLY, filePermissions)
+		require.ErrorIs(t, err, fs.ErrPermission)
+	}
+
+	// editors replace the file rather than writing through the link
+	require.NoError(t, os.Remove(first))
+	require.NoError(t, ioutil.WriteFile(first, []byte("edited"), filePermissions))
+
+	contents, err := ioutil.ReadFile(second)
+	require.NoError(t, err)
+	require.Equal(t, data, contents)
+
+	contents, err = ioutil.ReadFile(store.filePath(fileKey(ds, file)))
+	require.NoError(t, err)
+	require.Equal(t, data, contents)
+}
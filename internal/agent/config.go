@@ -3,10 +3,28 @@
 
 package agent
 
+import (
+	"time"
+)
+
 type Config struct {
 	Enable bool `json:"enable" usage:"enable the agent API"`
 
 	Shutdown struct {
 		Enable bool `json:"enable" usage:"enables the agent API to initiate a shutdown"`
 	} `json:"shutdown"`
+
+	Prune struct {
+		Interval     time.Duration `json:"interval"       usage:"how often to prune datasets pulled by subscriptions, disabled when zero"`
+		KeepLast     int           `json:"keep_last"      usage:"the number of versions of each dataset to keep, unlimited when zero"`
+		MaxDiskUsage string        `json:"max_disk_usage" usage:"the maximum amount of disk pulled datasets may occupy (e.g. 20GiB)"`
+		MinFreeSpace string        `json:"min_free_space" usage:"the amount of disk space to keep free on the filesystem (e.g. 5GiB)"`
+	} `json:"prune"`
+
+	Peers struct {
+		Address  string        `json:"address"  usage:"the address peers can download blocks from this agent at, blocks are only shared when set"`
+		Static   string        `json:"static"   usage:"comma separated list of peers to download blocks from before the hub"`
+		Tracker  string        `json:"tracker"  usage:"the hub used to find peers and announce the blocks this agent holds"`
+		Interval time.Duration `json:"interval" usage:"how often the blocks this agent holds are announced to the tracker" default:"1m"`
+	} `json:"peers"`
 }
@@ -0,0 +1,38 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext retains the values of its parent (loggers, clocks, file systems, etc) without inheriting its
+// cancellation. This allows work started by a request to outlive the request itself.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (d detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+func (d detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// detach returns a context that carries the values of ctx but is never cancelled.
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+var _ context.Context = detachedContext{}
@@ -0,0 +1,23 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+//go:build !windows
+// +build !windows
+
+package agent
+
+import (
+	"io/fs"
+	"strconv"
+	"syscall"
+)
+
+// fileID identifies the data behind the file so hardlinks to the same data are only counted once.
+func fileID(path string, info fs.FileInfo) string {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return path
+	}
+
+	return strconv.FormatUint(uint64(stat.Dev), 10) + ":" + strconv.FormatUint(uint64(stat.Ino), 10)
+}
@@ -0,0 +1,17 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+//go:build windows
+// +build windows
+
+package agent
+
+import (
+	"io/fs"
+)
+
+// fileID identifies the file by its path. Windows doesn't expose inodes through fs.FileInfo, so hardlinks to the same
+// data are counted more than once.
+func fileID(path string, info fs.FileInfo) string {
+	return path
+}
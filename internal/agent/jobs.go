@@ -0,0 +1,327 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	"github.com/mjpitz/myago/clocks"
+	"github.com/mjpitz/myago/ulid"
+)
+
+// maxCompletedJobs is the number of completed jobs the agent remembers. Older jobs are forgotten first.
+const maxCompletedJobs = 100
+
+// job tracks the progress of a single publish or subscribe request. A nil job is valid and discards all updates.
+type job struct {
+	mu      sync.Mutex
+	state   *agentv1.Job
+	changed chan struct{}
+
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+func (j *job) id() string {
+	if j == nil {
+		return ""
+	}
+
+	return j.state.Id
+}
+
+// update applies fn to the job and notifies any watchers.
+func (j *job) update(fn func(state *agentv1.Job)) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	fn(j.state)
+
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// snapshot returns a copy of the job along with a channel that is closed the next time the job changes.
+func (j *job) snapshot() (*agentv1.Job, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return proto.Clone(j.state).(*agentv1.Job), j.changed
+}
+
+// host updates the progress reported for the host and recomputes the total progress of the job.
+func (j *job) host(host string, fn func(progress *agentv1.JobProgress)) {
+	j.update(func(state *agentv1.Job) {
+		progress, ok := state.Hosts[host]
+		if !ok {
+			progress = &agentv1.JobProgress{}
+			state.Hosts[host] = progress
+		}
+
+		fn(progress)
+
+		total := &agentv1.JobProgress{}
+		for _, progress := range state.Hosts {
+			total.BytesDone += progress.BytesDone
+			total.BytesTotal += progress.BytesTotal
+			total.BlocksDone += progress.BlocksDone
+			total.BlocksTotal += progress.BlocksTotal
+			total.FilesDone += progress.FilesDone
+			total.FilesTotal += progress.FilesTotal
+		}
+
+		state.Progress = total
+	})
+}
+
+// fail records that the work against the host failed.
+func (j *job) fail(host string, err error) {
+	if err == nil {
+		return
+	}
+
+	j.host(host, func(progress *agentv1.JobProgress) {
+		progress.Error = err.Error()
+	})
+}
+
+// cancellable returns a context that's cancelled when the job is.
+func (j *job) cancellable(ctx context.Context) context.Context {
+	if j == nil {
+		return ctx
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.cancel = cancel
+	if j.cancelled {
+		cancel()
+	}
+
+	return ctx
+}
+
+// stop cancels the job. It returns false when the job has already completed.
+func (j *job) stop() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.state.State != agentv1.JobState_JOB_STATE_RUNNING {
+		return false
+	}
+
+	j.cancelled = true
+	if j.cancel != nil {
+		j.cancel()
+	}
+
+	return true
+}
+
+// complete moves the job into a terminal state.
+func (j *job) complete(ctx context.Context, err error, fn func(state *agentv1.Job)) {
+	now := timestamppb.New(clocks.Extract(ctx).Now())
+
+	j.update(func(state *agentv1.Job) {
+		state.Completed = now
+		state.State = agentv1.JobState_JOB_STATE_SUCCEEDED
+
+		switch {
+		case j.cancelled:
+			state.State = agentv1.JobState_JOB_STATE_CANCELLED
+		case err != nil:
+			state.State = agentv1.JobState_JOB_STATE_FAILED
+			state.Error = err.Error()
+		}
+
+		if fn != nil {
+			fn(state)
+		}
+	})
+}
+
+func (j *job) done() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.state.State != agentv1.JobState_JOB_STATE_RUNNING
+}
+
+// jobTracker keeps track of running and recently completed jobs.
+type jobTracker struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func (t *jobTracker) start(ctx context.Context, kind agentv1.JobKind, path string, tags []string) (*job, error) {
+	id, err := ulid.Extract(ctx).Generate(ctx, 128)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &job{
+		state: &agentv1.Job{
+			Id:       id.String(),
+			Kind:     kind,
+			State:    agentv1.JobState_JOB_STATE_RUNNING,
+			Path:     path,
+			Tags:     tags,
+			Created:  timestamppb.New(clocks.Extract(ctx).Now()),
+			Progress: &agentv1.JobProgress{},
+			Hosts:    make(map[string]*agentv1.JobProgress),
+		},
+		changed: make(chan struct{}),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.jobs == nil {
+		t.jobs = make(map[string]*job)
+	}
+
+	t.jobs[j.state.Id] = j
+	t.prune()
+
+	return j, nil
+}
+
+// prune forgets the oldest completed jobs once there are too many of them.
+func (t *jobTracker) prune() {
+	completed := make([]*agentv1.Job, 0, len(t.jobs))
+	for _, j := range t.jobs {
+		if j.done() {
+			state, _ := j.snapshot()
+			completed = append(completed, state)
+		}
+	}
+
+	if len(completed) <= maxCompletedJobs {
+		return
+	}
+
+	sort.Slice(completed, func(i, k int) bool {
+		return completed[i].Completed.AsTime().Before(completed[k].Completed.AsTime())
+	})
+
+	for _, state := range completed[:len(completed)-maxCompletedJobs] {
+		delete(t.jobs, state.Id)
+	}
+}
+
+func (t *jobTracker) get(id string) (*job, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	j, ok := t.jobs[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "job %s not found", id)
+	}
+
+	return j, nil
+}
+
+func (t *jobTracker) list() []*agentv1.Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	jobs := make([]*agentv1.Job, 0, len(t.jobs))
+	for _, j := range t.jobs {
+		state, _ := j.snapshot()
+		jobs = append(jobs, state)
+	}
+
+	// ulids sort by creation time
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].Id < jobs[k].Id
+	})
+
+	return jobs
+}
+
+// WatchJobUpdates calls fn with the current state of the job and again every time it changes until the job completes
+// or the context is cancelled.
+func (s *Service) WatchJobUpdates(ctx context.Context, id string, fn func(job *agentv1.Job) error) error {
+	j, err := s.jobs.get(id)
+	if err != nil {
+		return err
+	}
+
+	for {
+		state, changed := j.snapshot()
+
+		if err := fn(state); err != nil {
+			return err
+		}
+
+		if state.State != agentv1.JobState_JOB_STATE_RUNNING {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (s *Service) GetJob(ctx context.Context, request *agentv1.GetJobRequest) (*agentv1.GetJobResponse, error) {
+	j, err := s.jobs.get(request.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _ := j.snapshot()
+
+	return &agentv1.GetJobResponse{
+		Job: state,
+	}, nil
+}
+
+func (s *Service) ListJobs(ctx context.Context, request *agentv1.ListJobsRequest) (*agentv1.ListJobsResponse, error) {
+	return &agentv1.ListJobsResponse{
+		Jobs: s.jobs.list(),
+	}, nil
+}
+
+func (s *Service) CancelJob(ctx context.Context, request *agentv1.CancelJobRequest) (*agentv1.CancelJobResponse, error) {
+	j, err := s.jobs.get(request.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !j.stop() {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %s is not running", request.Id)
+	}
+
+	state, _ := j.snapshot()
+
+	return &agentv1.CancelJobResponse{
+		Job: state,
+	}, nil
+}
+
+func (s *Service) WatchJob(request *agentv1.WatchJobRequest, call agentv1.AgentAPI_WatchJobServer) error {
+	return s.WatchJobUpdates(call.Context(), request.Id, func(job *agentv1.Job) error {
+		return call.Send(&agentv1.WatchJobResponse{
+			Job: job,
+		})
+	})
+}
@@ -0,0 +1,256 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/clocks"
+)
+
+// journalFile records enough information about a file to detect when it changes between attempts.
+type journalFile struct {
+	Name         string `json:"name"`
+	Size         int64  `json:"size"`
+	LastModified int64  `json:"last_modified"`
+}
+
+// journal records the progress of publishing a dataset to a single host. It allows an interrupted publish to be
+// resumed without re-hashing the dataset or re-uploading the blocks that were already confirmed by the host. Only the
+// details of the request are stored under the journal's key. Signatures and uploaded blocks are appended under their own
+// keys as the publish progresses so recording progress doesn't require rewriting the entire journal.
+type journal struct {
+	Root      string   `json:"root"`
+	Host      string   `json:"host"`
+	Tags      []string `json:"tags"`
+	BlockSize int32    `json:"block_size"`
+	Include   []string `json:"include,omitempty"`
+	Exclude   []string `json:"exclude,omitempty"`
+	// ExpectedPrevious contains the digests the tags had to point at, so a resumed publish keeps the same precondition.
+	ExpectedPrevious map[string]string `json:"expected_previous,omitempty"`
+	Files            []journalFile     `json:"files"`
+	Signatures       []string          `json:"signatures,omitempty"`
+	Uploaded         []string          `json:"uploaded,omitempty"`
+	Updated          time.Time         `json:"updated"`
+}
+
+func journalKey(host, root string) string {
+	return host + "|" + root
+}
+
+// progressPrefix is the prefix of the keys recording the progress of a journal. Hosts never contain a "/", so these
+// keys can't be mistaken for a journal.
+func progressPrefix(host, root string) string {
+	return "progress/" + journalKey(host, root) + "/"
+}
+
+func journalFiles(files []*datasetv1.File) []journalFile {
+	out := make([]journalFile, 0, len(files))
+	for _, file := range files {
+		out = append(out, journalFile{
+			Name:         file.Name,
+			Size:         file.Size,
+			LastModified: file.GetLastModified().AsTime().UnixNano(),
+		})
+	}
+
+	return out
+}
+
+// matches returns true when the journal was recorded for the same files, block size, and filters. Only then can its
+// signatures be trusted.
+func (j *journal) matches(blockSize int32, files []*datasetv1.File, opts publishOptions) bool {
+	if j == nil || j.BlockSize != blockSize || !equalStrings(j.Include, opts.Include) || !equalStrings(j.Exclude, opts.Exclude) {
+		return false
+	}
+
+	current := journalFiles(files)
+	if len(current) != len(j.Files) {
+		return false
+	}
+
+	for i := range current {
+		if current[i] != j.Files[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// journalWriter persists the progress of a single publish. A nil writer discards all progress.
+type journalWriter struct {
+	mu      sync.Mutex
+	store   *local.Store
+	journal *journal
+	chunks  int
+}
+
+// start records the details of the publish. Progress recorded by a previous attempt is kept when it can be resumed and
+// discarded otherwise.
+func (w *journalWriter) start(ctx context.Context, resume bool) error {
+	if w == nil {
+		return nil
+	}
+
+	if !resume {
+		err := w.store.DeletePrefix(ctx, progressPrefix(w.journal.Host, w.journal.Root))
+		if err != nil {
+			return err
+		}
+	}
+
+	w.journal.Updated = clocks.Extract(ctx).Now()
+	return w.store.Put(ctx, journalKey(w.journal.Host, w.journal.Root), w.journal)
+}
+
+// signatures records the signatures of the next blocks in the dataset.
+func (w *journalWriter) signatures(ctx context.Context, signatures []string) error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	key := fmt.Sprintf("%ssignatures/%08d", progressPrefix(w.journal.Host, w.journal.Root), w.chunks)
+	w.chunks++
+
+	return w.store.Put(ctx, key, signatures)
+}
+
+// uploaded records that the host has confirmed it received the block.
+func (w *journalWriter) uploaded(ctx context.Context, signature string) error {
+	if w == nil {
+		return nil
+	}
+
+	return w.store.Put(ctx, progressPrefix(w.journal.Host, w.journal.Root)+"uploaded/"+signature, true)
+}
+
+// complete removes the journal once the dataset has been published.
+func (w *journalWriter) complete(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+
+	return removeJournal(ctx, w.store, w.journal.Host, w.journal.Root)
+}
+
+// removeJournal removes the journal along with the progress it recorded.
+func removeJournal(ctx context.Context, store *local.Store, host, root string) error {
+	err := store.DeletePrefix(ctx, progressPrefix(host, root))
+	if err != nil {
+		return err
+	}
+
+	return store.Delete(ctx, journalKey(host, root))
+}
+
+// permanent returns true for errors that retrying the publish won't fix.
+func permanent(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.FailedPrecondition, codes.OutOfRange, codes.Unimplemented:
+		return true
+	}
+
+	return false
+}
+
+// loadJournal returns the journal recorded for publishing the root to the host, if one exists.
+func (s *Service) loadJournal(ctx context.Context, host, root string) (*journal, error) {
+	if s.Journals == nil {
+		return nil, nil
+	}
+
+	j := &journal{}
+
+	err := s.Journals.Get(ctx, journalKey(host, root), j)
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	prefix := progressPrefix(host, root)
+
+	keys, err := s.Journals.ListPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	// keys are listed in order, so signatures are appended in the order they were recorded
+	for _, key := range keys {
+		progress := strings.TrimPrefix(key, prefix)
+
+		switch {
+		case strings.HasPrefix(progress, "signatures/"):
+			var signatures []string
+			if err := s.Journals.Get(ctx, key, &signatures); err != nil {
+				return nil, err
+			}
+
+			j.Signatures = append(j.Signatures, signatures...)
+		case strings.HasPrefix(progress, "uploaded/"):
+			j.Uploaded = append(j.Uploaded, strings.TrimPrefix(progress, "uploaded/"))
+		}
+	}
+
+	return j, nil
+}
+
+// listJournals returns all recorded journals. When root is provided, only the journals for that root are returned.
+func (s *Service) listJournals(ctx context.Context, root string) ([]*journal, error) {
+	if s.Journals == nil {
+		return nil, nil
+	}
+
+	keys, err := s.Journals.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	journals := make([]*journal, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, "progress/") || (root != "" && !strings.HasSuffix(key, "|"+root)) {
+			continue
+		}
+
+		j := &journal{}
+		if err := s.Journals.Get(ctx, key, j); err != nil {
+			return nil, err
+		}
+
+		journals = append(journals, j)
+	}
+
+	return journals, nil
+}
@@ -0,0 +1,574 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	trackerv1 "github.com/mjpitz/aetherfs/api/aetherfs/tracker/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/tracker"
+	"github.com/mjpitz/myago/clocks"
+)
+
+const (
+	// maxPeerDownloads is the number of parts of a block that are downloaded from peers at once.
+	maxPeerDownloads = 4
+
+	// peerTimeout bounds each request made to a peer so an unresponsive one falls back to the hub quickly.
+	peerTimeout = 10 * time.Second
+)
+
+// Peers are other agents that blocks are downloaded from before falling back to the hub. They're found using a static
+// list, the hub's tracker, or both. Blocks received from peers are verified against their signature before they're used.
+type Peers struct {
+	// Address is where peers can reach this agent. It's announced to the tracker and never treated as a peer.
+	Address string
+	Static  []string
+	Tracker string
+}
+
+// peerBlocks tracks which peers hold the blocks needed by a pull.
+type peerBlocks struct {
+	service *Service
+
+	mu      sync.Mutex
+	holders map[string][]string
+	sizes   map[string]int64
+	conns   map[string]*grpc.ClientConn
+}
+
+// peerBlocks returns nil when the agent isn't configured with peers.
+func (s *Service) peerBlocks() *peerBlocks {
+	if s.Peers == nil {
+		return nil
+	}
+
+	return &peerBlocks{
+		service: s,
+		holders: make(map[string][]string),
+		sizes:   make(map[string]int64),
+		conns:   make(map[string]*grpc.ClientConn),
+	}
+}
+
+func (p *peerBlocks) client(ctx context.Context, address string) (blockv1.BlockAPIClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conn, ok := p.conns[address]
+	if !ok {
+		var err error
+
+		conn, err = p.service.connectionFor(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+
+		p.conns[address] = conn
+	}
+
+	return blockv1.NewBlockAPIClient(conn), nil
+}
+
+// Close releases the connections made to peers.
+func (p *peerBlocks) Close() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, conn := range p.conns {
+		_ = conn.Close()
+	}
+}
+
+// add records that the address holds the signature.
+func (p *peerBlocks) add(signature, address string) {
+	if address == p.service.Peers.Address {
+		return
+	}
+
+	for _, holder := range p.holders[signature] {
+		if holder == address {
+			return
+		}
+	}
+
+	p.holders[signature] = append(p.holders[signature], address)
+}
+
+// locate finds the peers holding the blocks in full. Peers are a best effort, so failing to reach one only means the
+// blocks it holds are downloaded from the hub instead.
+func (p *peerBlocks) locate(ctx context.Context, ds *datasetv1.Dataset, full map[string]bool) {
+	logger := ctxzap.Extract(ctx)
+	peers := p.service.Peers
+
+	sizes := dataset.BlockSizes(ds)
+	signatures := make([]string, 0, len(full))
+
+	p.mu.Lock()
+	for i, signature := range ds.Blocks {
+		if _, ok := p.sizes[signature]; ok || !full[signature] {
+			continue
+		}
+
+		p.sizes[signature] = sizes[i]
+		signatures = append(signatures, signature)
+	}
+	p.mu.Unlock()
+
+	if len(signatures) == 0 {
+		return
+	}
+
+	for _, address := range peers.Static {
+		if address == peers.Address {
+			continue
+		}
+
+		client, err := p.client(ctx, address)
+		if err != nil {
+			logger.Warn("failed to connect to peer", zap.String("peer", address), zap.Error(err))
+			continue
+		}
+
+		lookupContext, cancel := context.WithTimeout(ctx, peerTimeout)
+		missing, err := afs.MissingBlocks(lookupContext, client, signatures)
+		cancel()
+
+		if err != nil {
+			logger.Warn("failed to lookup blocks on peer", zap.String("peer", address), zap.Error(err))
+			continue
+		}
+
+		p.mu.Lock()
+		for _, signature := range signatures {
+			if !missing[signature] {
+				p.add(signature, address)
+			}
+		}
+		p.mu.Unlock()
+	}
+
+	if peers.Tracker == "" {
+		return
+	}
+
+	conn, err := p.service.connectionFor(ctx, peers.Tracker)
+	if err != nil {
+		logger.Warn("failed to connect to tracker", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	trackerAPI := trackerv1.NewTrackerAPIClient(conn)
+
+	for start := 0; start < len(signatures); start += tracker.MaxSignatures {
+		end := start + tracker.MaxSignatures
+		if end > len(signatures) {
+			end = len(signatures)
+		}
+
+		locateContext, cancel := context.WithTimeout(ctx, peerTimeout)
+		resp, err := trackerAPI.Locate(locateContext, &trackerv1.LocateRequest{
+			Signatures: signatures[start:end],
+		})
+		cancel()
+
+		if err != nil {
+			logger.Warn("failed to locate peers", zap.Error(err))
+			return
+		}
+
+		p.mu.Lock()
+		for _, holders := range resp.Holders {
+			for _, address := range holders.Addresses {
+				p.add(holders.Signature, address)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// download reads a part of the block from the peer.
+func (p *peerBlocks) download(ctx context.Context, address, signature string, offset int64, part []byte) error {
+	client, err := p.client(ctx, address)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, peerTimeout)
+	defer cancel()
+
+	stream, err := client.Download(ctx, &blockv1.DownloadRequest{
+		Signature: signature,
+		Offset:    offset,
+		Size:      int64(len(part)),
+	})
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for n < len(part) {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		n += copy(part[n:], resp.Part)
+	}
+
+	if n < len(part) {
+		return fmt.Errorf("received %d of %d bytes", n, len(part))
+	}
+
+	return nil
+}
+
+// fetch downloads the block from the peers holding it. The block is split into parts that are downloaded from several
+// peers at once, moving on to another peer when one fails. The block is only returned once it has been verified.
+func (p *peerBlocks) fetch(ctx context.Context, signature string) ([]byte, bool) {
+	if p == nil {
+		return nil, false
+	}
+
+	logger := ctxzap.Extract(ctx).With(zap.String("signature", signature))
+
+	p.mu.Lock()
+	holders := append([]string(nil), p.holders[signature]...)
+	size := p.sizes[signature]
+	p.mu.Unlock()
+
+	if len(holders) == 0 || size == 0 {
+		return nil, false
+	}
+
+	// spread requests out so every agent doesn't download from the same peer
+	rand.Shuffle(len(holders), func(i, j int) {
+		holders[i], holders[j] = holders[j], holders[i]
+	})
+
+	partSize := int64(blocks.PartSize)
+	data := make([]byte, size)
+
+	parts := make(chan int64, (size+partSize-1)/partSize)
+	for offset := int64(0); offset < size; offset += partSize {
+		parts <- offset
+	}
+	close(parts)
+
+	workers := len(holders)
+	if workers > maxPeerDownloads {
+		workers = maxPeerDownloads
+	}
+
+	group, groupContext := errgroup.WithContext(ctx)
+
+	for i := 0; i < workers; i++ {
+		worker := i
+
+		group.Go(func() error {
+			for offset := range parts {
+				part := data[offset:min(offset+partSize, size)]
+
+				var err error
+				for attempt := 0; attempt < len(holders); attempt++ {
+					address := holders[(worker+attempt)%len(holders)]
+
+					err = p.download(groupContext, address, signature, offset, part)
+					if err == nil {
+						break
+					}
+
+					logger.Debug("failed to download part from peer", zap.String("peer", address), zap.Error(err))
+				}
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		logger.Warn("failed to download block from peers, falling back to hub", zap.Error(err))
+		return nil, false
+	}
+
+	if err := blocks.VerifySignature(signature, data); err != nil {
+		// without knowing which peer sent the bad part, none of them are trusted for this block
+		p.mu.Lock()
+		delete(p.holders, signature)
+		p.mu.Unlock()
+
+		logger.Warn("block from peers failed verification, falling back to hub", zap.Error(err))
+		return nil, false
+	}
+
+	logger.Debug("downloaded block from peers", zap.Int("peers", len(holders)))
+	return data, true
+}
+
+// subscribedPaths returns the distinct paths the agent has subscriptions for.
+func (s *Service) subscribedPaths(ctx context.Context) ([]string, error) {
+	records, err := s.listSubscriptions(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(records))
+	for _, record := range records {
+		if len(paths) == 0 || paths[len(paths)-1] != record.Path {
+			paths = append(paths, record.Path)
+		}
+	}
+
+	return paths, nil
+}
+
+// heldBlocks returns the signatures of the verified blocks held for the agent's subscriptions.
+func (s *Service) heldBlocks(ctx context.Context) ([]string, error) {
+	paths, err := s.subscribedPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	signatures := make([]string, 0)
+
+	for _, path := range paths {
+		entries, err := os.ReadDir(filepath.Join(path, aetherFSDirName, blocksDirName))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			// partial downloads are named after the block they'll become
+			name := entry.Name()
+			if entry.IsDir() || strings.Contains(name, ".") || seen[name] {
+				continue
+			}
+
+			seen[name] = true
+			signatures = append(signatures, name)
+		}
+	}
+
+	sort.Strings(signatures)
+	return signatures, nil
+}
+
+// announce advertises the blocks held by the agent to the tracker.
+func (s *Service) announce(ctx context.Context) error {
+	signatures, err := s.heldBlocks(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(signatures) > tracker.MaxSignatures {
+		ctxzap.Extract(ctx).Warn("holding more blocks than can be announced",
+			zap.Int("blocks", len(signatures)), zap.Int("announced", tracker.MaxSignatures))
+
+		signatures = signatures[:tracker.MaxSignatures]
+	}
+
+	conn, err := s.connectionFor(ctx, s.Peers.Tracker)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = trackerv1.NewTrackerAPIClient(conn).Announce(ctx, &trackerv1.AnnounceRequest{
+		Address:    s.Peers.Address,
+		Signatures: signatures,
+	})
+
+	return err
+}
+
+// AnnounceEvery advertises the blocks held by the agent to the tracker immediately and then on every interval until
+// the context is cancelled. Nothing is announced unless the agent has both a tracker and an address to share blocks at.
+func (s *Service) AnnounceEvery(ctx context.Context, interval time.Duration) {
+	if s.Peers == nil || s.Peers.Tracker == "" || s.Peers.Address == "" {
+		return
+	}
+
+	logger := ctxzap.Extract(ctx)
+	clock := clocks.Extract(ctx)
+
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.announce(ctx)
+		if err != nil {
+			logger.Error("failed to announce blocks to tracker", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+	}
+}
+
+// PeerServer shares the verified blocks held for the agent's subscriptions with its peers. Only the parts of the block
+// API needed to download blocks are supported.
+type PeerServer struct {
+	blockv1.UnimplementedBlockAPIServer
+
+	Service *Service
+}
+
+// paths returns the subscribed paths blocks are shared from.
+func (p *PeerServer) paths(ctx context.Context) ([]string, error) {
+	paths, err := p.Service.subscribedPaths(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list subscriptions")
+	}
+
+	return paths, nil
+}
+
+// open returns the block from one of the subscribed paths.
+func (p *PeerServer) open(paths []string, signature string) (*os.File, int64, error) {
+	if !dataset.ValidSignature(signature) {
+		return nil, 0, status.Error(codes.InvalidArgument, "invalid signature")
+	}
+
+	for _, path := range paths {
+		f, err := os.Open(filepath.Join(path, aetherFSDirName, blocksDirName, signature))
+		if err != nil {
+			continue
+		}
+
+		info, err := f.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			_ = f.Close()
+			continue
+		}
+
+		return f, info.Size(), nil
+	}
+
+	return nil, 0, status.Error(codes.NotFound, "block not found")
+}
+
+func (p *PeerServer) Lookup(ctx context.Context, request *blockv1.LookupRequest) (*blockv1.LookupResponse, error) {
+	paths, err := p.paths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f, _, err := p.open(paths, request.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = f.Close()
+	return &blockv1.LookupResponse{}, nil
+}
+
+func (p *PeerServer) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	if len(request.Signatures) > blocks.LookupBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d signatures can be looked up at once", blocks.LookupBatchSize)
+	}
+
+	paths, err := p.paths(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &blockv1.LookupBatchResponse{}
+
+	for _, signature := range request.Signatures {
+		f, _, err := p.open(paths, signature)
+		switch {
+		case status.Code(err) == codes.NotFound:
+			resp.Missing = append(resp.Missing, signature)
+		case err != nil:
+			return nil, err
+		default:
+			_ = f.Close()
+		}
+	}
+
+	return resp, nil
+}
+
+func (p *PeerServer) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
+	paths, err := p.paths(call.Context())
+	if err != nil {
+		return err
+	}
+
+	f, size, err := p.open(paths, request.Signature)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if request.Offset < 0 || request.Offset > size || request.Size < 0 {
+		return status.Error(codes.InvalidArgument, "range is outside the block")
+	}
+
+	length := size - request.Offset
+	if request.Size > 0 && request.Size < length {
+		length = request.Size
+	}
+
+	reader := io.NewSectionReader(f, request.Offset, length)
+	part := make([]byte, blocks.PartSize)
+
+	for {
+		n, err := reader.Read(part)
+		if n > 0 {
+			if serr := call.Send(&blockv1.DownloadResponse{Part: part[:n]}); serr != nil {
+				return serr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return status.Error(codes.Internal, "failed to read block")
+		}
+	}
+}
+
+var _ blockv1.BlockAPIServer = &PeerServer{}
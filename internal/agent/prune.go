@@ -0,0 +1,638 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/myago/clocks"
+)
+
+const (
+	pruneKeepLast     = "keep-last"
+	pruneMaxDiskUsage = "max-disk-usage"
+	pruneMinFreeSpace = "min-free-space"
+)
+
+// pulledDataset is a dataset that was previously pulled to disk and is a candidate for being pruned.
+type pulledDataset struct {
+	Tag        string    // the tag the dataset was pulled with
+	Group      string    // versions of the same dataset share a group
+	Dir        string    // the directory the dataset was written to
+	Snapshot   string    // the snapshot file describing the dataset
+	Pulled     time.Time // when the directory was last updated
+	Protected  string    // when set, the reason the directory must be kept
+	Subscribed bool      // a persisted subscription writes to the directory
+
+	// Files maps the files making up the dataset, both in its directory and in the block store, to their size. Files
+	// are identified by their inode so hardlinks shared between datasets and the block store are only counted once.
+	Files map[string]int64
+}
+
+// legacyPulledDataset infers the tag and directory of a snapshot written before they were recorded. Both the name and
+// version can contain dots, so every split is tried until one matches a directory on disk.
+func legacyPulledDataset(path, aetherFSDir, snapshotFile string) (tag, group, dir string) {
+	rel, err := filepath.Rel(aetherFSDir, snapshotFile)
+	if err != nil {
+		return "", "", ""
+	}
+
+	rel = strings.TrimSuffix(filepath.ToSlash(rel), snapshotSuffix)
+
+	for i := strings.Index(rel, "."); i >= 0; {
+		name, version := rel[:i], rel[i+1:]
+
+		candidate := filepath.Join(path, name, version)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return name + ":" + version, name, candidate
+		}
+
+		next := strings.Index(rel[i+1:], ".")
+		if next < 0 {
+			break
+		}
+
+		i += next + 1
+	}
+
+	return "", "", ""
+}
+
+// diskUsage records the size of every regular file under dir in files.
+func diskUsage(dir string, files map[string]int64) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			files[fileID(path, info)] = info.Size()
+		}
+
+		return nil
+	})
+}
+
+// storeUsage records the size of the entries in the block store that the snapshot references in files.
+func storeUsage(aetherFSDir string, snapshot *agentv1.Snapshot, files map[string]int64) {
+	ds := snapshot.GetDataset()
+
+	paths := make([]string, 0, len(ds.GetFiles())+len(ds.GetBlocks()))
+	for _, f := range ds.GetFiles() {
+		paths = append(paths, filepath.Join(aetherFSDir, filesDirName, fileKey(ds, f)))
+	}
+
+	// blocks stay in the store until no snapshot references them
+	for _, signature := range ds.GetBlocks() {
+		paths = append(paths, filepath.Join(aetherFSDir, blocksDirName, signature))
+	}
+
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			files[fileID(path, info)] = info.Size()
+		}
+	}
+}
+
+// within returns true if target is dir or is contained by it.
+func within(target, dir string) bool {
+	return target == dir || strings.HasPrefix(target, dir+string(filepath.Separator))
+}
+
+// walkSnapshots calls fn with every readable snapshot written to the .aetherfs directory of the path.
+func walkSnapshots(ctx context.Context, path string, fn func(file string, info fs.FileInfo, snapshot *agentv1.Snapshot) error) error {
+	logger := ctxzap.Extract(ctx)
+	aetherFSDir := filepath.Join(path, aetherFSDirName)
+
+	return filepath.WalkDir(aetherFSDir, func(file string, d fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		case d.IsDir() && isStoreDir(aetherFSDir, file):
+			return filepath.SkipDir
+		case d.IsDir() || !strings.HasSuffix(file, snapshotSuffix):
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		snapshot, err := readSnapshot(ctx, file)
+		if err != nil {
+			logger.Warn("ignoring unreadable snapshot", zap.String("file", file), zap.Error(err))
+			return nil
+		}
+
+		return fn(file, info, snapshot)
+	})
+}
+
+// snapshotLocation returns the tag and directory the snapshot was pulled to along with the group of versions it belongs
+// to. Empty values are returned when they can't be determined.
+func snapshotLocation(path, file string, snapshot *agentv1.Snapshot) (tag, group, dir string) {
+	if snapshot.Dir == "" {
+		return legacyPulledDataset(path, filepath.Join(path, aetherFSDirName), file)
+	}
+
+	ref := dataset.Tag{}
+	if err := ref.UnmarshalText([]byte(snapshot.Tag)); err != nil {
+		return snapshot.Tag, "", snapshot.Dir
+	}
+
+	return snapshot.Tag, ref.Host + "/" + ref.Dataset, snapshot.Dir
+}
+
+// findPulledDatasets returns the datasets pulled to the path using the snapshots written to its .aetherfs directory.
+func findPulledDatasets(ctx context.Context, path string) ([]*pulledDataset, error) {
+	logger := ctxzap.Extract(ctx)
+
+	var pulled []*pulledDataset
+
+	err := walkSnapshots(ctx, path, func(file string, info fs.FileInfo, snapshot *agentv1.Snapshot) error {
+		ds := &pulledDataset{
+			Snapshot: file,
+			Pulled:   info.ModTime(),
+		}
+
+		ds.Tag, ds.Group, ds.Dir = snapshotLocation(path, file, snapshot)
+
+		if ds.Dir == "" || ds.Group == "" {
+			logger.Warn("unable to determine where snapshot was pulled to", zap.String("file", file))
+			return nil
+		}
+
+		if _, err := os.Stat(ds.Dir); err != nil {
+			logger.Warn("ignoring snapshot for missing directory", zap.String("file", file), zap.String("dir", ds.Dir))
+			return nil
+		}
+
+		ds.Files = make(map[string]int64)
+		storeUsage(filepath.Join(path, aetherFSDirName), snapshot, ds.Files)
+
+		err := diskUsage(ds.Dir, ds.Files)
+		if err != nil {
+			return err
+		}
+
+		pulled = append(pulled, ds)
+		return nil
+	})
+
+	return pulled, err
+}
+
+// isStoreDir returns true when dir holds the contents of the block store rather than snapshots.
+func isStoreDir(aetherFSDir, dir string) bool {
+	return dir == filepath.Join(aetherFSDir, blocksDirName) || dir == filepath.Join(aetherFSDir, filesDirName)
+}
+
+// collectGarbage removes the blocks and files from the block store that are no longer referenced by a snapshot, ignoring
+// the snapshots being removed. The number of bytes freed is returned, not including the files that were already
+// accounted for when the datasets were removed.
+func collectGarbage(ctx context.Context, path string, removed map[string]bool, accounted map[string]bool, dryRun bool) (int64, error) {
+	aetherFSDir := filepath.Join(path, aetherFSDirName)
+
+	referenced := make(map[string]bool)
+
+	err := filepath.WalkDir(aetherFSDir, func(file string, d fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		case d.IsDir() && isStoreDir(aetherFSDir, file):
+			return filepath.SkipDir
+		case d.IsDir() || removed[file]:
+			return nil
+		case !strings.HasSuffix(file, snapshotSuffix) && !strings.HasSuffix(file, pendingSuffix):
+			return nil
+		}
+
+		snapshot, err := readSnapshot(ctx, file)
+		if err != nil {
+			// without knowing what it references, nothing can safely be removed
+			return err
+		}
+
+		ds := snapshot.GetDataset()
+		for _, signature := range ds.GetBlocks() {
+			referenced[filepath.Join(aetherFSDir, blocksDirName, signature)] = true
+		}
+
+		for _, f := range ds.GetFiles() {
+			referenced[filepath.Join(aetherFSDir, filesDirName, fileKey(ds, f))] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reclaimed := int64(0)
+
+	for _, dir := range []string{blocksDirName, filesDirName} {
+		entries, err := os.ReadDir(filepath.Join(aetherFSDir, dir))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return reclaimed, err
+		}
+
+		for _, entry := range entries {
+			file := filepath.Join(aetherFSDir, dir, entry.Name())
+
+			// partial files are named after what they'll become
+			if idx := strings.Index(entry.Name(), "."); idx >= 0 {
+				file = filepath.Join(aetherFSDir, dir, entry.Name()[:idx])
+			}
+
+			if referenced[file] {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return reclaimed, err
+			}
+
+			if !dryRun {
+				if err := os.Remove(filepath.Join(aetherFSDir, dir, entry.Name())); err != nil {
+					return reclaimed, err
+				}
+			}
+
+			if !accounted[fileID(file, info)] {
+				reclaimed += info.Size()
+			}
+		}
+	}
+
+	return reclaimed, nil
+}
+
+// subscribedDirs returns the directories of the persisted subscriptions, mapped to whether they're among the newest
+// keepLast subscriptions of their dataset. At least the newest subscription of every dataset is kept so the latest
+// version isn't removed only to be pulled again.
+func subscribedDirs(records []*subscriptionRecord, keepLast int32) map[string]bool {
+	if keepLast < 1 {
+		keepLast = 1
+	}
+
+	groups := make(map[string][]*subscriptionRecord)
+	for _, record := range records {
+		group := record.Tag
+
+		ref := dataset.Tag{}
+		if err := ref.UnmarshalText([]byte(record.Tag)); err == nil {
+			group = ref.Host + "/" + ref.Dataset
+		}
+
+		groups[group] = append(groups[group], record)
+	}
+
+	dirs := make(map[string]bool)
+	for _, group := range groups {
+		// newest first
+		sort.Slice(group, func(i, j int) bool {
+			if !group[i].Created.Equal(group[j].Created) {
+				return group[i].Created.After(group[j].Created)
+			}
+
+			return group[i].Tag > group[j].Tag
+		})
+
+		for i, record := range group {
+			dirs[record.Dir] = dirs[record.Dir] || int32(i) < keepLast
+		}
+	}
+
+	return dirs
+}
+
+// protect marks the directories that must not be removed: those belonging to the newest keepLast subscriptions of each
+// dataset, those still being downloaded, and those that are the target of a symlink in the path or one of the provided
+// symlinks. Directories of older subscriptions are marked as subscribed so their subscription is removed with them.
+func (s *Service) protect(ctx context.Context, path string, keepLast int32, symlinks []string, pulled []*pulledDataset) error {
+	records, err := s.listSubscriptions(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	subscribed := subscribedDirs(records, keepLast)
+
+	dirs := make(map[string]bool)
+	for _, ds := range pulled {
+		dirs[ds.Dir] = true
+	}
+
+	aetherFSDir := filepath.Join(path, aetherFSDirName)
+
+	// only the directories leading up to datasets are searched for symlinks
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case file == aetherFSDir || dirs[file]:
+			return filepath.SkipDir
+		case d.Type()&fs.ModeSymlink != 0:
+			symlinks = append(symlinks, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	targets := make([]string, 0, len(symlinks))
+	for _, symlink := range symlinks {
+		target, err := filepath.EvalSymlinks(symlink)
+		if err != nil {
+			// dangling links don't keep anything alive
+			continue
+		}
+
+		targets = append(targets, target)
+	}
+
+	for _, ds := range pulled {
+		pending := strings.TrimSuffix(ds.Snapshot, snapshotSuffix) + pendingSuffix
+
+		kept, ok := subscribed[ds.Dir]
+		ds.Subscribed = ok
+
+		switch {
+		case kept:
+			ds.Protected = "subscribed"
+			continue
+		case fileExists(pending):
+			ds.Protected = "downloading"
+			continue
+		}
+
+		dir, err := filepath.EvalSymlinks(ds.Dir)
+		if err != nil {
+			dir = ds.Dir
+		}
+
+		for _, target := range targets {
+			if within(target, dir) || within(dir, target) {
+				ds.Protected = "symlinked"
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// removePulledDataset removes the dataset directory and its snapshots along with any directories left empty.
+func removePulledDataset(path string, ds *pulledDataset) error {
+	err := os.RemoveAll(ds.Dir)
+	if err != nil {
+		return err
+	}
+
+	pending := strings.TrimSuffix(ds.Snapshot, snapshotSuffix) + pendingSuffix
+	for _, file := range []string{ds.Snapshot, pending} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	aetherFSDir := filepath.Join(path, aetherFSDirName)
+
+	for _, root := range []struct{ root, dir string }{{path, ds.Dir}, {aetherFSDir, ds.Snapshot}} {
+		for dir := filepath.Dir(root.dir); dir != root.root && len(dir) > len(root.root); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				// not empty
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) Prune(ctx context.Context, request *agentv1.PruneRequest) (*agentv1.PruneResponse, error) {
+	switch {
+	case request.Path == "":
+		return nil, status.Error(codes.InvalidArgument, "path is required")
+	case request.KeepLast < 0 || request.MaxDiskUsage < 0 || request.MinFreeSpace < 0:
+		return nil, status.Error(codes.InvalidArgument, "limits must not be negative")
+	}
+
+	logger := ctxzap.Extract(ctx).With(zap.String("path", request.Path))
+
+	pulled, err := findPulledDatasets(ctx, request.Path)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to find pulled datasets: %v", err)
+	}
+
+	err = s.protect(ctx, request.Path, request.KeepLast, request.Symlinks, pulled)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to determine which datasets are in use: %v", err)
+	}
+
+	// newest first
+	sort.Slice(pulled, func(i, j int) bool {
+		return pulled[i].Pulled.After(pulled[j].Pulled)
+	})
+
+	// files can be shared between datasets, so they're only freed once every dataset using them is removed
+	refs := make(map[string]int)
+	usage := int64(0)
+
+	for _, ds := range pulled {
+		for id, size := range ds.Files {
+			if refs[id] == 0 {
+				usage += size
+			}
+
+			refs[id]++
+		}
+	}
+
+	resp := &agentv1.PruneResponse{}
+	removed := make(map[*pulledDataset]bool)
+	accounted := make(map[string]bool)
+
+	remove := func(ds *pulledDataset, reason string) {
+		freed := int64(0)
+		for id, size := range ds.Files {
+			refs[id]--
+			if refs[id] == 0 {
+				freed += size
+				accounted[id] = true
+			}
+		}
+
+		removed[ds] = true
+		resp.Removed = append(resp.Removed, &agentv1.PrunedSnapshot{
+			Tag:    ds.Tag,
+			Dir:    ds.Dir,
+			Size:   freed,
+			Reason: reason,
+		})
+		resp.Reclaimed += freed
+		usage -= freed
+	}
+
+	if request.KeepLast > 0 {
+		versions := make(map[string]int32)
+
+		for _, ds := range pulled {
+			versions[ds.Group]++
+
+			if versions[ds.Group] > request.KeepLast && ds.Protected == "" {
+				remove(ds, pruneKeepLast)
+			}
+		}
+	}
+
+	// disk limits remove the oldest datasets first
+	oldest := func(done func() bool, reason string) {
+		for i := len(pulled) - 1; i >= 0 && !done(); i-- {
+			ds := pulled[i]
+			if removed[ds] || ds.Protected != "" {
+				continue
+			}
+
+			remove(ds, reason)
+		}
+	}
+
+	if request.MaxDiskUsage > 0 {
+		oldest(func() bool { return usage <= request.MaxDiskUsage }, pruneMaxDiskUsage)
+	}
+
+	if request.MinFreeSpace > 0 {
+		free, err := freeSpace(request.Path)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "failed to determine free space: %v", err)
+		}
+
+		// account for anything already slated for removal along with anything removed below
+		free += resp.Reclaimed
+		before := usage
+
+		oldest(func() bool { return free+(before-usage) >= request.MinFreeSpace }, pruneMinFreeSpace)
+	}
+
+	resp.DiskUsage = usage
+
+	snapshots := make(map[string]bool)
+	for _, ds := range pulled {
+		if !removed[ds] {
+			continue
+		}
+
+		snapshots[ds.Snapshot] = true
+		if request.DryRun {
+			continue
+		}
+
+		logger.Info("pruning dataset", zap.String("tag", ds.Tag), zap.String("dir", ds.Dir))
+
+		// otherwise, the subscription would pull the dataset again
+		if ds.Subscribed {
+			if err := s.Subscriptions.Delete(ctx, ds.Dir); err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to remove subscription for %s: %v", ds.Dir, err)
+			}
+		}
+
+		err := removePulledDataset(request.Path, ds)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to remove %s: %v", ds.Dir, err)
+		}
+	}
+
+	resp.StoreReclaimed, err = collectGarbage(ctx, request.Path, snapshots, accounted, request.DryRun)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to clean up block store: %v", err)
+	}
+
+	return resp, nil
+}
+
+// PruneEvery applies the policy to every path with persisted subscriptions on the provided interval until the context
+// is cancelled.
+func (s *Service) PruneEvery(ctx context.Context, interval time.Duration, policy *agentv1.PruneRequest) {
+	logger := ctxzap.Extract(ctx)
+	clock := clocks.Extract(ctx)
+
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+
+		records, err := s.listSubscriptions(ctx, "")
+		if err != nil {
+			logger.Error("failed to list subscriptions", zap.Error(err))
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, record := range records {
+			if seen[record.Path] {
+				continue
+			}
+			seen[record.Path] = true
+
+			request := &agentv1.PruneRequest{
+				Path:         record.Path,
+				KeepLast:     policy.KeepLast,
+				MaxDiskUsage: policy.MaxDiskUsage,
+				MinFreeSpace: policy.MinFreeSpace,
+				Symlinks:     policy.Symlinks,
+			}
+
+			resp, err := s.Prune(ctx, request)
+			if err != nil {
+				logger.Error("failed to prune", zap.String("path", record.Path), zap.Error(err))
+				continue
+			}
+
+			if len(resp.Removed) > 0 {
+				logger.Info("pruned datasets", zap.String("path", record.Path),
+					zap.Int("removed", len(resp.Removed)), zap.Int64("reclaimed", resp.Reclaimed))
+			}
+		}
+	}
+}
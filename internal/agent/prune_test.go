@@ -0,0 +1,259 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+)
+
+func TestSubscribedDirs(t *testing.T) {
+	base := time.Now()
+
+	records := []*subscriptionRecord{
+		{Tag: "hub/a:v1", Dir: "a/v1", Created: base},
+		{Tag: "hub/a:v3", Dir: "a/v3", Created: base.Add(2 * time.Minute)},
+		{Tag: "hub/a:v2", Dir: "a/v2", Created: base.Add(time.Minute)},
+		{Tag: "hub/b:v1", Dir: "b/v1", Created: base},
+		{Tag: "other/a:v1", Dir: "other/a/v1", Created: base},
+	}
+
+	testCases := []struct {
+		name     string
+		keepLast int32
+		expected map[string]bool
+	}{
+		{
+			name:     "newest when unset",
+			expected: map[string]bool{"a/v1": false, "a/v2": false, "a/v3": true, "b/v1": true, "other/a/v1": true},
+		},
+		{
+			name:     "keep last",
+			keepLast: 2,
+			expected: map[string]bool{"a/v1": false, "a/v2": true, "a/v3": true, "b/v1": true, "other/a/v1": true},
+		},
+		{
+			name:     "more than subscribed",
+			keepLast: 5,
+			expected: map[string]bool{"a/v1": true, "a/v2": true, "a/v3": true, "b/v1": true, "other/a/v1": true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, subscribedDirs(records, testCase.keepLast))
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	type pull struct {
+		tag      string
+		size     int
+		pending  bool   // the download hasn't finished
+		hardlink string // the tag whose file is linked rather than written
+	}
+
+	testCases := []struct {
+		name       string
+		pulled     []pull   // oldest first
+		subscribed []string // oldest first
+		symlinked  string
+		request    *agentv1.PruneRequest
+
+		removed     []string
+		reclaimed   int64
+		diskUsage   int64
+		remaining   []string // the subscriptions left afterwards
+		skipWindows bool
+	}{
+		{
+			name:      "keep last of each dataset",
+			pulled:    []pull{{tag: "a:v1", size: 1}, {tag: "a:v2", size: 2}, {tag: "a:v3", size: 4}, {tag: "b:v1", size: 8}},
+			request:   &agentv1.PruneRequest{KeepLast: 2},
+			removed:   []string{"a:v1"},
+			reclaimed: 1,
+			diskUsage: 14,
+		},
+		{
+			name:      "max disk usage removes the oldest",
+			pulled:    []pull{{tag: "b:v1", size: 8}, {tag: "a:v1", size: 1}, {tag: "a:v2", size: 2}},
+			request:   &agentv1.PruneRequest{MaxDiskUsage: 3},
+			removed:   []string{"b:v1"},
+			reclaimed: 8,
+			diskUsage: 3,
+		},
+		{
+			name:      "pending download",
+			pulled:    []pull{{tag: "a:v1", size: 1, pending: true}, {tag: "a:v2", size: 2}},
+			request:   &agentv1.PruneRequest{KeepLast: 1},
+			diskUsage: 3,
+		},
+		{
+			name:      "symlink target",
+			pulled:    []pull{{tag: "a:v1", size: 1}, {tag: "a:v2", size: 2}},
+			symlinked: "a:v1",
+			request:   &agentv1.PruneRequest{KeepLast: 1},
+			diskUsage: 3,
+		},
+		{
+			name:       "subscriptions beyond keep last",
+			pulled:     []pull{{tag: "a:v1", size: 1}, {tag: "a:v2", size: 2}, {tag: "a:v3", size: 4}},
+			subscribed: []string{"a:v1", "a:v2", "a:v3"},
+			request:    &agentv1.PruneRequest{KeepLast: 2},
+			removed:    []string{"a:v1"},
+			reclaimed:  1,
+			diskUsage:  6,
+			remaining:  []string{"a:v2", "a:v3"},
+		},
+		{
+			name:       "newest subscription kept under disk limits",
+			pulled:     []pull{{tag: "a:v1", size: 1}, {tag: "a:v2", size: 2}},
+			subscribed: []string{"a:v1", "a:v2"},
+			request:    &agentv1.PruneRequest{MaxDiskUsage: 1},
+			removed:    []string{"a:v1"},
+			reclaimed:  1,
+			diskUsage:  2,
+			remaining:  []string{"a:v2"},
+		},
+		{
+			name:       "dry run keeps subscriptions",
+			pulled:     []pull{{tag: "a:v1", size: 1}, {tag: "a:v2", size: 2}},
+			subscribed: []string{"a:v1", "a:v2"},
+			request:    &agentv1.PruneRequest{KeepLast: 1, DryRun: true},
+			removed:    []string{"a:v1"},
+			reclaimed:  1,
+			diskUsage:  2,
+			remaining:  []string{"a:v1", "a:v2"},
+		},
+		{
+			name:        "hardlinks are counted once",
+			pulled:      []pull{{tag: "a:v1", size: 4}, {tag: "a:v2", hardlink: "a:v1"}},
+			request:     &agentv1.PruneRequest{KeepLast: 1},
+			removed:     []string{"a:v1"},
+			diskUsage:   4,
+			skipWindows: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.skipWindows && runtime.GOOS == "windows" {
+				t.Skip("files are identified by their path on windows")
+			}
+
+			ctx := context.Background()
+			path := t.TempDir()
+			aetherFSDir := filepath.Join(path, aetherFSDirName)
+
+			db, err := local.Open(ctx, t.TempDir(), "")
+			require.NoError(t, err)
+			defer db.Close()
+
+			svc := &Service{Subscriptions: db.Subscriptions()}
+
+			subs := make(map[string]*subscription)
+			base := time.Now().Add(-time.Hour)
+
+			for i, pulled := range testCase.pulled {
+				ref := dataset.Tag{}
+				require.NoError(t, ref.UnmarshalText([]byte("hub/"+pulled.tag)))
+
+				sub := &subscription{Ref: ref, Dir: filepath.Join(path, ref.Dataset, ref.Version)}
+				subs[pulled.tag] = sub
+
+				file := filepath.Join(sub.Dir, "data.bin")
+				require.NoError(t, os.MkdirAll(sub.Dir, dirPermissions))
+
+				if pulled.hardlink != "" {
+					require.NoError(t, os.Link(filepath.Join(subs[pulled.hardlink].Dir, "data.bin"), file))
+				} else {
+					require.NoError(t, ioutil.WriteFile(file, []byte(strings.Repeat("x", pulled.size)), filePermissions))
+				}
+
+				snapshot := &agentv1.Snapshot{Tag: ref.String(), Dir: sub.Dir, Dataset: &datasetv1.Dataset{}}
+				require.NoError(t, writeSnapshot(sub.snapshotFile(aetherFSDir), snapshot))
+
+				if pulled.pending {
+					require.NoError(t, writeSnapshot(sub.pendingFile(aetherFSDir), snapshot))
+				}
+
+				pulledAt := base.Add(time.Duration(i) * time.Minute)
+				require.NoError(t, os.Chtimes(sub.snapshotFile(aetherFSDir), pulledAt, pulledAt))
+			}
+
+			for i, tag := range testCase.subscribed {
+				sub := subs[tag]
+				require.NoError(t, svc.Subscriptions.Put(ctx, sub.Dir, &subscriptionRecord{
+					Path:    path,
+					Tag:     sub.Ref.String(),
+					Dir:     sub.Dir,
+					Created: base.Add(time.Duration(i) * time.Minute),
+				}))
+			}
+
+			if testCase.symlinked != "" {
+				require.NoError(t, os.Symlink(subs[testCase.symlinked].Dir, filepath.Join(path, "current")))
+			}
+
+			request := testCase.request
+			request.Path = path
+
+			resp, err := svc.Prune(ctx, request)
+			require.NoError(t, err)
+
+			removed := make([]string, 0, len(resp.Removed))
+			for _, snapshot := range resp.Removed {
+				removed = append(removed, strings.TrimPrefix(snapshot.Tag, "hub/"))
+			}
+
+			require.ElementsMatch(t, testCase.removed, removed)
+			require.Equal(t, testCase.reclaimed, resp.Reclaimed)
+			require.Equal(t, testCase.diskUsage, resp.DiskUsage)
+
+			for tag, sub := range subs {
+				_, err := os.Stat(sub.Dir)
+
+				if request.DryRun || !contains(testCase.removed, tag) {
+					require.NoError(t, err, tag)
+				} else {
+					require.True(t, os.IsNotExist(err), tag)
+				}
+			}
+
+			records, err := svc.listSubscriptions(ctx, path)
+			require.NoError(t, err)
+
+			remaining := make([]string, 0, len(records))
+			for _, record := range records {
+				remaining = append(remaining, strings.TrimPrefix(record.Tag, "hub/"))
+			}
+
+			require.ElementsMatch(t, testCase.remaining, remaining)
+		})
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,733 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/afero"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/filter"
+	"github.com/mjpitz/myago/vfs"
+	"github.com/mjpitz/myago/zaputil"
+)
+
+// buildBlockTable walks the provided root and produces the list of files in the dataset along with a block table
+// detailing which file segments belong to which block. Large files are broken up into multiple blocks while small
+// files are globbed together into a single block.
+func buildBlockTable(ctx context.Context, root string, blockSize int64, selected *filter.Filter) ([]*datasetv1.File, []*blocks.Block, error) {
+	var files []*datasetv1.File
+	var allBlocks []*blocks.Block
+	current := &blocks.Block{}
+
+	err := afero.Walk(vfs.Extract(ctx), root, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(path, root), "/")
+
+		switch {
+		case info.IsDir() && name != "" && selected.Excluded(name, true):
+			return filepath.SkipDir
+		case !info.Mode().IsRegular():
+			// skip non-regular files for now
+			return nil
+		case !selected.Match(name):
+			return nil
+		}
+
+		// store some local metadata
+		file := &datasetv1.File{
+			Name:         name,
+			Size:         info.Size(),
+			LastModified: timestamppb.New(info.ModTime()),
+		}
+		files = append(files, file)
+
+		remainingInFile := file.Size
+		offset := int64(0)
+
+		for remainingInFile > 0 {
+			// how many bytes to grab
+			size := blockSize - current.Size
+			if remainingInFile < size {
+				size = remainingInFile
+			}
+
+			// update block table
+			current.Segments = append(current.Segments, &blocks.FileSegment{
+				FilePath: path,
+				Offset:   offset,
+				Size:     size,
+			})
+			current.Size += size
+
+			// advance pointer and decrement step
+			offset += size
+			remainingInFile -= size
+
+			switch {
+			case current.Size > blockSize:
+				// pebcak - programmer error
+				return fmt.Errorf("block overflow")
+
+			case current.Size == blockSize:
+				// roll over full blocks
+				allBlocks = append(allBlocks, current)
+				current = &blocks.Block{}
+			}
+		}
+
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil, status.Errorf(codes.InvalidArgument, "associated file path does not exist")
+	case err != nil:
+		return nil, nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	// catch any partial blocks
+	if current.Size > 0 {
+		allBlocks = append(allBlocks, current)
+	}
+
+	return files, allBlocks, nil
+}
+
+// publishFilter constructs the filter used to select which files under root are published. The agent's own snapshot
+// directory and the root .afsignore file are always excluded, followed by the patterns found in the root .afsignore
+// file and those on the request. Only the .afsignore file at the root of the dataset is read, those in nested
+// directories are published like any other file.
+func publishFilter(ctx context.Context, request *agentv1.PublishRequest) (*filter.Filter, error) {
+	exclude := []string{aetherFSDirName + "/", "/" + filter.IgnoreFile}
+
+	file, err := vfs.Extract(ctx).Open(filepath.Join(request.Path, filter.IgnoreFile))
+	switch {
+	case err == nil:
+		defer file.Close()
+
+		patterns, err := filter.ParseIgnore(file)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to read %s", filter.IgnoreFile)
+		}
+
+		exclude = append(exclude, patterns...)
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, status.Errorf(codes.Internal, "failed to open %s", filter.IgnoreFile)
+	}
+
+	selected, err := filter.New(request.Include, append(exclude, request.Exclude...))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	return selected, nil
+}
+
+// addToSummary accounts for a block in the summary. Blocks that appear in the dataset more than once are only counted
+// the first time they're seen.
+func addToSummary(summary *agentv1.PublishSummary, block *blocks.Block, signature string, missing, seen map[string]bool) {
+	summary.TotalSize += block.Size
+
+	if seen[signature] {
+		return
+	}
+	seen[signature] = true
+
+	if missing[signature] {
+		summary.NewBlocks++
+		summary.UploadSize += block.Size
+	} else {
+		summary.ExistingBlocks++
+	}
+}
+
+// publishWindowSize bounds how much block data is held in memory between hashing blocks and uploading the ones the
+// host is missing. Windows always contain at least one block.
+const publishWindowSize = int64(256 * blocks.Mebibyte)
+
+// nextWindow returns the end of the window of blocks starting at start.
+func nextWindow(allBlocks []*blocks.Block, start int) int {
+	end := start
+	size := int64(0)
+
+	for end < len(allBlocks) && end-start < blocks.LookupBatchSize {
+		if end > start && size+allBlocks[end].Size > publishWindowSize {
+			break
+		}
+
+		size += allBlocks[end].Size
+		end++
+	}
+
+	return end
+}
+
+// publishOptions controls how the dataset on disk is published to a host.
+type publishOptions struct {
+	Root       string
+	DryRun     bool
+	Include    []string
+	Exclude    []string
+	Filter     *filter.Filter
+	Signatures *signatureCache
+	Job        *job
+
+	// Release stops the publish from holding up a graceful shutdown. It's safe to call more than once.
+	Release func()
+
+	// ExpectedPrevious contains the digests each host's tags (name:version) must point at before they're updated.
+	ExpectedPrevious map[string]map[string]string
+}
+
+func (s *Service) publish(ctx context.Context, host string, request *datasetv1.PublishRequest, opts publishOptions) (*agentv1.PublishSummary, error) {
+	conn, err := s.connectionFor(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	blockAPI := blockv1.NewBlockAPIClient(conn)
+	datasetAPI := datasetv1.NewDatasetAPIClient(conn)
+
+	logger := ctxzap.Extract(ctx).With(zap.String("host", host))
+
+	if len(request.Tags) > 0 {
+		ctx = afs.WithDataset(ctx, request.Tags[0].Name)
+	}
+
+	files, allBlocks, err := buildBlockTable(ctx, opts.Root, int64(request.Dataset.BlockSize), opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Dataset.Files = files
+
+	previous, err := s.loadJournal(ctx, host, opts.Root)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to load journal: %v", err)
+	}
+
+	// signatures recorded by a previous attempt, these may only cover the first part of the dataset
+	var known []string
+	confirmed := make(map[string]bool)
+
+	resume := previous.matches(request.Dataset.BlockSize, files, opts) && len(previous.Signatures) <= len(allBlocks)
+	if resume {
+		logger.Info("resuming publish", zap.Int("uploaded", len(previous.Uploaded)))
+
+		// files haven't changed since the last attempt, so neither have their signatures
+		known = previous.Signatures
+		for _, signature := range previous.Uploaded {
+			confirmed[signature] = true
+		}
+	}
+
+	var progress *journalWriter
+	if s.Journals != nil && !opts.DryRun {
+		tags := make([]string, 0, len(request.Tags))
+		expectedPrevious := make(map[string]string, len(request.ExpectedPrevious))
+
+		for _, tag := range request.Tags {
+			ref := &dataset.Tag{Host: host, Dataset: tag.Name, Version: tag.Version}
+			tags = append(tags, ref.String())
+
+			if digest, ok := request.ExpectedPrevious[tag.Name+":"+tag.Version]; ok {
+				expectedPrevious[ref.String()] = digest
+			}
+		}
+
+		progress = &journalWriter{
+			store: s.Journals,
+			journal: &journal{
+				Root:             opts.Root,
+				Host:             host,
+				Tags:             tags,
+				BlockSize:        request.Dataset.BlockSize,
+				Include:          opts.Include,
+				Exclude:          opts.Exclude,
+				ExpectedPrevious: expectedPrevious,
+				Files:            journalFiles(files),
+			},
+		}
+
+		err = progress.start(ctx, resume)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to write journal: %v", err)
+		}
+	}
+
+	summary := &agentv1.PublishSummary{
+		FileCount:     int64(len(files)),
+		ResumedBlocks: int64(len(confirmed)),
+	}
+
+	opts.Job.host(host, func(progress *agentv1.JobProgress) {
+		progress.BlocksDone = 0
+		progress.BlocksTotal = 0
+		progress.BytesDone = 0
+		progress.BytesTotal = 0
+		progress.Error = ""
+	})
+
+	filesByPath := make(map[string]*datasetv1.File, len(files))
+	for _, file := range files {
+		filesByPath[filepath.Join(opts.Root, file.Name)] = file
+	}
+
+	// blocks are hashed and uploaded a window at a time so each block is only read once. the buffers are re-used
+	// between windows to keep memory usage low and reduce garbage collection.
+	buffers := make([][]byte, 0)
+	buffer := func(i int, size int64) []byte {
+		for len(buffers) <= i {
+			buffers = append(buffers, make([]byte, request.Dataset.BlockSize))
+		}
+
+		return buffers[i][:size]
+	}
+
+	seen := make(map[string]bool, len(allBlocks))
+
+	for start := 0; start < len(allBlocks); {
+		end := nextWindow(allBlocks, start)
+		window := allBlocks[start:end]
+
+		// data holds the contents of the blocks that had to be read in order to compute their signature
+		data := make([][]byte, len(window))
+		signatures := make([]string, len(window))
+
+		for i, block := range window {
+			idx := start + i
+			key := blockKey(block, filesByPath)
+
+			if idx < len(known) {
+				signatures[i] = known[idx]
+				continue
+			} else if signature, ok := opts.Signatures.get(key); ok {
+				signatures[i] = signature
+				continue
+			}
+
+			data[i] = buffer(i, block.Size)
+
+			_, err := block.Read(data[i])
+			if err != nil && err != io.EOF {
+				return nil, status.Errorf(codes.Internal, err.Error())
+			}
+
+			signatures[i], err = blocks.ComputeSignature("sha256", data[i])
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, err.Error())
+			}
+
+			opts.Signatures.put(key, signatures[i])
+		}
+
+		request.Dataset.Blocks = append(request.Dataset.Blocks, signatures...)
+
+		// only probe for the blocks that haven't already been confirmed by a previous attempt or an earlier window
+		unconfirmed := make([]string, 0, len(signatures))
+		for _, signature := range signatures {
+			if !confirmed[signature] && !seen[signature] {
+				unconfirmed = append(unconfirmed, signature)
+			}
+		}
+
+		missing, err := afs.MissingBlocks(ctx, blockAPI, unconfirmed)
+		if err != nil {
+			return nil, err
+		}
+
+		newBlocks, uploadSize := summary.NewBlocks, summary.UploadSize
+		for i, block := range window {
+			addToSummary(summary, block, signatures[i], missing, seen)
+		}
+
+		opts.Job.host(host, func(progress *agentv1.JobProgress) {
+			progress.BlocksTotal += summary.NewBlocks - newBlocks
+			progress.BytesTotal += summary.UploadSize - uploadSize
+		})
+
+		err = progress.signatures(ctx, signatures)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to write journal: %v", err)
+		}
+
+		for i, block := range window {
+			signature := signatures[i]
+			if opts.DryRun || !missing[signature] {
+				continue
+			}
+
+			if data[i] == nil {
+				// the signature was already known, so the block hasn't been read yet
+				data[i] = buffer(i, block.Size)
+
+				_, err := block.Read(data[i])
+				if err != nil && err != io.EOF {
+					return nil, status.Errorf(codes.Internal, err.Error())
+				}
+			}
+
+			logger.Info("uploading block", zap.String("signature", signature))
+
+			err = afs.Upload(ctx, blockAPI, signature, data[i])
+			if err != nil {
+				return nil, err
+			}
+
+			err = progress.uploaded(ctx, signature)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to write journal: %v", err)
+			}
+
+			opts.Job.host(host, func(progress *agentv1.JobProgress) {
+				progress.BlocksDone++
+				progress.BytesDone += block.Size
+			})
+
+			// datasets can contain the same block multiple times, only upload it once
+			delete(missing, signature)
+		}
+
+		start = end
+	}
+
+	summary.BlockCount = int64(len(seen))
+	if summary.TotalSize > 0 {
+		summary.DedupRatio = 1 - float64(summary.UploadSize)/float64(summary.TotalSize)
+	}
+
+	if opts.DryRun {
+		return summary, nil
+	}
+
+	logger.Info("publishing dataset with tags",
+		zap.Int64("total", summary.BlockCount),
+		zap.Int64("uploaded", summary.NewBlocks))
+
+	_, err = datasetAPI.Publish(ctx, request)
+	if err != nil {
+		if permanent(err) {
+			// resuming would only fail the same way again
+			if err := progress.complete(ctx); err != nil {
+				logger.Warn("failed to remove journal", zap.Error(err))
+			}
+		}
+
+		return nil, err
+	}
+
+	err = progress.complete(ctx)
+	if err != nil {
+		logger.Warn("failed to remove journal", zap.Error(err))
+	}
+
+	return summary, nil
+}
+
+func (s *Service) publishAsync(ctx context.Context, request *agentv1.PublishRequest, tagsByHost map[string][]*datasetv1.Tag, opts publishOptions) (*agentv1.PublishResponse, error) {
+	group, ctx := errgroup.WithContext(ctx)
+
+	resp := &agentv1.PublishResponse{
+		Summaries: make(map[string]*agentv1.PublishSummary, len(tagsByHost)),
+	}
+	mu := sync.Mutex{}
+
+	publishAsync := func(host string, tags []*datasetv1.Tag) {
+		req := &datasetv1.PublishRequest{
+			Dataset: &datasetv1.Dataset{
+				BlockSize: request.BlockSize,
+			},
+			Tags:             tags,
+			ExpectedPrevious: opts.ExpectedPrevious[host],
+		}
+
+		group.Go(func() error {
+			zaputil.Extract(ctx).Info("running", zap.String("target", host), zap.Stringer("req", req))
+			summary, err := s.publish(ctx, host, req, opts)
+			if err != nil {
+				opts.Job.fail(host, err)
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			resp.Summaries[host] = summary
+			return nil
+		})
+	}
+
+	for host, tags := range tagsByHost {
+		publishAsync(host, tags)
+	}
+
+	err := group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// resumeRequest fills in the details of the request from the journals recorded for its path.
+func (s *Service) resumeRequest(ctx context.Context, request *agentv1.PublishRequest) error {
+	journals, err := s.listJournals(ctx, request.Path)
+	switch {
+	case err != nil:
+		return status.Errorf(codes.Internal, "failed to load journals: %v", err)
+	case len(journals) == 0:
+		return status.Errorf(codes.NotFound, "no interrupted publish found for %s", request.Path)
+	}
+
+	if len(request.Tags) == 0 {
+		for _, j := range journals {
+			request.Tags = append(request.Tags, j.Tags...)
+		}
+	}
+
+	if request.BlockSize == 0 {
+		request.BlockSize = journals[0].BlockSize
+	}
+
+	if len(request.Include) == 0 && len(request.Exclude) == 0 {
+		request.Include = journals[0].Include
+		request.Exclude = journals[0].Exclude
+	}
+
+	if len(request.ExpectedPrevious) == 0 {
+		// tags may have moved since the publish was interrupted, so it must not overwrite them
+		requested := make(map[string]bool, len(request.Tags))
+		for _, tag := range request.Tags {
+			if normalized, err := normalizeTag(tag); err == nil {
+				requested[normalized] = true
+			}
+		}
+
+		for _, j := range journals {
+			for tag, digest := range j.ExpectedPrevious {
+				if !requested[tag] {
+					continue
+				}
+
+				if request.ExpectedPrevious == nil {
+					request.ExpectedPrevious = make(map[string]string)
+				}
+
+				request.ExpectedPrevious[tag] = digest
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResumePublishing continues any publishes that were interrupted the last time the agent was stopped. A journal that
+// can't be resumed is logged and skipped so it doesn't hold up the rest. Journals for paths that no longer exist, or
+// whose publish can never succeed, are removed.
+func (s *Service) ResumePublishing(ctx context.Context) error {
+	logger := ctxzap.Extract(ctx)
+
+	journals, err := s.listJournals(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, j := range journals {
+		if seen[j.Root] {
+			continue
+		}
+		seen[j.Root] = true
+
+		logger := logger.With(zap.String("path", j.Root))
+
+		_, err := vfs.Extract(ctx).Stat(j.Root)
+		if err == nil {
+			logger.Info("resuming interrupted publish")
+
+			_, err = s.Publish(ctx, &agentv1.PublishRequest{
+				Path:   j.Root,
+				Resume: true,
+			})
+		}
+
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, fs.ErrNotExist) || permanent(err):
+			logger.Warn("discarding interrupted publish", zap.Error(err))
+			s.removeJournals(ctx, j.Root)
+		default:
+			logger.Error("failed to resume interrupted publish", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// removeJournals removes every journal recorded for the root.
+func (s *Service) removeJournals(ctx context.Context, root string) {
+	journals, err := s.listJournals(ctx, root)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("failed to list journals", zap.String("path", root), zap.Error(err))
+		return
+	}
+
+	for _, j := range journals {
+		err := removeJournal(ctx, s.Journals, j.Host, j.Root)
+		if err != nil {
+			ctxzap.Extract(ctx).Warn("failed to remove journal", zap.String("path", root), zap.Error(err))
+		}
+	}
+}
+
+func (s *Service) Publish(ctx context.Context, request *agentv1.PublishRequest) (*agentv1.PublishResponse, error) {
+	if request.Resume {
+		err := s.resumeRequest(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tagsByHost := make(map[string][]*datasetv1.Tag)
+	requested := make(map[string]bool)
+
+	for _, tag := range request.Tags {
+		t := &dataset.Tag{}
+		err := t.UnmarshalText([]byte(tag))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid tag %s", tag)
+		}
+
+		tagsByHost[t.Host] = append(tagsByHost[t.Host], &datasetv1.Tag{
+			Name:    t.Dataset,
+			Version: t.Version,
+		})
+
+		requested[t.String()] = true
+	}
+
+	expectedPrevious := make(map[string]map[string]string)
+	for tag, digest := range request.ExpectedPrevious {
+		t := &dataset.Tag{}
+		err := t.UnmarshalText([]byte(tag))
+		switch {
+		case err != nil:
+			return nil, status.Errorf(codes.InvalidArgument, "invalid tag %s", tag)
+		case !requested[t.String()]:
+			return nil, status.Errorf(codes.InvalidArgument, "expected previous digest provided for a tag that isn't being published: %s", tag)
+		case digest != "" && !dataset.IsDigest(digest):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid expected previous digest for %s: %s", tag, digest)
+		}
+
+		if expectedPrevious[t.Host] == nil {
+			expectedPrevious[t.Host] = make(map[string]string)
+		}
+
+		expectedPrevious[t.Host][t.Dataset+":"+t.Version] = digest
+	}
+
+	if request.Watch && request.DryRun {
+		return nil, status.Error(codes.InvalidArgument, "watch cannot be used with dry_run")
+	}
+
+	if request.Watch && len(request.ExpectedPrevious) > 0 {
+		// the tags move on after the first publish, so every republish would fail
+		return nil, status.Error(codes.InvalidArgument, "watch cannot be used with expected_previous")
+	}
+
+	selected, err := publishFilter(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := publishOptions{
+		Root:             request.Path,
+		DryRun:           request.DryRun,
+		Include:          request.Include,
+		Exclude:          request.Exclude,
+		Filter:           selected,
+		ExpectedPrevious: expectedPrevious,
+	}
+
+	run := s.publishAsync
+	if request.Watch {
+		run = s.watch
+	}
+
+	if atomic.LoadInt32(&s.shutdown) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "shutdown already initiated")
+	}
+
+	opts.Job, err = s.jobs.start(ctx, agentv1.JobKind_JOB_KIND_PUBLISH, request.Path, request.Tags)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create job: %v", err)
+	}
+
+	// the publish is ongoing from the moment it's accepted, watches release it once their initial publish is done
+	atomic.AddInt32(&s.ongoing, 1)
+
+	release := sync.Once{}
+	opts.Release = func() {
+		release.Do(func() { atomic.AddInt32(&s.ongoing, -1) })
+	}
+
+	track := func(ctx context.Context) (*agentv1.PublishResponse, error) {
+		defer opts.Release()
+
+		resp, err := run(opts.Job.cancellable(ctx), request, tagsByHost, opts)
+		if resp != nil {
+			resp.JobId = opts.Job.id()
+		}
+
+		opts.Job.complete(ctx, err, func(state *agentv1.Job) {
+			state.Publish = resp
+		})
+
+		return resp, err
+	}
+
+	if request.Sync {
+		return track(ctx)
+	}
+
+	// returning cancels the request context, so work continues on a detached one
+	ctx = detach(ctx)
+
+	go func() {
+		_, err := track(ctx)
+		if err != nil {
+			ctxzap.Extract(ctx).Error("failed to publish dataset", zap.Error(err))
+		}
+	}()
+
+	return &agentv1.PublishResponse{
+		JobId: opts.Job.id(),
+	}, nil
+}
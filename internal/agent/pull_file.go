@@ -0,0 +1,233 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/filter"
+	"github.com/mjpitz/myago/vfs"
+)
+
+// PullFileVersion is the current version of the pull file format.
+const PullFileVersion = "v1"
+
+// PullFile declares the datasets an application depends on. It's intended to be checked into source control alongside
+// the application and consumed using `aetherfs pull -c application.afs.yaml <path>`.
+//
+//	version: v1
+//	datasets:
+//	  - name: maxmind
+//	    tag: v1
+//	  - host: private.company.io
+//	    name: "@models/classifier"
+//	    digest: sha256:...
+//	    path: models
+//	    include:
+//	      - prod/*.onnx
+//	    exclude:
+//	      - "*.tmp.onnx"
+type PullFile struct {
+	Version  string            `json:"version"  yaml:"version"`
+	Datasets []PullFileDataset `json:"datasets" yaml:"datasets"`
+}
+
+// PullFileDataset declares a single dataset dependency.
+type PullFileDataset struct {
+	Host    string   `json:"host,omitempty"    yaml:"host,omitempty"`    // defaults to localhost:8080
+	Name    string   `json:"name"              yaml:"name"`              // the name of the dataset
+	Tag     string   `json:"tag,omitempty"     yaml:"tag,omitempty"`     // defaults to latest when no digest is set
+	Digest  string   `json:"digest,omitempty"  yaml:"digest,omitempty"`  // pins the dataset to an immutable version
+	Path    string   `json:"path,omitempty"    yaml:"path,omitempty"`    // defaults to <name>/<tag or digest>
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"` // only pulls the matching files
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"` // skips the matching files
+}
+
+// Ref returns the tag used to resolve the dataset. When both a tag and a digest are provided, the tag is resolved and
+// the digest is used to verify the result.
+func (d PullFileDataset) Ref() dataset.Tag {
+	ref := dataset.Tag{
+		Host:    d.Host,
+		Dataset: d.Name,
+		Version: d.Tag,
+	}
+
+	if ref.Host == "" {
+		ref.Host = dataset.DefaultHost
+	}
+
+	switch {
+	case ref.Version == "" && d.Digest != "":
+		ref.Version = d.Digest
+	case ref.Version == "":
+		ref.Version = "latest"
+	}
+
+	return ref
+}
+
+// Dir returns the directory, relative to the pull path, where the dataset is written.
+func (d PullFileDataset) Dir() string {
+	if d.Path != "" {
+		return filepath.FromSlash(path.Clean(d.Path))
+	}
+
+	ref := d.Ref()
+	return filepath.Join(ref.Dataset, ref.Version)
+}
+
+// Validate checks the pull file for errors, reporting every problem it finds at once.
+func (f *PullFile) Validate() error {
+	var problems []string
+
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch f.Version {
+	case "":
+		report("version is required")
+	case PullFileVersion:
+	default:
+		report("unsupported version %q, expected %q", f.Version, PullFileVersion)
+	}
+
+	if len(f.Datasets) == 0 {
+		report("datasets: at least one dataset is required")
+	}
+
+	dirs := make(map[string]int)
+	versions := make(map[string]int)
+	for i, ds := range f.Datasets {
+		prefix := fmt.Sprintf("datasets[%d]", i)
+
+		switch {
+		case ds.Name == "":
+			report("%s: name is required", prefix)
+		case !validName(ds.Name):
+			report("%s: invalid name %q", prefix, ds.Name)
+		}
+
+		if strings.ContainsAny(ds.Host, "/@") {
+			report("%s: invalid host %q", prefix, ds.Host)
+		}
+
+		if strings.ContainsAny(ds.Tag, ":@/") {
+			report("%s: invalid tag %q", prefix, ds.Tag)
+		}
+
+		if ds.Digest != "" && !dataset.IsDigest(ds.Digest) {
+			report("%s: invalid digest %q, expected sha256:<hex>", prefix, ds.Digest)
+		}
+
+		validPath := true
+		if ds.Path != "" {
+			clean := path.Clean(filepath.ToSlash(ds.Path))
+
+			switch {
+			case path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../"):
+				report("%s: path must be relative to the pull directory", prefix)
+				validPath = false
+			case clean == "." || clean == aetherFSDirName || strings.HasPrefix(clean, aetherFSDirName+"/"):
+				report("%s: path %q is reserved", prefix, ds.Path)
+				validPath = false
+			}
+		}
+
+		if _, err := filter.New(ds.Include, ds.Exclude); err != nil {
+			report("%s: %v", prefix, err)
+		}
+
+		if ds.Name != "" && validPath {
+			dir := ds.Dir()
+			ref := ds.Ref()
+			version := ref.Dataset + ":" + ref.Version
+
+			// the snapshot of what was written is recorded per version, so each version can only be pulled once
+			if j, ok := dirs[dir]; ok {
+				report("%s: path %q is already used by datasets[%d]", prefix, filepath.ToSlash(dir), j)
+			} else if j, ok := versions[version]; ok {
+				report("%s: %s is already pulled by datasets[%d]", prefix, version, j)
+			} else {
+				dirs[dir] = i
+				versions[version] = i
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// ParsePullFile decodes and validates a pull file. The format is determined using the extension of the file name
+// (.json, .yaml, or .yml). Unknown fields are rejected to catch typos early.
+func ParsePullFile(name string, r io.Reader) (*PullFile, error) {
+	pullFile := &PullFile{}
+
+	var err error
+	switch filepath.Ext(name) {
+	case ".json":
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(pullFile)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(r)
+		decoder.KnownFields(true)
+		err = decoder.Decode(pullFile)
+	default:
+		return nil, fmt.Errorf("%s: unsupported file extension, expected .yaml, .yml, or .json", name)
+	}
+
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if err = pullFile.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: invalid pull file\n%s", name, indent(err.Error()))
+	}
+
+	return pullFile, nil
+}
+
+// LoadPullFile reads and validates the pull file at the provided path.
+func LoadPullFile(ctx context.Context, name string) (*PullFile, error) {
+	data, err := afero.ReadFile(vfs.Extract(ctx), name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePullFile(name, bytes.NewReader(data))
+}
+
+// validName checks that the name is either a plain dataset name or a scoped one (@scope/name).
+func validName(name string) bool {
+	scope, base := "", name
+	if idx := strings.Index(name, "/"); idx >= 0 {
+		scope, base = name[:idx], name[idx+1:]
+
+		if len(scope) < 2 || !strings.HasPrefix(scope, "@") || strings.ContainsAny(scope[1:], ":@") {
+			return false
+		}
+	}
+
+	return base != "" && !strings.ContainsAny(base, ":@/")
+}
+
+func indent(msg string) string {
+	return "  " + strings.ReplaceAll(msg, "\n", "\n  ")
+}
@@ -0,0 +1,146 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjpitz/aetherfs/internal/agent"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+)
+
+func TestParsePullFile(t *testing.T) {
+	digest := dataset.Digest([]byte("{}"))
+
+	testCases := []struct {
+		name     string
+		file     string
+		contents string
+		error    string
+		dirs     []string
+		refs     []string
+	}{
+		{
+			name: "yaml",
+			file: "application.afs.yaml",
+			contents: `
+version: v1
+datasets:
+  - name: maxmind
+    tag: v1
+  - host: private.company.io
+    name: "@models/classifier"
+    digest: ` + digest + `
+    path: models
+    include:
+      - prod/*.onnx
+  - name: geoip
+`,
+			dirs: []string{"maxmind/v1", "models", "geoip/latest"},
+			refs: []string{
+				"localhost:8080/maxmind:v1",
+				"private.company.io/@models/classifier@" + digest,
+				"localhost:8080/geoip:latest",
+			},
+		},
+		{
+			name:     "json",
+			file:     "application.afs.json",
+			contents: `{"version": "v1", "datasets": [{"name": "maxmind", "tag": "v1", "digest": "` + digest + `"}]}`,
+			dirs:     []string{"maxmind/v1"},
+			refs:     []string{"localhost:8080/maxmind:v1"},
+		},
+		{
+			name:     "unknown field",
+			file:     "application.afs.yml",
+			contents: "version: v1\ndatasets:\n  - name: maxmind\n    tags: v1\n",
+			error:    "application.afs.yml: yaml: unmarshal errors:\n  line 4: field tags not found in type agent.PullFileDataset",
+		},
+		{
+			name:     "unsupported extension",
+			file:     "application.afs.toml",
+			contents: "",
+			error:    "application.afs.toml: unsupported file extension, expected .yaml, .yml, or .json",
+		},
+		{
+			name:     "empty",
+			file:     "application.afs.yaml",
+			contents: "",
+			error:    "application.afs.yaml: invalid pull file\n  version is required\n  datasets: at least one dataset is required",
+		},
+		{
+			name: "invalid datasets",
+			file: "application.afs.yaml",
+			contents: `
+version: v2
+datasets:
+  - name: maxmind
+  - tag: v1
+  - name: a/b
+    tag: "v1:2"
+    digest: sha256:abc
+  - name: maxmind
+    path: ../outside
+    include: ["[a-"]
+  - name: geoip
+    path: maxmind/latest
+  - name: geoip
+    path: .aetherfs/geoip
+`,
+			error: strings.Join([]string{
+				"application.afs.yaml: invalid pull file",
+				`  unsupported version "v2", expected "v1"`,
+				"  datasets[1]: name is required",
+				`  datasets[2]: invalid name "a/b"`,
+				`  datasets[2]: invalid tag "v1:2"`,
+				`  datasets[2]: invalid digest "sha256:abc", expected sha256:<hex>`,
+				"  datasets[3]: path must be relative to the pull directory",
+				`  datasets[3]: invalid pattern: "[a-"`,
+				`  datasets[4]: path "maxmind/latest" is already used by datasets[0]`,
+				`  datasets[5]: path ".aetherfs/geoip" is reserved`,
+			}, "\n"),
+		},
+		{
+			name: "version pulled twice",
+			file: "application.afs.yaml",
+			contents: `
+version: v1
+datasets:
+  - name: maxmind
+    tag: v1
+    path: geoip
+    include: ["*.mmdb"]
+  - name: maxmind
+    tag: v1
+    path: geoip-csv
+    include: ["*.csv"]
+`,
+			error: "application.afs.yaml: invalid pull file\n  datasets[1]: maxmind:v1 is already pulled by datasets[0]",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.name)
+
+		pullFile, err := agent.ParsePullFile(testCase.file, strings.NewReader(testCase.contents))
+		if len(testCase.error) > 0 {
+			require.Error(t, err)
+			require.Equal(t, testCase.error, err.Error())
+			continue
+		}
+
+		require.NoError(t, err)
+		require.Len(t, pullFile.Datasets, len(testCase.dirs))
+
+		for i, ds := range pullFile.Datasets {
+			ref := ds.Ref()
+
+			require.Equal(t, testCase.dirs[i], ds.Dir())
+			require.Equal(t, testCase.refs[i], ref.String())
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+//go:build !windows
+// +build !windows
+
+package agent
+
+import (
+	"syscall"
+)
+
+// freeSpace returns the number of bytes available to unprivileged users on the filesystem containing path.
+func freeSpace(path string) (int64, error) {
+	stat := syscall.Statfs_t{}
+
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
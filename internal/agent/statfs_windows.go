@@ -0,0 +1,16 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+//go:build windows
+// +build windows
+
+package agent
+
+import (
+	"errors"
+)
+
+// freeSpace is not supported on windows. Pruning by free space is rejected instead.
+func freeSpace(path string) (int64, error) {
+	return 0, errors.New("free space is not available on windows")
+}
@@ -0,0 +1,664 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/afero"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/filter"
+	"github.com/mjpitz/myago/vfs"
+)
+
+const (
+	// partialSuffix is appended to the name of files while they're being downloaded.
+	partialSuffix = ".afs.partial"
+
+	// downloadBufferSize is the number of bytes read from the dataset before being written to disk.
+	downloadBufferSize = 4 << 20
+
+	// snapshotSuffix and pendingSuffix are appended to the names of the snapshots written to the .aetherfs directory.
+	snapshotSuffix = ".snapshot.afs.json"
+	pendingSuffix  = ".pending.afs.json"
+)
+
+// subscription describes a single dataset that is materialized on disk.
+type subscription struct {
+	Ref     dataset.Tag    // the tag (or digest) used to resolve the dataset
+	Digest  string         // when set, the resolved dataset must have this digest
+	Dir     string         // the directory where the dataset is written
+	Include []string       // patterns selecting which files are written
+	Exclude []string       // patterns excluding files from being written
+	Filter  *filter.Filter // the compiled form of Include and Exclude
+	Layout  agentv1.Layout // how files are written to disk
+}
+
+func (sub *subscription) tag() *datasetv1.Tag {
+	return &datasetv1.Tag{
+		Name:    sub.Ref.Dataset,
+		Version: sub.Ref.Version,
+	}
+}
+
+// snapshotFile returns the path to the snapshot that records what was written to the subscriptions directory.
+func (sub *subscription) snapshotFile(aetherFSDir string) string {
+	return filepath.Join(aetherFSDir, sub.Ref.Dataset+"."+sub.Ref.Version+snapshotSuffix)
+}
+
+// pendingFile returns the path to the snapshot being materialized. It's removed once the snapshot is complete and
+// allows partially downloaded files to be resumed after a restart.
+func (sub *subscription) pendingFile(aetherFSDir string) string {
+	return filepath.Join(aetherFSDir, sub.Ref.Dataset+"."+sub.Ref.Version+pendingSuffix)
+}
+
+// subscriptionsFor resolves the subscriptions requested through tags and the optional pull file.
+func subscriptionsFor(ctx context.Context, request *agentv1.SubscribeRequest) ([]*subscription, error) {
+	var subscriptions []*subscription
+
+	selected, err := filter.New(request.Include, request.Exclude)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	for _, tag := range request.Tags {
+		t := dataset.Tag{}
+		err := t.UnmarshalText([]byte(tag))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid tag %s", tag)
+		}
+
+		subscriptions = append(subscriptions, &subscription{
+			Ref:     t,
+			Dir:     filepath.Join(request.Path, t.Dataset, t.Version),
+			Include: request.Include,
+			Exclude: request.Exclude,
+			Filter:  selected,
+			Layout:  request.Layout,
+		})
+	}
+
+	if len(request.ConfigFile) > 0 {
+		pullFile, err := LoadPullFile(ctx, request.ConfigFile)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, err.Error())
+		}
+
+		for _, ds := range pullFile.Datasets {
+			// patterns have already been validated
+			selected, _ := filter.New(ds.Include, ds.Exclude)
+
+			subscriptions = append(subscriptions, &subscription{
+				Ref:     ds.Ref(),
+				Digest:  ds.Digest,
+				Dir:     filepath.Join(request.Path, ds.Dir()),
+				Include: ds.Include,
+				Exclude: ds.Exclude,
+				Filter:  selected,
+				Layout:  request.Layout,
+			})
+		}
+	}
+
+	dirs := make(map[string]bool)
+	snapshots := make(map[string]bool)
+	for _, sub := range subscriptions {
+		snapshot := sub.snapshotFile("")
+
+		switch {
+		case dirs[sub.Dir]:
+			return nil, status.Errorf(codes.InvalidArgument, "multiple datasets would be written to %s", sub.Dir)
+		case snapshots[snapshot]:
+			// the snapshot of what was written is recorded per version, so each version can only be pulled once
+			return nil, status.Errorf(codes.InvalidArgument, "%s:%s is pulled more than once", sub.Ref.Dataset, sub.Ref.Version)
+		}
+
+		dirs[sub.Dir] = true
+		snapshots[snapshot] = true
+	}
+
+	return subscriptions, nil
+}
+
+// readSnapshot reads the snapshot written by a previous pull. Snapshots written before filters were recorded only
+// contain the dataset, which is equivalent to pulling every file.
+func readSnapshot(ctx context.Context, metadataFile string) (*agentv1.Snapshot, error) {
+	data, err := afero.ReadFile(vfs.Extract(ctx), metadataFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	snapshot := &agentv1.Snapshot{}
+
+	err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// selectedFiles returns the files in the snapshot that were selected by its filter, keyed by name.
+func selectedFiles(snapshot *agentv1.Snapshot) map[string]*datasetv1.File {
+	files := make(map[string]*datasetv1.File)
+	if snapshot == nil {
+		return files
+	}
+
+	selected, err := filter.New(snapshot.Include, snapshot.Exclude)
+	if err != nil {
+		// an unreadable filter means we can't trust anything on disk
+		return files
+	}
+
+	for _, file := range snapshot.GetDataset().GetFiles() {
+		if selected.Match(file.Name) {
+			files[file.Name] = file
+		}
+	}
+
+	return files
+}
+
+// removeFile removes a file that's no longer part of the dataset along with any directories left empty between it and
+// the root of the dataset.
+func removeFile(root, filePath string) error {
+	err := os.Remove(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	_ = os.Remove(filePath + partialSuffix)
+
+	for dir := filepath.Dir(filePath); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			// not empty
+			break
+		}
+	}
+
+	return nil
+}
+
+// downloadFile assembles the file from the block store into a partial file next to its final destination, moving it
+// into place once complete. When resume is set, a previously written partial file is assumed to hold the beginning of
+// the same file and the download continues from where it left off. The written func is called as bytes are written to
+// disk.
+func downloadFile(ctx context.Context, store *blockStore, ds *datasetv1.Dataset, file *datasetv1.File, filePath string, resume bool, written func(n int64)) error {
+	partialPath := filePath + partialSuffix
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+
+	out, err := os.OpenFile(partialPath, flags, filePermissions)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	offset, err := out.Seek(0, io.SeekEnd)
+	switch {
+	case err != nil:
+		return err
+	case offset > file.Size:
+		// can't be the same file, start over
+		if err = out.Truncate(0); err != nil {
+			return err
+		}
+
+		if offset, err = out.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	case offset > 0:
+		ctxzap.Extract(ctx).Info("resuming download", zap.String("file", file.Name), zap.Int64("offset", offset))
+		written(offset)
+	}
+
+	err = store.assemble(ctx, ds, file, out, offset, func(n int64) {
+		offset += n
+		written(n)
+	})
+	if err != nil {
+		return err
+	}
+
+	if offset != file.Size {
+		return fmt.Errorf("short read: %d of %d bytes", offset, file.Size)
+	}
+
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(partialPath, filePath)
+}
+
+// materialize brings the subscriptions directory in line with the next snapshot. Files that were written by the
+// previous snapshot and have not changed are left as is, files that are no longer selected are removed, and the rest
+// are assembled from the block store. Blocks needed in their entirety are downloaded into the store while only the
+// portions of other blocks that overlap selected files are downloaded. Files that were partially downloaded for the
+// pending snapshot are resumed when they're unchanged in the next one.
+func materialize(ctx context.Context, store *blockStore, host string, sub *subscription, previous, pending, next *agentv1.Snapshot, j *job) error {
+	logger := ctxzap.Extract(ctx).With(zap.String("name", sub.Ref.Dataset), zap.String("tag", sub.Ref.Version))
+
+	existing := selectedFiles(previous)
+
+	partials := make(map[string]*datasetv1.File)
+	for _, file := range pending.GetDataset().GetFiles() {
+		partials[file.Name] = file
+	}
+
+	type download struct {
+		file   *datasetv1.File
+		resume bool
+	}
+
+	// figure out what needs to be downloaded first so progress can be reported against a total
+	downloads := make([]download, 0, len(next.Dataset.Files))
+	totalSize := int64(0)
+
+	for _, file := range next.Dataset.Files {
+		if !sub.Filter.Match(file.Name) {
+			continue
+		}
+
+		filePath := filepath.Join(sub.Dir, file.Name)
+
+		prior, ok := existing[file.Name]
+		delete(existing, file.Name)
+
+		partial, resume := partials[file.Name]
+		delete(partials, file.Name)
+
+		if ok && proto.Equal(prior, file) {
+			if info, err := os.Stat(filePath); err == nil && info.Size() == file.Size {
+				continue
+			}
+		}
+
+		downloads = append(downloads, download{
+			file:   file,
+			resume: resume && proto.Equal(partial, file),
+		})
+
+		totalSize += file.Size
+	}
+
+	files := make([]*datasetv1.File, 0, len(downloads))
+	for _, d := range downloads {
+		files = append(files, d.file)
+	}
+
+	store.Full = fullBlocks(next.Dataset, files)
+
+	if store.Peers != nil {
+		store.Peers.locate(ctx, next.Dataset, store.Full)
+	}
+
+	j.host(host, func(progress *agentv1.JobProgress) {
+		progress.FilesTotal += int64(len(downloads))
+		progress.BytesTotal += totalSize
+	})
+
+	written := func(n int64) {
+		j.host(host, func(progress *agentv1.JobProgress) {
+			progress.BytesDone += n
+		})
+	}
+
+	_ = os.MkdirAll(sub.Dir, dirPermissions)
+	for _, d := range downloads {
+		filePath := filepath.Join(sub.Dir, d.file.Name)
+
+		_ = os.MkdirAll(filepath.Dir(filePath), dirPermissions)
+
+		logger.Info("downloading file", zap.String("file", d.file.Name))
+
+		var err error
+		if sub.Layout == agentv1.Layout_LAYOUT_HARDLINK {
+			err = store.link(ctx, next.Dataset, d.file, filePath, written)
+		} else {
+			err = downloadFile(ctx, store, next.Dataset, d.file, filePath, d.resume, written)
+		}
+
+		if err != nil {
+			logger.Error("failed to download file", zap.String("file", d.file.Name), zap.Error(err))
+			return status.Errorf(codes.Internal, "failed to download file")
+		}
+
+		j.host(host, func(progress *agentv1.JobProgress) {
+			progress.FilesDone++
+		})
+	}
+
+	// partial files that can no longer be resumed
+	for name := range partials {
+		_ = os.Remove(filepath.Join(sub.Dir, name) + partialSuffix)
+	}
+
+	// anything left over was written previously but is no longer selected or part of the dataset
+	for name := range existing {
+		logger.Info("removing file", zap.String("file", name))
+
+		err := removeFile(sub.Dir, filepath.Join(sub.Dir, name))
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to remove file")
+		}
+	}
+
+	return nil
+}
+
+// writeSnapshot writes the snapshot to the provided metadata file.
+func writeSnapshot(metadataFile string, snapshot *agentv1.Snapshot) error {
+	opts := protojson.MarshalOptions{
+		Multiline: true,
+		Indent:    "  ",
+	}
+
+	data, err := opts.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// scoped datasets are nested under a directory named after their scope
+	_ = os.MkdirAll(filepath.Dir(metadataFile), dirPermissions)
+
+	return ioutil.WriteFile(metadataFile, data, filePermissions)
+}
+
+// subscribe materializes the subscriptions to datasets hosted by host, returning the tags whose contents changed.
+func (s *Service) subscribe(ctx context.Context, host string, subscriptions []*subscription, aetherFSDir string, j *job) ([]string, error) {
+	conn, err := s.connectionFor(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	logger := ctxzap.Extract(ctx).With(zap.String("host", host))
+
+	blockAPI := blockv1.NewBlockAPIClient(conn)
+	datasetAPI := datasetv1.NewDatasetAPIClient(conn)
+
+	peers := s.peerBlocks()
+	defer peers.Close()
+
+	snapshots := make([]*agentv1.Snapshot, 0, len(subscriptions))
+
+	for _, sub := range subscriptions {
+		req := &datasetv1.LookupRequest{
+			Tag: sub.tag(),
+		}
+
+		resp, err := datasetAPI.Lookup(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case sub.Digest == "":
+		case resp.Digest == "":
+			return nil, status.Errorf(codes.FailedPrecondition, "%s does not report dataset digests", host)
+		case resp.Digest != sub.Digest:
+			return nil, status.Errorf(codes.FailedPrecondition, "%s resolved to %s, expected %s",
+				sub.Ref.String(), resp.Digest, sub.Digest)
+		}
+
+		snapshots = append(snapshots, &agentv1.Snapshot{
+			Dataset: resp.Dataset,
+			Digest:  resp.Digest,
+			Include: sub.Include,
+			Exclude: sub.Exclude,
+			Tag:     sub.Ref.String(),
+			Dir:     sub.Dir,
+		})
+	}
+
+	updated := make([]string, 0, len(snapshots))
+
+	// save snapshots
+	for i, snapshot := range snapshots {
+		sub := subscriptions[i]
+		metadataFile := sub.snapshotFile(aetherFSDir)
+
+		previous, err := readSnapshot(ctx, metadataFile)
+		if err != nil {
+			logger.Warn("ignoring unreadable snapshot", zap.String("file", metadataFile), zap.Error(err))
+			previous = nil
+		}
+
+		if previous != nil && proto.Equal(previous, snapshot) {
+			continue
+		}
+
+		// this could definitely be done in a more efficient way, but this is a good start
+
+		logger.Info("downloading dataset", zap.String("name", sub.Ref.Dataset), zap.String("tag", sub.Ref.Version))
+
+		// record what's being downloaded so an interrupted pull can pick up where it left off
+		pendingFile := sub.pendingFile(aetherFSDir)
+
+		pending, err := readSnapshot(ctx, pendingFile)
+		if err != nil {
+			logger.Warn("ignoring unreadable snapshot", zap.String("file", pendingFile), zap.Error(err))
+			pending = nil
+		}
+
+		err = writeSnapshot(pendingFile, snapshot)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to write metadata file")
+		}
+
+		store := &blockStore{
+			Dir:      aetherFSDir,
+			BlockAPI: blockAPI,
+			Peers:    peers,
+		}
+
+		err = materialize(afs.WithDataset(ctx, sub.Ref.Dataset), store, host, sub, previous, pending, snapshot, j)
+		if err != nil {
+			return nil, err
+		}
+
+		// save snapshot
+
+		err = writeSnapshot(metadataFile, snapshot)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to write metadata file")
+		}
+
+		_ = os.Remove(pendingFile)
+
+		updated = append(updated, sub.Ref.String())
+	}
+
+	return updated, nil
+}
+
+func (s *Service) subscribeAsync(ctx context.Context, subscriptions []*subscription, aetherFSDir string, j *job) ([]string, error) {
+	defer atomic.AddInt32(&s.ongoing, -1)
+
+	group, ctx := errgroup.WithContext(ctx)
+
+	mu := sync.Mutex{}
+	updated := make([]string, 0)
+
+	subscriptionsByHost := make(map[string][]*subscription)
+	for _, sub := range subscriptions {
+		subscriptionsByHost[sub.Ref.Host] = append(subscriptionsByHost[sub.Ref.Host], sub)
+	}
+
+	subscribeAsync := func(host string, subscriptions []*subscription) {
+		group.Go(func() error {
+			tags, err := s.subscribe(ctx, host, subscriptions, aetherFSDir, j)
+			j.fail(host, err)
+
+			mu.Lock()
+			updated = append(updated, tags...)
+			mu.Unlock()
+
+			return err
+		})
+	}
+
+	for host, subscriptions := range subscriptionsByHost {
+		subscribeAsync(host, subscriptions)
+	}
+
+	err := group.Wait()
+	sort.Strings(updated)
+
+	return updated, err
+}
+
+// prepareDir ensures the path and its .aetherfs directory exist, returning the latter.
+func prepareDir(path string) (string, error) {
+	_ = os.MkdirAll(path, dirPermissions)
+	{
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			return "", status.Error(codes.InvalidArgument, "failed to make directory")
+		case !info.IsDir():
+			return "", status.Error(codes.InvalidArgument, "path is not a directory")
+		}
+	}
+
+	aetherFSDir := filepath.Join(path, aetherFSDirName)
+	_ = os.MkdirAll(aetherFSDir, dirPermissions)
+
+	{
+		info, err := os.Stat(aetherFSDir)
+		switch {
+		case err != nil:
+			return "", status.Error(codes.InvalidArgument, "failed to make aetherfs directory")
+		case !info.IsDir():
+			return "", status.Error(codes.InvalidArgument, ".aetherfs is a file")
+		}
+	}
+
+	return aetherFSDir, nil
+}
+
+// startSubscriptions creates a job that materializes the subscriptions under the path. When sync is set, the call
+// blocks until the job completes.
+func (s *Service) startSubscriptions(ctx context.Context, path string, subscriptions []*subscription, sync bool) (*agentv1.SubscribeResponse, error) {
+	resp := &agentv1.SubscribeResponse{
+		Paths: make(map[string]string),
+	}
+
+	tags := make([]string, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		resp.Paths[sub.Ref.String()] = sub.Dir
+		tags = append(tags, sub.Ref.String())
+	}
+
+	aetherFSDir, err := prepareDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := s.jobs.start(ctx, agentv1.JobKind_JOB_KIND_SUBSCRIBE, path, tags)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create job: %v", err)
+	}
+
+	resp.JobId = j.id()
+
+	atomic.AddInt32(&s.ongoing, 1)
+
+	track := func(ctx context.Context) (*agentv1.SubscribeResponse, error) {
+		updated, err := s.subscribeAsync(j.cancellable(ctx), subscriptions, aetherFSDir, j)
+
+		// resp may already be in the hands of the caller, so the result is recorded on a copy
+		result := proto.Clone(resp).(*agentv1.SubscribeResponse)
+		result.Updated = updated
+
+		j.complete(ctx, err, func(state *agentv1.Job) {
+			state.Subscribe = result
+		})
+
+		return result, err
+	}
+
+	if sync {
+		resp, err = track(ctx)
+
+	} else {
+		// returning cancels the request context, so work continues on a detached one
+		ctx := detach(ctx)
+
+		go func() {
+			_, err := track(ctx)
+			if err != nil {
+				ctxzap.Extract(ctx).Error("failed to subscribe to dataset", zap.Error(err))
+			}
+		}()
+	}
+
+	if err != nil {
+		// preserve codes like NotFound and FailedPrecondition from the lookup
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	return resp, nil
+}
+
+func (s *Service) Subscribe(ctx context.Context, request *agentv1.SubscribeRequest) (*agentv1.SubscribeResponse, error) {
+	if len(request.Path) == 0 {
+		request.Path = afero.GetTempDir(vfs.Extract(ctx), "aetherfs")
+	}
+
+	subscriptions, err := subscriptionsFor(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic.LoadInt32(&s.shutdown) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "shutdown already initiated")
+	}
+
+	if _, err := prepareDir(request.Path); err != nil {
+		return nil, err
+	}
+
+	// persisted up front so subscriptions interrupted by a restart are picked up again
+	err = s.saveSubscriptions(ctx, request.Path, subscriptions)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to persist subscriptions: %v", err)
+	}
+
+	return s.startSubscriptions(ctx, request.Path, subscriptions, request.Sync)
+}
@@ -0,0 +1,261 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/filter"
+	"github.com/mjpitz/myago/clocks"
+)
+
+// subscriptionRecord is the persisted form of a subscription. Records are keyed by the directory the dataset is
+// written to since only one dataset can occupy a directory.
+type subscriptionRecord struct {
+	Path    string         `json:"path"`
+	Tag     string         `json:"tag"`
+	Digest  string         `json:"digest,omitempty"`
+	Dir     string         `json:"dir"`
+	Include []string       `json:"include,omitempty"`
+	Exclude []string       `json:"exclude,omitempty"`
+	Layout  agentv1.Layout `json:"layout,omitempty"`
+	Created time.Time      `json:"created"`
+}
+
+func (r *subscriptionRecord) subscription() (*subscription, error) {
+	ref := dataset.Tag{}
+	err := ref.UnmarshalText([]byte(r.Tag))
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := filter.New(r.Include, r.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscription{
+		Ref:     ref,
+		Digest:  r.Digest,
+		Dir:     r.Dir,
+		Include: r.Include,
+		Exclude: r.Exclude,
+		Filter:  selected,
+		Layout:  r.Layout,
+	}, nil
+}
+
+func (r *subscriptionRecord) proto() *agentv1.Subscription {
+	return &agentv1.Subscription{
+		Path:    r.Path,
+		Tag:     r.Tag,
+		Digest:  r.Digest,
+		Dir:     r.Dir,
+		Include: r.Include,
+		Exclude: r.Exclude,
+		Created: timestamppb.New(r.Created),
+		Layout:  r.Layout,
+	}
+}
+
+// saveSubscriptions persists the subscriptions made against the path. Subscriptions that were previously recorded for
+// the same directory are replaced, but retain their original creation time.
+func (s *Service) saveSubscriptions(ctx context.Context, path string, subscriptions []*subscription) error {
+	if s.Subscriptions == nil {
+		return nil
+	}
+
+	now := clocks.Extract(ctx).Now()
+
+	for _, sub := range subscriptions {
+		record := &subscriptionRecord{}
+
+		err := s.Subscriptions.Get(ctx, sub.Dir, record)
+		switch {
+		case errors.Is(err, badger.ErrKeyNotFound):
+			record.Created = now
+		case err != nil:
+			return err
+		}
+
+		record.Path = path
+		record.Tag = sub.Ref.String()
+		record.Digest = sub.Digest
+		record.Dir = sub.Dir
+		record.Include = sub.Include
+		record.Exclude = sub.Exclude
+		record.Layout = sub.Layout
+
+		err = s.Subscriptions.Put(ctx, sub.Dir, record)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listSubscriptions returns the persisted subscriptions ordered by path and tag. When path is provided, only the
+// subscriptions for that path are returned.
+func (s *Service) listSubscriptions(ctx context.Context, path string) ([]*subscriptionRecord, error) {
+	if s.Subscriptions == nil {
+		return nil, nil
+	}
+
+	keys, err := s.Subscriptions.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*subscriptionRecord, 0, len(keys))
+	for _, key := range keys {
+		record := &subscriptionRecord{}
+		if err := s.Subscriptions.Get(ctx, key, record); err != nil {
+			return nil, err
+		}
+
+		if path != "" && record.Path != path {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Path != records[j].Path {
+			return records[i].Path < records[j].Path
+		}
+
+		return records[i].Tag < records[j].Tag
+	})
+
+	return records, nil
+}
+
+// ReconcileSubscriptions pulls every persisted subscription in the background, bringing the directories up to date with
+// the latest version of their datasets and finishing any downloads that were interrupted by a restart.
+func (s *Service) ReconcileSubscriptions(ctx context.Context) error {
+	records, err := s.listSubscriptions(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0)
+	subscriptionsByPath := make(map[string][]*subscription)
+
+	for _, record := range records {
+		sub, err := record.subscription()
+		if err != nil {
+			ctxzap.Extract(ctx).Error("skipping invalid subscription",
+				zap.String("path", record.Path), zap.String("tag", record.Tag), zap.Error(err))
+			continue
+		}
+
+		if _, ok := subscriptionsByPath[record.Path]; !ok {
+			paths = append(paths, record.Path)
+		}
+
+		subscriptionsByPath[record.Path] = append(subscriptionsByPath[record.Path], sub)
+	}
+
+	for _, path := range paths {
+		ctxzap.Extract(ctx).Info("reconciling subscriptions", zap.String("path", path))
+
+		// one bad path shouldn't prevent the others from being reconciled
+		_, err := s.startSubscriptions(ctx, path, subscriptionsByPath[path], false)
+		if err != nil {
+			ctxzap.Extract(ctx).Error("failed to reconcile subscriptions", zap.String("path", path), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context, request *agentv1.ListSubscriptionsRequest) (*agentv1.ListSubscriptionsResponse, error) {
+	if s.Subscriptions == nil {
+		return nil, status.Error(codes.FailedPrecondition, "subscriptions are not persisted by this agent")
+	}
+
+	records, err := s.listSubscriptions(ctx, request.Path)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list subscriptions: %v", err)
+	}
+
+	resp := &agentv1.ListSubscriptionsResponse{
+		Subscriptions: make([]*agentv1.Subscription, 0, len(records)),
+	}
+
+	for _, record := range records {
+		resp.Subscriptions = append(resp.Subscriptions, record.proto())
+	}
+
+	return resp, nil
+}
+
+func (s *Service) Unsubscribe(ctx context.Context, request *agentv1.UnsubscribeRequest) (*agentv1.UnsubscribeResponse, error) {
+	if s.Subscriptions == nil {
+		return nil, status.Error(codes.FailedPrecondition, "subscriptions are not persisted by this agent")
+	}
+
+	if request.Path == "" || request.Tag == "" {
+		return nil, status.Error(codes.InvalidArgument, "path and tag are required")
+	}
+
+	ref := dataset.Tag{}
+	err := ref.UnmarshalText([]byte(request.Tag))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tag %s", request.Tag)
+	}
+
+	records, err := s.listSubscriptions(ctx, request.Path)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list subscriptions: %v", err)
+	}
+
+	var record *subscriptionRecord
+	for _, r := range records {
+		if r.Tag == ref.String() {
+			record = r
+			break
+		}
+	}
+
+	if record == nil {
+		return nil, status.Errorf(codes.NotFound, "no subscription to %s found in %s", ref.String(), request.Path)
+	}
+
+	err = s.Subscriptions.Delete(ctx, record.Dir)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to remove subscription: %v", err)
+	}
+
+	if request.RemoveFiles {
+		sub := &subscription{Ref: ref}
+		aetherFSDir := filepath.Join(record.Path, aetherFSDirName)
+
+		for _, path := range []string{record.Dir, sub.snapshotFile(aetherFSDir), sub.pendingFile(aetherFSDir)} {
+			if err := os.RemoveAll(path); err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to remove %s: %v", path, err)
+			}
+		}
+	}
+
+	return &agentv1.UnsubscribeResponse{
+		Subscription: record.proto(),
+	}, nil
+}
@@ -0,0 +1,488 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+)
+
+// localFile tracks what was found on disk for a file selected by a snapshot.
+type localFile struct {
+	File       *datasetv1.File
+	Path       string         // where the file was written
+	Start      int64          // where the file starts within the dataset
+	Size       int64          // the size of the file on disk
+	Missing    bool           // the file doesn't exist
+	Bad        map[int64]bool // the blocks overlapping the file whose contents don't match
+	Unverified bool           // some blocks overlapping the file couldn't be checked
+}
+
+func (f *localFile) modified() bool {
+	return !f.Missing && (f.Size != f.File.Size || len(f.Bad) > 0)
+}
+
+// blockPart is the portion of a block that's occupied by a file.
+type blockPart struct {
+	Local  *localFile // nil when the file wasn't pulled
+	Lo, Hi int64      // the range of the block, relative to the start of the dataset
+	Failed bool       // the range couldn't be read from disk
+}
+
+// readPart reads the range of the file overlapping the block into buf.
+func readPart(part *blockPart, buf []byte) bool {
+	f, err := os.Open(part.Local.Path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	_, err = f.ReadAt(buf, part.Lo-part.Local.Start)
+	return err == nil
+}
+
+// referenceBlock returns the verified contents of the block from the block store. When fetch is set, blocks that aren't
+// held locally are downloaded.
+func referenceBlock(ctx context.Context, store *blockStore, signature string, blockSize int32, fetch bool) []byte {
+	path := store.blockPath(signature)
+
+	if data, err := os.ReadFile(path); err == nil {
+		if blocks.VerifySignature(signature, data) == nil {
+			return data
+		}
+
+		// the block store has been tampered with as well
+		_ = os.Remove(path)
+	}
+
+	if !fetch || store.fetch(ctx, signature, blockSize) != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+// verifyBlocks recomputes the signature of every block from the files on disk. Blocks that can't be rebuilt entirely
+// from disk (e.g. because they're shared with files that weren't pulled) are compared against the block store instead.
+// When a block doesn't match, the block store is also used to determine which of the files sharing it are at fault.
+func verifyBlocks(ctx context.Context, store *blockStore, ds *datasetv1.Dataset, locals map[string]*localFile, fetch bool) {
+	blockSize := int64(ds.BlockSize)
+
+	datasetSize := int64(0)
+	for _, file := range ds.Files {
+		datasetSize += file.Size
+	}
+
+	buf := make([]byte, blockSize)
+
+	for idx, signature := range ds.Blocks {
+		blockStart := int64(idx) * blockSize
+		blockEnd := min(blockStart+blockSize, datasetSize)
+		data := buf[:blockEnd-blockStart]
+
+		var parts []*blockPart
+		complete := true
+
+		start := int64(0)
+		for _, file := range ds.Files {
+			lo, hi := start, start+file.Size
+			start = hi
+
+			if lo < blockStart {
+				lo = blockStart
+			}
+
+			if hi > blockEnd {
+				hi = blockEnd
+			}
+
+			if lo >= hi {
+				continue
+			}
+
+			part := &blockPart{Local: locals[file.Name], Lo: lo, Hi: hi}
+			parts = append(parts, part)
+
+			if part.Local == nil {
+				complete = false
+				continue
+			}
+
+			part.Failed = part.Local.Missing || !readPart(part, data[lo-blockStart:hi-blockStart])
+			if part.Failed {
+				complete = false
+			}
+		}
+
+		if complete && blocks.VerifySignature(signature, data) == nil {
+			continue
+		}
+
+		// only fetch blocks that are needed to judge or repair files that were pulled
+		pulled := false
+		for _, part := range parts {
+			pulled = pulled || part.Local != nil
+		}
+
+		if !pulled {
+			continue
+		}
+
+		reference := referenceBlock(ctx, store, signature, ds.BlockSize, fetch)
+
+		for _, part := range parts {
+			switch {
+			case part.Local == nil:
+			case part.Failed:
+				part.Local.Bad[int64(idx)] = true
+			case reference != nil:
+				if !bytes.Equal(data[part.Lo-blockStart:part.Hi-blockStart], reference[part.Lo-blockStart:part.Hi-blockStart]) {
+					part.Local.Bad[int64(idx)] = true
+				}
+			case complete:
+				// every file was read but the block doesn't match, there's no telling which file changed
+				part.Local.Bad[int64(idx)] = true
+			default:
+				part.Local.Unverified = true
+			}
+		}
+	}
+}
+
+// repairFile rewrites the file, re-downloading the bad blocks and keeping the rest of its contents from disk. The
+// number of bytes written is returned.
+func repairFile(ctx context.Context, store *blockStore, ds *datasetv1.Dataset, local *localFile) (int64, error) {
+	blockSize := int64(ds.BlockSize)
+	written := int64(0)
+
+	// hardlinked files share their contents with the file store, which is just as damaged
+	if stored := store.filePath(fileKey(ds, local.File)); fileExists(stored) {
+		storedInfo, serr := os.Stat(stored)
+		localInfo, lerr := os.Stat(local.Path)
+
+		if serr == nil && lerr == nil && os.SameFile(storedInfo, localInfo) {
+			_ = os.Remove(stored)
+		}
+	}
+
+	if local.Missing {
+		_ = os.MkdirAll(filepath.Dir(local.Path), dirPermissions)
+
+		err := downloadFile(ctx, store, ds, local.File, local.Path, false, func(n int64) { written += n })
+		return written, err
+	}
+
+	in, err := os.Open(local.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	partialPath := local.Path + partialSuffix
+
+	out, err := os.OpenFile(partialPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePermissions)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	pos := local.Start
+	end := local.Start + local.File.Size
+
+	for pos < end {
+		idx := pos / blockSize
+		blockOffset := pos % blockSize
+		size := min(blockSize-blockOffset, end-pos)
+
+		var n int64
+		if local.Bad[idx] || pos-local.Start+size > local.Size {
+			n, err = store.copyRange(ctx, out, ds.Blocks[idx], ds.BlockSize, blockOffset, size)
+		} else {
+			n, err = io.Copy(out, io.NewSectionReader(in, pos-local.Start, size))
+		}
+
+		pos += n
+		written += n
+
+		switch {
+		case err != nil:
+			return written, err
+		case n < size:
+			return written, io.ErrUnexpectedEOF
+		}
+	}
+
+	if err = out.Close(); err != nil {
+		return written, err
+	}
+
+	return written, os.Rename(partialPath, local.Path)
+}
+
+// extraFiles returns the files in dir that aren't selected by the snapshot.
+func extraFiles(dir string, locals map[string]*localFile) ([]string, error) {
+	var extra []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		case d.IsDir() || strings.HasSuffix(path, partialSuffix):
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if _, ok := locals[rel]; !ok {
+			extra = append(extra, rel)
+		}
+
+		return nil
+	})
+
+	return extra, err
+}
+
+// verify checks the dataset pulled to dir against its snapshot, repairing damaged files when requested.
+func (s *Service) verify(ctx context.Context, path, tag, dir string, snapshot *agentv1.Snapshot, repair bool) (*agentv1.VerifiedDataset, error) {
+	logger := ctxzap.Extract(ctx).With(zap.String("tag", tag), zap.String("dir", dir))
+
+	result := &agentv1.VerifiedDataset{
+		Tag: tag,
+		Dir: dir,
+	}
+
+	store := &blockStore{
+		Dir: filepath.Join(path, aetherFSDirName),
+	}
+
+	if repair {
+		ref := dataset.Tag{}
+		if err := ref.UnmarshalText([]byte(tag)); err != nil {
+			return nil, err
+		}
+
+		conn, err := s.connectionFor(ctx, ref.Host)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		store.BlockAPI = blockv1.NewBlockAPIClient(conn)
+		ctx = afs.WithDataset(ctx, ref.Dataset)
+	}
+
+	ds := snapshot.GetDataset()
+	selected := selectedFiles(snapshot)
+	locals := make(map[string]*localFile, len(selected))
+
+	start := int64(0)
+	for _, file := range ds.GetFiles() {
+		offset := start
+		start += file.Size
+
+		if _, ok := selected[file.Name]; !ok {
+			continue
+		}
+
+		local := &localFile{
+			File:  file,
+			Path:  filepath.Join(dir, file.Name),
+			Start: offset,
+			Bad:   make(map[int64]bool),
+		}
+
+		info, err := os.Stat(local.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			local.Missing = true
+		case err != nil:
+			return nil, err
+		default:
+			local.Size = info.Size()
+		}
+
+		locals[file.Name] = local
+	}
+
+	verifyBlocks(ctx, store, ds, locals, repair)
+
+	var damaged []*localFile
+	for _, file := range ds.GetFiles() {
+		local, ok := locals[file.Name]
+		switch {
+		case !ok:
+			continue
+		case local.Missing:
+			result.Missing = append(result.Missing, file.Name)
+		case local.modified():
+			result.Modified = append(result.Modified, file.Name)
+		case local.Unverified:
+			result.Unverified = append(result.Unverified, file.Name)
+			continue
+		default:
+			continue
+		}
+
+		damaged = append(damaged, local)
+	}
+
+	extra, err := extraFiles(dir, locals)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Extra = extra
+
+	if !repair || len(damaged) == 0 {
+		return result, nil
+	}
+
+	files := make([]*datasetv1.File, 0, len(damaged))
+	for _, local := range damaged {
+		files = append(files, local.File)
+	}
+
+	store.Full = fullBlocks(ds, files)
+
+	for _, local := range damaged {
+		logger.Info("repairing file", zap.String("file", local.File.Name))
+
+		n, err := repairFile(ctx, store, ds, local)
+		result.RepairedBytes += n
+
+		if err != nil {
+			logger.Error("failed to repair file", zap.String("file", local.File.Name), zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to repair %s", local.File.Name)
+		}
+
+		result.Repaired = append(result.Repaired, local.File.Name)
+	}
+
+	return result, nil
+}
+
+// normalizeTag returns the canonical form of the tag so tags written with and without defaults can be compared.
+func normalizeTag(tag string) (string, error) {
+	ref := dataset.Tag{}
+	if err := ref.UnmarshalText([]byte(tag)); err != nil {
+		return "", err
+	}
+
+	return ref.String(), nil
+}
+
+func (s *Service) Verify(ctx context.Context, request *agentv1.VerifyRequest) (*agentv1.VerifyResponse, error) {
+	if request.Path == "" {
+		return nil, status.Error(codes.InvalidArgument, "path is required")
+	}
+
+	requested := make(map[string]bool)
+	for _, tag := range request.Tags {
+		normalized, err := normalizeTag(tag)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid tag %s", tag)
+		}
+
+		requested[normalized] = false
+	}
+
+	type candidate struct {
+		tag, dir, file string
+		snapshot       *agentv1.Snapshot
+	}
+
+	var candidates []candidate
+
+	err := walkSnapshots(ctx, request.Path, func(file string, _ fs.FileInfo, snapshot *agentv1.Snapshot) error {
+		tag, _, dir := snapshotLocation(request.Path, file, snapshot)
+		if dir == "" {
+			ctxzap.Extract(ctx).Warn("unable to determine where snapshot was pulled to", zap.String("file", file))
+			return nil
+		}
+
+		if len(requested) > 0 {
+			normalized, err := normalizeTag(tag)
+			if _, ok := requested[normalized]; err != nil || !ok {
+				return nil
+			}
+
+			requested[normalized] = true
+		}
+
+		candidates = append(candidates, candidate{tag: tag, dir: dir, file: file, snapshot: snapshot})
+		return nil
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to find pulled datasets: %v", err)
+	}
+
+	for tag, found := range requested {
+		if !found {
+			return nil, status.Errorf(codes.NotFound, "%s has not been pulled to %s", tag, request.Path)
+		}
+	}
+
+	resp := &agentv1.VerifyResponse{}
+
+	for _, c := range candidates {
+		pending := strings.TrimSuffix(c.file, snapshotSuffix) + pendingSuffix
+		if fileExists(pending) {
+			resp.Datasets = append(resp.Datasets, &agentv1.VerifiedDataset{
+				Tag:     c.tag,
+				Dir:     c.dir,
+				Skipped: "downloading",
+			})
+
+			continue
+		}
+
+		result, err := s.verify(ctx, request.Path, c.tag, c.dir, c.snapshot, request.Repair)
+		if err != nil {
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+
+			return nil, status.Errorf(codes.Internal, "failed to verify %s: %v", c.tag, err)
+		}
+
+		resp.Datasets = append(resp.Datasets, result)
+	}
+
+	sort.Slice(resp.Datasets, func(i, j int) bool {
+		return resp.Datasets[i].Tag < resp.Datasets[j].Tag
+	})
+
+	return resp, nil
+}
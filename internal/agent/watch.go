@@ -0,0 +1,228 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/filter"
+	"github.com/mjpitz/myago/clocks"
+)
+
+const defaultDebounce = 5 * time.Second
+
+// blockKey describes the contents of a block using the files, offsets, sizes, and modification times of the segments
+// that make it up. If none of these change between publishes, neither does the block.
+func blockKey(block *blocks.Block, filesByPath map[string]*datasetv1.File) string {
+	key := strings.Builder{}
+
+	for _, segment := range block.Segments {
+		file := filesByPath[segment.FilePath]
+
+		_, _ = fmt.Fprintf(&key, "%s:%d:%d:%d:%d;", segment.FilePath, segment.Offset, segment.Size,
+			file.GetSize(), file.GetLastModified().AsTime().UnixNano())
+	}
+
+	return key.String()
+}
+
+// signatureCache remembers the signatures of blocks between publishes so unchanged blocks do not need to be read and
+// hashed again. A nil cache is valid and never contains any signatures.
+type signatureCache struct {
+	mu         sync.Mutex
+	signatures map[string]string
+	used       map[string]bool
+}
+
+func (c *signatureCache) get(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	signature, ok := c.signatures[key]
+	if ok {
+		c.used[key] = true
+	}
+
+	return signature, ok
+}
+
+func (c *signatureCache) put(key, signature string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.signatures == nil {
+		c.signatures = make(map[string]string)
+		c.used = make(map[string]bool)
+	}
+
+	c.signatures[key] = signature
+	c.used[key] = true
+}
+
+// sweep drops any signatures that were not used since the last sweep.
+func (c *signatureCache) sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.signatures {
+		if !c.used[key] {
+			delete(c.signatures, key)
+		}
+	}
+
+	c.used = make(map[string]bool)
+}
+
+// watchDirectories adds a watch for dir and every directory below it that has not been excluded.
+func watchDirectories(watcher *fsnotify.Watcher, root, dir string, selected *filter.Filter) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(path, root), "/")
+		if name != "" && selected.Excluded(name, true) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(path)
+	})
+}
+
+// watch publishes the dataset and then republishes it every time the files under the root change. Changes are
+// debounced so a burst of writes results in a single publish. Signatures are cached between publishes, so each
+// iteration only reads and uploads the blocks that changed. The watch runs until its job is cancelled, its context is
+// done, or the agent shuts down. It only holds up a graceful shutdown while it has changes left to publish.
+func (s *Service) watch(ctx context.Context, request *agentv1.PublishRequest, tagsByHost map[string][]*datasetv1.Tag, opts publishOptions) (*agentv1.PublishResponse, error) {
+	logger := ctxzap.Extract(ctx).With(zap.String("path", request.Path))
+	clock := clocks.Extract(ctx)
+
+	debounce := request.Debounce.AsDuration()
+	if debounce <= 0 {
+		debounce = defaultDebounce
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	err = watchDirectories(watcher, opts.Root, opts.Root, opts.Filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to watch path: %v", err)
+	}
+
+	opts.Signatures = &signatureCache{}
+
+	resp, err := s.publishAsync(ctx, request, tagsByHost, opts)
+	opts.Release()
+	if err != nil {
+		return nil, err
+	}
+	opts.Signatures.sweep()
+
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// changed is set while there are changes waiting to be published, which count as ongoing work
+	var changed <-chan time.Time
+	defer func() {
+		if changed != nil {
+			atomic.AddInt32(&s.ongoing, -1)
+		}
+	}()
+
+	republish := func() {
+		changed = nil
+		defer atomic.AddInt32(&s.ongoing, -1)
+
+		logger.Info("republishing dataset")
+		next, err := s.publishAsync(ctx, request, tagsByHost, opts)
+		if err != nil {
+			// keep watching, the next change will trigger another attempt
+			logger.Error("failed to republish dataset", zap.Error(err))
+			return
+		}
+
+		opts.Signatures.sweep()
+		resp = next
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return resp, nil
+
+		case <-ticker.Chan():
+			if atomic.LoadInt32(&s.shutdown) > 0 {
+				// flush any pending changes before letting the agent shut down
+				if changed != nil {
+					republish()
+				}
+
+				return resp, nil
+			}
+
+		case err := <-watcher.Errors:
+			logger.Error("watch failed", zap.Error(err))
+
+		case event := <-watcher.Events:
+			name := strings.TrimPrefix(strings.TrimPrefix(event.Name, opts.Root), "/")
+
+			info, err := os.Stat(event.Name)
+			switch {
+			case err == nil && info.IsDir():
+				if opts.Filter.Excluded(name, true) {
+					continue
+				}
+
+				// files may have been written before the watch was added, so always treat this as a change
+				err = watchDirectories(watcher, opts.Root, event.Name, opts.Filter)
+				if err != nil {
+					logger.Error("failed to watch directory", zap.String("dir", name), zap.Error(err))
+				}
+			case err == nil && !opts.Filter.Match(name):
+				continue
+			case err != nil && opts.Filter.Excluded(name, false):
+				// removed or renamed paths can no longer be inspected
+				continue
+			}
+
+			if changed == nil {
+				atomic.AddInt32(&s.ongoing, 1)
+			}
+
+			changed = clock.After(debounce)
+
+		case <-changed:
+			republish()
+		}
+	}
+}
@@ -33,3 +33,26 @@ func ComputeSignature(algorithm string, data []byte) (string, error) {
 
 	return strings.ToLower(base32.StdEncoding.EncodeToString(signer.Sum(nil))), nil
 }
+
+// Algorithms lists the algorithms signatures can be computed with.
+var Algorithms = []string{"sha256", "sha512"}
+
+// VerifySignature checks that the data matches the signature. Signatures don't record the algorithm used to compute
+// them, but it can be determined from their length.
+func VerifySignature(signature string, data []byte) error {
+	for _, algorithm := range Algorithms {
+		computed, err := ComputeSignature(algorithm, data)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case computed == signature:
+			return nil
+		case len(computed) == len(signature):
+			return fmt.Errorf("block %s failed verification", signature)
+		}
+	}
+
+	return fmt.Errorf("block %s has an unrecognized signature", signature)
+}
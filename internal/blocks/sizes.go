@@ -15,4 +15,14 @@ var (
 	// PartSize is a cache-optimized length that is used to send and share parts of a block amongst a group of nodes.
 	// It is also used during uploads and downloads as the segment sizes to avoid buffering gigabytes of data in memory.
 	PartSize = 64 * Kibibyte
+
+	// MaxBlockSize is the largest block size a dataset can be published with. Readers buffer entire blocks, so larger
+	// blocks are more likely to exhaust their memory than to improve throughput.
+	MaxBlockSize = 1024 * Mebibyte
+)
+
+var (
+	// LookupBatchSize is the maximum number of signatures that can be checked in a single LookupBatch call. Clients
+	// checking more signatures than this should split their requests.
+	LookupBatchSize = 1000
 )
@@ -6,6 +6,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"text/template"
@@ -17,6 +18,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/mjpitz/aetherfs/internal/components"
+	"github.com/mjpitz/aetherfs/internal/output"
 	"github.com/mjpitz/aetherfs/internal/storage/local"
 	"github.com/mjpitz/myago/auth"
 	oidcauth "github.com/mjpitz/myago/auth/oidc"
@@ -184,10 +186,12 @@ func Auth() *cli.Command {
 						}
 					}
 
-					return t.Execute(ctx.App.Writer, &data{
-						Server:           server,
-						GRPCClientConfig: clientConfig,
-						UserInfo:         userInfo,
+					return output.Write(ctx.Context, ctx.App.Writer, newAuthDetails(server, clientConfig, userInfo), func(w io.Writer) error {
+						return t.Execute(w, &data{
+							Server:           server,
+							GRPCClientConfig: clientConfig,
+							UserInfo:         userInfo,
+						})
 					})
 				},
 				HideHelpCommand: true,
@@ -197,6 +201,59 @@ func Auth() *cli.Command {
 	}
 }
 
+// authDetails is the structured output of auth show. Secrets are never included.
+type authDetails struct {
+	Server   string         `json:"server"`
+	Target   string         `json:"target"`
+	AuthType string         `json:"auth_type"`
+	TLS      authTLS        `json:"tls"`
+	OIDC     *authOIDC      `json:"oidc,omitempty"`
+	User     *auth.UserInfo `json:"user,omitempty"`
+}
+
+type authTLS struct {
+	Enable   bool   `json:"enable"`
+	CertPath string `json:"cert_path,omitempty"`
+	CAFile   string `json:"ca_file,omitempty"`
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+}
+
+type authOIDC struct {
+	Issuer               string `json:"issuer"`
+	CertificateAuthority string `json:"certificate_authority,omitempty"`
+	ClientID             string `json:"client_id"`
+}
+
+func newAuthDetails(server string, cfg components.GRPCClientConfig, userInfo auth.UserInfo) *authDetails {
+	details := &authDetails{
+		Server:   server,
+		Target:   cfg.Target,
+		AuthType: cfg.AuthType,
+		TLS: authTLS{
+			Enable:   cfg.TLS.Enable,
+			CertPath: cfg.TLS.CertPath,
+			CAFile:   cfg.TLS.CAFile,
+			CertFile: cfg.TLS.CertFile,
+			KeyFile:  cfg.TLS.KeyFile,
+		},
+	}
+
+	if cfg.AuthType == "oidc" {
+		details.OIDC = &authOIDC{
+			Issuer:               cfg.OIDC.Issuer.ServerURL,
+			CertificateAuthority: cfg.OIDC.Issuer.CertificateAuthority,
+			ClientID:             cfg.OIDC.ClientID,
+		}
+	}
+
+	if userInfo.Subject != "" {
+		details.User = &userInfo
+	}
+
+	return details
+}
+
 type data struct {
 	Server string
 	components.GRPCClientConfig
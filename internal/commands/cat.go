@@ -0,0 +1,105 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli/v2"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/components"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/flagset"
+)
+
+// Cat returns a command that streams files from a dataset without pulling it.
+func Cat() *cli.Command {
+	return &cli.Command{
+		Name:  "cat",
+		Usage: "Streams files from a dataset to stdout without pulling it",
+		UsageText: flagset.ExampleString(
+			"aetherfs cat [host/]dataset[:tag]/path...",
+			"aetherfs cat maxmind:v1/GeoLite2-City.mmdb",
+			"aetherfs cat private.company.io/@scope/models:v2/config.json",
+		),
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() == 0 {
+				return fmt.Errorf("missing file argument")
+			}
+
+			for _, arg := range ctx.Args().Slice() {
+				ref, err := parseFileReference(arg)
+				if err != nil {
+					return err
+				}
+
+				if ref.Path == "" {
+					return fmt.Errorf("%s: missing file path", arg)
+				}
+
+				conn, err := components.GRPCClientFor(ctx.Context, local.Extract(ctx.Context).Credentials(), ref.Host)
+				if err != nil {
+					return err
+				}
+
+				err = func() error {
+					defer conn.Close()
+
+					resp, err := datasetv1.NewDatasetAPIClient(conn).Lookup(ctx.Context, &datasetv1.LookupRequest{
+						Tag: &datasetv1.Tag{
+							Name:    ref.Dataset,
+							Version: ref.Version,
+						},
+					})
+					if err != nil {
+						return err
+					}
+
+					var file *datasetv1.File
+					for _, f := range resp.Dataset.Files {
+						if f.Name == ref.Path {
+							file = f
+						}
+					}
+
+					if file == nil {
+						return fmt.Errorf("%s: no such file", ref.Path)
+					}
+
+					reader := &afs.DatasetFile{
+						Context:     ctx.Context,
+						BlockAPI:    blockv1.NewBlockAPIClient(conn),
+						Dataset:     resp.Dataset,
+						CurrentPath: file.Name,
+						File:        file,
+					}
+
+					// reading a block at a time avoids a download for every small buffer io.Copy would use
+					size := int64(resp.Dataset.BlockSize)
+					if file.Size < size {
+						size = file.Size
+					}
+
+					if size <= 0 {
+						size = 1
+					}
+
+					_, err = io.CopyBuffer(struct{ io.Writer }{ctx.App.Writer}, struct{ io.Reader }{reader}, make([]byte, size))
+					return err
+				}()
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		HideHelpCommand: true,
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/components"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/output"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/flagset"
+)
+
+// diff compares the datasets. When both are on the same host, the host compares them so the manifests don't need to be
+// downloaded.
+func diff(ctx context.Context, base, target dataset.Tag) (*datasetv1.DiffResponse, error) {
+	if base.Host == target.Host {
+		conn, err := components.GRPCClientFor(ctx, local.Extract(ctx).Credentials(), base.Host)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		resp, err := datasetv1.NewDatasetAPIClient(conn).Diff(ctx, &datasetv1.DiffRequest{
+			Base:   &datasetv1.Tag{Name: base.Dataset, Version: base.Version},
+			Target: &datasetv1.Tag{Name: target.Dataset, Version: target.Version},
+		})
+
+		// hubs that predate the diff api are compared locally
+		if status.Code(err) != codes.Unimplemented {
+			return resp, err
+		}
+	}
+
+	baseResp, err := lookup(ctx, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup %s: %w", base.String(), err)
+	}
+
+	targetResp, err := lookup(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup %s: %w", target.String(), err)
+	}
+
+	resp := dataset.Diff(baseResp.Dataset, targetResp.Dataset)
+	resp.BaseDigest = baseResp.Digest
+	resp.TargetDigest = targetResp.Digest
+
+	return resp, nil
+}
+
+// Diff returns a command that reports the differences between two datasets.
+func Diff() *cli.Command {
+	return &cli.Command{
+		Name:  "diff",
+		Usage: "Reports the differences between two datasets",
+		UsageText: flagset.ExampleString(
+			"aetherfs diff [options] <base> <target>",
+			"aetherfs diff maxmind:v1 maxmind:v2",
+			"aetherfs diff maxmind@sha256:<hex> maxmind:prod",
+			"aetherfs --output json diff private.company.io/maxmind:prod maxmind:prod",
+		),
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() != 2 {
+				return fmt.Errorf("expected base and target datasets")
+			}
+
+			base, err := dataset.ParseTag(ctx.Args().Get(0))
+			if err != nil {
+				return fmt.Errorf("invalid base dataset: %w", err)
+			}
+
+			target, err := dataset.ParseTag(ctx.Args().Get(1))
+			if err != nil {
+				return fmt.Errorf("invalid target dataset: %w", err)
+			}
+
+			resp, err := diff(ctx.Context, base, target)
+			if err != nil {
+				return err
+			}
+
+			t, err := template.New("summary").Funcs(template.FuncMap{
+				"bytes":   func(v int64) string { return humanize.IBytes(uint64(v)) },
+				"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+			}).Parse(diffSummary)
+			if err != nil {
+				return err
+			}
+
+			return output.Write(ctx.Context, ctx.App.Writer, resp, func(w io.Writer) error {
+				return t.Execute(w, &diffSummaryData{
+					Base:     base.String(),
+					Target:   target.String(),
+					Response: resp,
+				})
+			})
+		},
+		HideHelpCommand: true,
+	}
+}
+
+type diffSummaryData struct {
+	Base     string
+	Target   string
+	Response *datasetv1.DiffResponse
+}
+
+const diffSummary = `
+{{- with .Response -}}
+BASE:   {{ $.Base }} ({{ .BaseDigest }})
+TARGET: {{ $.Target }} ({{ .TargetDigest }})
+{{- range .Added }}
+  ADDED:      {{ . }}
+{{- end }}
+{{- range .Removed }}
+  REMOVED:    {{ . }}
+{{- end }}
+{{- range .Resized }}
+  RESIZED:    {{ .Name }} ({{ bytes .BaseSize }} -> {{ bytes .TargetSize }})
+{{- end }}
+{{- range .Changed }}
+  CHANGED:    {{ . }}
+{{- end }}
+{{- range .Unverified }}
+  UNVERIFIED: {{ . }}
+{{- end }}
+UNCHANGED FILES: {{ .Unchanged }}
+SHARED BLOCKS:   {{ .Blocks.Shared }} ({{ bytes .Blocks.SharedBytes }})
+ADDED BLOCKS:    {{ .Blocks.Added }} ({{ bytes .Blocks.AddedBytes }})
+REMOVED BLOCKS:  {{ .Blocks.Removed }} ({{ bytes .Blocks.RemovedBytes }})
+OVERLAP:         {{ percent .Blocks.Ratio }}
+{{ end }}`
@@ -0,0 +1,133 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+
+	"github.com/mjpitz/aetherfs/internal/output"
+	"github.com/mjpitz/myago/flagset"
+)
+
+// DuConfig encapsulates all the configuration required to report the disk usage of a dataset.
+type DuConfig struct {
+	All      bool `json:"all" alias:"a"               usage:"report files in addition to directories"`
+	MaxDepth int  `json:"max_depth" alias:"max-depth" usage:"only report entries this many levels below the path, unlimited when zero"`
+}
+
+// duEntry is the size of a file or directory below the path.
+type duEntry struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// duOutput is the structured output of du. Like the text output, the total for the path is last.
+type duOutput struct {
+	Entries []duEntry `json:"entries"`
+}
+
+// Du returns a command that reports the size of each directory in a dataset.
+func Du() *cli.Command {
+	cfg := &DuConfig{}
+
+	return &cli.Command{
+		Name:  "du",
+		Usage: "Reports the size of each directory in a dataset",
+		UsageText: flagset.ExampleString(
+			"aetherfs du [options] [host/]dataset[:tag][/path]",
+			"aetherfs du maxmind:v1",
+			"aetherfs du --max-depth 1 private.company.io/models:v2/checkpoints",
+		),
+		Flags: flagset.Extract(cfg),
+		Action: func(ctx *cli.Context) error {
+			ref, err := parseFileReference(ctx.Args().Get(0))
+			if err != nil {
+				return err
+			}
+
+			resp, err := lookup(ctx.Context, ref.Tag)
+			if err != nil {
+				return err
+			}
+
+			files := filesUnder(resp.Dataset, ref.Path)
+			if ref.Path != "" && len(files) == 0 {
+				return fmt.Errorf("%s: no such file or directory", ref.Path)
+			}
+
+			root := ref.Path
+			if root == "" {
+				root = "."
+			}
+
+			depth := func(name string) int {
+				if name == root {
+					return 0
+				}
+
+				if ref.Path != "" {
+					name = strings.TrimPrefix(name, ref.Path+"/")
+				}
+
+				return strings.Count(name, "/") + 1
+			}
+
+			sizes := make(map[string]int64)
+			for _, file := range files {
+				if cfg.All || file.Name == ref.Path {
+					sizes[file.Name] += file.Size
+				}
+
+				for dir := path.Dir(file.Name); dir != "." && strings.HasPrefix(dir, ref.Path); dir = path.Dir(dir) {
+					sizes[dir] += file.Size
+				}
+
+				if ref.Path == "" {
+					sizes[root] += file.Size
+				}
+			}
+
+			names := make([]string, 0, len(sizes))
+			for name := range sizes {
+				if name != root && (cfg.MaxDepth <= 0 || depth(name) <= cfg.MaxDepth) {
+					names = append(names, name)
+				}
+			}
+
+			sort.Strings(names)
+
+			// like du, the total for the path is reported last
+			names = append(names, root)
+
+			result := &duOutput{
+				Entries: make([]duEntry, 0, len(names)),
+			}
+
+			for _, name := range names {
+				result.Entries = append(result.Entries, duEntry{
+					Path: name,
+					Size: sizes[name],
+				})
+			}
+
+			return output.Write(ctx.Context, ctx.App.Writer, result, func(w io.Writer) error {
+				tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+				for _, entry := range result.Entries {
+					fmt.Fprintf(tw, "%s\t%s\n", humanize.IBytes(uint64(entry.Size)), entry.Path)
+				}
+
+				return tw.Flush()
+			})
+		},
+		HideHelpCommand: true,
+	}
+}
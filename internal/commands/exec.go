@@ -0,0 +1,263 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	"github.com/mjpitz/aetherfs/internal/agent"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/clocks"
+	"github.com/mjpitz/myago/flagset"
+	"github.com/mjpitz/myago/zaputil"
+)
+
+// envName matches the names that can be exported as environment variables.
+var envName = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// ExecConfig encapsulates all the configuration required to run a process with datasets from AetherFS.
+type ExecConfig struct {
+	Datasets *cli.StringSlice `json:"dataset" alias:"d" usage:"name=tag of a dataset to pull, its path is exported as the environment variable name (repeatable)"`
+	Path     string           `json:"path"              usage:"directory the datasets are pulled to (defaults to a temporary directory)"`
+	Watch    time.Duration    `json:"watch"             usage:"how often to check for new versions of the datasets (disabled when 0)"`
+	Signal   string           `json:"signal"            usage:"signal sent to the process when a dataset changes (defaults to HUP, or TERM when restarting)"`
+	Restart  bool             `json:"restart"           usage:"restart the process once it exits after being signaled for a change"`
+}
+
+// execDataset associates a dataset with the environment variable its path is exported as.
+type execDataset struct {
+	Name string
+	Tag  string
+}
+
+func parseExecDatasets(values []string) ([]execDataset, error) {
+	datasets := make([]execDataset, 0, len(values))
+	seen := make(map[string]bool)
+
+	for _, value := range values {
+		idx := strings.Index(value, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("dataset %s must be of the form name=tag", value)
+		}
+
+		name := value[:idx]
+		if !envName.MatchString(name) {
+			return nil, fmt.Errorf("%s is not a valid environment variable name", name)
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("multiple datasets are named %s", name)
+		}
+		seen[name] = true
+
+		tag := dataset.Tag{}
+		if err := tag.UnmarshalText([]byte(value[idx+1:])); err != nil {
+			return nil, fmt.Errorf("invalid tag %s: %v", value[idx+1:], err)
+		}
+
+		datasets = append(datasets, execDataset{
+			Name: name,
+			Tag:  tag.String(),
+		})
+	}
+
+	return datasets, nil
+}
+
+// exitStatus converts the result of waiting on the process into an error carrying its exit code.
+func exitStatus(err error) error {
+	exitErr := &exec.ExitError{}
+	if errors.As(err, &exitErr) {
+		return cli.Exit("", exitCode(exitErr.ProcessState))
+	}
+
+	return err
+}
+
+// Exec returns a command that runs a process with datasets pulled to local disk.
+func Exec() *cli.Command {
+	cfg := &ExecConfig{}
+
+	return &cli.Command{
+		Name:  "exec",
+		Usage: "Runs a process with datasets pulled from AetherFS",
+		UsageText: flagset.ExampleString(
+			"aetherfs exec [options] -- <command> [args...]",
+			"aetherfs exec -d MAXMIND=maxmind:v1 -- sh -c 'ls $MAXMIND'",
+			"aetherfs exec --path /var/datasets -d MODELS=models:latest --watch 5m -- ./serve",
+			"aetherfs exec -d MODELS=models:latest --watch 5m --restart -- ./serve",
+		),
+		Flags: flagset.Extract(cfg),
+		Action: func(ctx *cli.Context) error {
+			args := ctx.Args().Slice()
+			if len(args) == 0 {
+				return fmt.Errorf("missing command")
+			}
+
+			datasets, err := parseExecDatasets(cfg.Datasets.Value())
+			switch {
+			case err != nil:
+				return err
+			case len(datasets) == 0:
+				return fmt.Errorf("missing datasets")
+			}
+
+			var sig os.Signal
+			if cfg.Watch > 0 {
+				name := cfg.Signal
+				switch {
+				case name != "":
+				case cfg.Restart:
+					name = "TERM"
+				default:
+					name = "HUP"
+				}
+
+				sig, err = parseSignal(name)
+				if err != nil {
+					return err
+				}
+			}
+
+			root := cfg.Path
+			if root != "" {
+				root, err = filepath.Abs(root)
+				if err != nil {
+					return err
+				}
+			}
+
+			tags := make([]string, 0, len(datasets))
+			for _, ds := range datasets {
+				tags = append(tags, ds.Tag)
+			}
+
+			logger := zaputil.Extract(ctx.Context)
+
+			agentService := &agent.Service{
+				Credentials: local.Extract(ctx.Context).Credentials(),
+			}
+
+			resp, err := agentService.Subscribe(ctx.Context, &agentv1.SubscribeRequest{
+				Path: root,
+				Tags: tags,
+			})
+			if err != nil {
+				return err
+			}
+
+			job, err := waitForJob(ctx, agentService, resp.JobId)
+			if err != nil {
+				return err
+			}
+
+			env := os.Environ()
+			for _, ds := range datasets {
+				env = append(env, ds.Name+"="+job.Subscribe.Paths[ds.Tag])
+			}
+
+			start := func() (*exec.Cmd, <-chan error, error) {
+				cmd := exec.Command(args[0], args[1:]...)
+				cmd.Env = env
+				cmd.Stdin = os.Stdin
+				cmd.Stdout = os.Stdout
+				cmd.Stderr = os.Stderr
+
+				if err := cmd.Start(); err != nil {
+					return nil, nil, err
+				}
+
+				exited := make(chan error, 1)
+				go func() {
+					exited <- cmd.Wait()
+				}()
+
+				return cmd, exited, nil
+			}
+
+			// signals are forwarded for as long as the process runs, including those that cancel the context
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, forwardedSignals...)
+			defer signal.Stop(signals)
+
+			cmd, exited, err := start()
+			if err != nil {
+				return err
+			}
+
+			var refresh <-chan time.Time
+			if cfg.Watch > 0 {
+				ticker := clocks.Extract(ctx.Context).NewTicker(cfg.Watch)
+				defer ticker.Stop()
+
+				refresh = ticker.Chan()
+			}
+
+			done := ctx.Context.Done()
+			restarting := false
+
+			for {
+				select {
+				case <-done:
+					// stop looking for changes, but leave it to the process to decide when to exit
+					done = nil
+					refresh = nil
+
+				case s := <-signals:
+					_ = cmd.Process.Signal(s)
+
+				case <-refresh:
+					resp, err := agentService.Subscribe(ctx.Context, &agentv1.SubscribeRequest{
+						Sync: true,
+						Path: root,
+						Tags: tags,
+					})
+
+					switch {
+					case err != nil:
+						logger.Warn("failed to refresh datasets", zap.Error(err))
+						continue
+					case len(resp.Updated) == 0 || restarting:
+						continue
+					}
+
+					logger.Info("datasets updated", zap.Strings("tags", resp.Updated))
+
+					if err := cmd.Process.Signal(sig); err != nil {
+						logger.Warn("failed to signal process", zap.Error(err))
+						continue
+					}
+
+					restarting = cfg.Restart
+
+				case err := <-exited:
+					if !restarting || ctx.Context.Err() != nil {
+						return exitStatus(err)
+					}
+
+					restarting = false
+
+					cmd, exited, err = start()
+					if err != nil {
+						return err
+					}
+				}
+			}
+		},
+		HideHelpCommand: true,
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+//go:build windows
+// +build windows
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// forwardedSignals are relayed to the process run by exec.
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// parseSignal resolves the signal by name. Windows can only deliver KILL to another process.
+func parseSignal(name string) (os.Signal, error) {
+	if strings.TrimPrefix(strings.ToUpper(name), "SIG") != "KILL" {
+		return nil, fmt.Errorf("unsupported signal: %s", name)
+	}
+
+	return os.Kill, nil
+}
+
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
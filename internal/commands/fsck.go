@@ -0,0 +1,128 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+	"github.com/mjpitz/aetherfs/internal/components"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/output"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/flagset"
+	"github.com/mjpitz/myago/zaputil"
+)
+
+// FsckConfig encapsulates all the configuration required to check the integrity of a hub's storage.
+type FsckConfig struct {
+	Rehash            bool   `json:"rehash"                                            usage:"download every referenced block and recompute its signature"`
+	RequestsPerSecond int    `json:"requests_per_second" alias:"requests-per-second" usage:"the maximum number of requests made to storage per second, unlimited when zero"`
+	BytesPerSecond    string `json:"bytes_per_second" alias:"bytes-per-second"       usage:"the maximum amount of data read from storage per second when rehashing (e.g. 50MiB)"`
+}
+
+// Fsck returns a command that checks the integrity of a hub's storage.
+func Fsck() *cli.Command {
+	cfg := &FsckConfig{}
+
+	return &cli.Command{
+		Name:  "fsck",
+		Usage: "Checks the integrity of the datasets stored by a hub",
+		UsageText: flagset.ExampleString(
+			"aetherfs fsck [options] [host]",
+			"aetherfs fsck",
+			"aetherfs fsck --requests-per-second 50 private.company.io",
+			"aetherfs fsck --rehash --bytes-per-second 50MiB private.company.io",
+		),
+		Flags: flagset.Extract(cfg),
+		Action: func(ctx *cli.Context) error {
+			host := ctx.Args().Get(0)
+			if host == "" {
+				host = dataset.DefaultHost
+			}
+
+			bytesPerSecond, err := parseSize(cfg.BytesPerSecond)
+			if err != nil {
+				return err
+			}
+
+			fsckRequest := &adminv1.FsckRequest{
+				Rehash:            cfg.Rehash,
+				RequestsPerSecond: int32(cfg.RequestsPerSecond),
+				BytesPerSecond:    bytesPerSecond,
+			}
+
+			zaputil.Extract(ctx.Context).Debug("fsck", zap.Stringer("request", fsckRequest))
+
+			conn, err := components.GRPCClientFor(ctx.Context, local.Extract(ctx.Context).Credentials(), host)
+			if err != nil {
+				return err
+			}
+
+			resp, err := adminv1.NewAdminAPIClient(conn).Fsck(ctx.Context, fsckRequest)
+			if err != nil {
+				return err
+			}
+
+			t, err := template.New("summary").Funcs(template.FuncMap{
+				"bytes": func(v int64) string { return humanize.IBytes(uint64(v)) },
+			}).Parse(fsckSummary)
+			if err != nil {
+				return err
+			}
+
+			orphaned := int64(0)
+			for _, block := range resp.Orphaned {
+				orphaned += block.Size
+			}
+
+			err = output.Write(ctx.Context, ctx.App.Writer, resp, func(w io.Writer) error {
+				return t.Execute(w, &fsckSummaryData{
+					Response:      resp,
+					OrphanedBytes: orphaned,
+				})
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(resp.Broken) > 0 {
+				return cli.Exit(fmt.Sprintf("%d dataset(s) are broken", len(resp.Broken)), 1)
+			}
+
+			return nil
+		},
+		HideHelpCommand: true,
+	}
+}
+
+type fsckSummaryData struct {
+	Response      *adminv1.FsckResponse
+	OrphanedBytes int64
+}
+
+const fsckSummary = `
+{{- range $broken := .Response.Broken }}
+BROKEN: {{ $broken.Tag.Name }}:{{ $broken.Tag.Version }}{{ if $broken.Reason }} ({{ $broken.Reason }}){{ end }}
+{{- range $broken.Blocks }}
+  {{ .Signature }} ({{ .Reason }})
+{{- end }}
+{{- end }}
+{{- range .Response.Orphaned }}
+ORPHANED: {{ .Signature }} ({{ bytes .Size }})
+{{- end }}
+{{- range .Response.Unchecked }}
+UNCHECKED: {{ . }} (not supported by the storage)
+{{- end }}
+DATASETS CHECKED: {{ .Response.Datasets }}
+BLOCKS CHECKED:   {{ .Response.Blocks }}
+BROKEN DATASETS:  {{ len .Response.Broken }}
+ORPHANED BLOCKS:  {{ len .Response.Orphaned }} ({{ bytes .OrphanedBytes }})
+`
@@ -0,0 +1,59 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/components"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+)
+
+// lookup resolves the dataset for the tag from the tag's host.
+func lookup(ctx context.Context, tag dataset.Tag) (*datasetv1.LookupResponse, error) {
+	conn, err := components.GRPCClientFor(ctx, local.Extract(ctx).Credentials(), tag.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return datasetv1.NewDatasetAPIClient(conn).Lookup(ctx, &datasetv1.LookupRequest{
+		Tag: &datasetv1.Tag{
+			Name:    tag.Dataset,
+			Version: tag.Version,
+		},
+	})
+}
+
+// parseFileReference parses a reference to a path within a dataset, defaulting to the latest tag.
+func parseFileReference(val string) (dataset.Reference, error) {
+	ref, err := dataset.ParseReference(val)
+	switch {
+	case err != nil:
+		return ref, err
+	case ref.Dataset == "":
+		return ref, fmt.Errorf("missing dataset")
+	case ref.Version == "":
+		ref.Version = "latest"
+	}
+
+	return ref, nil
+}
+
+// filesUnder returns the files at or below the path. Every file is returned for an empty path.
+func filesUnder(ds *datasetv1.Dataset, path string) []*datasetv1.File {
+	files := make([]*datasetv1.File, 0, len(ds.Files))
+
+	for _, file := range ds.Files {
+		if path == "" || file.Name == path || strings.HasPrefix(file.Name, path+"/") {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
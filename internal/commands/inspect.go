@@ -0,0 +1,106 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"io"
+	"path"
+	"text/template"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/output"
+	"github.com/mjpitz/myago/flagset"
+)
+
+// Inspect returns a command that summarizes the manifest of a dataset.
+func Inspect() *cli.Command {
+	return &cli.Command{
+		Name:  "inspect",
+		Usage: "Summarizes the manifest of a dataset",
+		UsageText: flagset.ExampleString(
+			"aetherfs inspect [host/]dataset[:tag]",
+			"aetherfs inspect maxmind:v1",
+			"aetherfs inspect private.company.io/maxmind@sha256:<hex>",
+		),
+		Action: func(ctx *cli.Context) error {
+			ref, err := parseFileReference(ctx.Args().Get(0))
+			if err != nil {
+				return err
+			}
+
+			resp, err := lookup(ctx.Context, ref.Tag)
+			if err != nil {
+				return err
+			}
+
+			ds := resp.Dataset
+
+			data := &inspectSummaryData{
+				Tag:       ref.Tag.String(),
+				Digest:    resp.Digest,
+				BlockSize: int64(ds.BlockSize),
+				Files:     len(ds.Files),
+				Blocks:    len(ds.Blocks),
+			}
+
+			unique := make(map[string]bool, len(ds.Blocks))
+			for i, size := range dataset.BlockSizes(ds) {
+				if !unique[ds.Blocks[i]] {
+					unique[ds.Blocks[i]] = true
+					data.UniqueSize += size
+				}
+			}
+
+			data.UniqueBlocks = len(unique)
+
+			dirs := make(map[string]bool)
+			for _, file := range ds.Files {
+				data.TotalSize += file.Size
+
+				for dir := path.Dir(file.Name); dir != "."; dir = path.Dir(dir) {
+					dirs[dir] = true
+				}
+			}
+
+			data.Directories = len(dirs)
+
+			t, err := template.New("summary").Funcs(template.FuncMap{
+				"bytes": func(v int64) string { return humanize.IBytes(uint64(v)) },
+			}).Parse(inspectSummary)
+			if err != nil {
+				return err
+			}
+
+			return output.Write(ctx.Context, ctx.App.Writer, data, func(w io.Writer) error {
+				return t.Execute(w, data)
+			})
+		},
+		HideHelpCommand: true,
+	}
+}
+
+type inspectSummaryData struct {
+	Tag          string `json:"tag"`
+	Digest       string `json:"digest"`
+	BlockSize    int64  `json:"block_size"`
+	Files        int    `json:"files"`
+	Directories  int    `json:"directories"`
+	TotalSize    int64  `json:"total_size"`
+	Blocks       int    `json:"blocks"`
+	UniqueBlocks int    `json:"unique_blocks"`
+	UniqueSize   int64  `json:"unique_size"`
+}
+
+const inspectSummary = `DATASET:       {{ .Tag }}
+DIGEST:        {{ .Digest }}
+BLOCK SIZE:    {{ bytes .BlockSize }}
+FILES:         {{ .Files }}
+DIRECTORIES:   {{ .Directories }}
+SIZE:          {{ bytes .TotalSize }}
+BLOCKS:        {{ .Blocks }}
+UNIQUE BLOCKS: {{ .UniqueBlocks }} ({{ bytes .UniqueSize }})
+`
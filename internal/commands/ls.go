@@ -0,0 +1,219 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/components"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/output"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/flagset"
+)
+
+// LsConfig encapsulates all the configuration required to list the contents of a hub.
+type LsConfig struct {
+	Long      bool `json:"long" alias:"l"      usage:"include the size of each file"`
+	Recursive bool `json:"recursive" alias:"r" usage:"list every file below the path instead of only its direct children"`
+}
+
+// lsEntry is a file or directory below the listed path.
+type lsEntry struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Directory bool   `json:"directory"`
+}
+
+// lsDatasets, lsTags, and lsFiles are the structured outputs of ls for each kind of listing.
+type lsDatasets struct {
+	Datasets []string `json:"datasets"`
+}
+
+type lsTags struct {
+	Tags []string `json:"tags"`
+}
+
+type lsFiles struct {
+	Files []lsEntry `json:"files"`
+}
+
+// writeLines writes one value per line.
+func writeLines(values []string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		for _, value := range values {
+			if _, err := fmt.Fprintln(w, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// listEntries returns the files and directories below the path, sorted by name.
+func listEntries(ds *datasetv1.Dataset, path string, recursive bool) ([]lsEntry, error) {
+	files := filesUnder(ds, path)
+
+	if path != "" && len(files) == 0 {
+		return nil, fmt.Errorf("%s: no such file or directory", path)
+	}
+
+	prefix := ""
+	if path != "" {
+		prefix = path + "/"
+	}
+
+	sizes := make(map[string]int64)
+	dirs := make(map[string]bool)
+
+	for _, file := range files {
+		if file.Name == path {
+			// the path is a file
+			return []lsEntry{{Name: file.Name, Size: file.Size}}, nil
+		}
+
+		name := strings.TrimPrefix(file.Name, prefix)
+		if idx := strings.Index(name, "/"); idx >= 0 && !recursive {
+			name = name[:idx]
+			dirs[name] = true
+		}
+
+		sizes[name] += file.Size
+	}
+
+	entries := make([]lsEntry, 0, len(sizes))
+	for name, size := range sizes {
+		entries = append(entries, lsEntry{
+			Name:      name,
+			Size:      size,
+			Directory: dirs[name],
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, nil
+}
+
+// Ls returns a command that lists the datasets, tags, and files on a hub.
+func Ls() *cli.Command {
+	cfg := &LsConfig{}
+
+	return &cli.Command{
+		Name:  "ls",
+		Usage: "Lists the datasets, tags, and files on a hub",
+		UsageText: flagset.ExampleString(
+			"aetherfs ls [options] [host/][dataset[:tag][/path]]",
+			"aetherfs ls",
+			"aetherfs ls private.company.io/",
+			"aetherfs ls maxmind",
+			"aetherfs ls -l maxmind:v1",
+			"aetherfs ls -r private.company.io/@scope/maxmind:v1/geo",
+		),
+		Flags: flagset.Extract(cfg),
+		Action: func(ctx *cli.Context) error {
+			ref, err := dataset.ParseReference(ctx.Args().Get(0))
+			if err != nil {
+				return err
+			}
+
+			conn, err := components.GRPCClientFor(ctx.Context, local.Extract(ctx.Context).Credentials(), ref.Host)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			datasetAPI := datasetv1.NewDatasetAPIClient(conn)
+
+			switch {
+			case ref.Dataset == "":
+				resp, err := datasetAPI.List(ctx.Context, &datasetv1.ListRequest{})
+				if err != nil {
+					return err
+				}
+
+				result := &lsDatasets{
+					Datasets: make([]string, 0, len(resp.Datasets)),
+				}
+
+				for _, ds := range resp.Datasets {
+					result.Datasets = append(result.Datasets, ds.Name)
+				}
+
+				return output.Write(ctx.Context, ctx.App.Writer, result, writeLines(result.Datasets))
+
+			case ref.Version == "" && ref.Path == "":
+				resp, err := datasetAPI.ListTags(ctx.Context, &datasetv1.ListTagsRequest{
+					Name: ref.Dataset,
+				})
+				if err != nil {
+					return err
+				}
+
+				result := &lsTags{
+					Tags: make([]string, 0, len(resp.Tags)),
+				}
+
+				for _, tag := range resp.Tags {
+					result.Tags = append(result.Tags, (&dataset.Tag{Dataset: tag.Name, Version: tag.Version}).String())
+				}
+
+				return output.Write(ctx.Context, ctx.App.Writer, result, writeLines(result.Tags))
+			}
+
+			if ref.Version == "" {
+				ref.Version = "latest"
+			}
+
+			resp, err := datasetAPI.Lookup(ctx.Context, &datasetv1.LookupRequest{
+				Tag: &datasetv1.Tag{
+					Name:    ref.Dataset,
+					Version: ref.Version,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			entries, err := listEntries(resp.Dataset, ref.Path, cfg.Recursive)
+			if err != nil {
+				return err
+			}
+
+			result := &lsFiles{
+				Files: entries,
+			}
+
+			return output.Write(ctx.Context, ctx.App.Writer, result, func(w io.Writer) error {
+				tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+				for _, entry := range result.Files {
+					name := entry.Name
+					if entry.Directory {
+						name += "/"
+					}
+
+					if cfg.Long {
+						fmt.Fprintf(tw, "%s\t  %s\n", humanize.IBytes(uint64(entry.Size)), name)
+					} else {
+						fmt.Fprintln(tw, name)
+					}
+				}
+
+				return tw.Flush()
+			})
+		},
+		HideHelpCommand: true,
+	}
+}
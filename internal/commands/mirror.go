@@ -0,0 +1,111 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/components"
+	"github.com/mjpitz/aetherfs/internal/mirror"
+	"github.com/mjpitz/aetherfs/internal/output"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/flagset"
+)
+
+// MirrorConfig encapsulates all the configuration required to mirror datasets between hubs.
+type MirrorConfig struct {
+	Dataset string `json:"dataset" usage:"glob of dataset names to mirror (e.g. maxmind-*), all when empty"`
+	Tags    string `json:"tags"    usage:"glob of tag versions to mirror (e.g. v1.*), all when empty"`
+}
+
+// Mirror returns a command that copies datasets from one hub to another.
+func Mirror() *cli.Command {
+	cfg := &MirrorConfig{}
+
+	return &cli.Command{
+		Name:  "mirror",
+		Usage: "Copies datasets from one hub to another",
+		UsageText: flagset.ExampleString(
+			"aetherfs mirror [options] <src-host> <dst-host>",
+			"aetherfs mirror aetherfs.company.io aetherfs.us-east-1.company.io",
+			"aetherfs mirror --dataset 'maxmind*' --tags 'v1.*' aetherfs.company.io localhost:8080",
+		),
+		Flags: flagset.Extract(cfg),
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() != 2 {
+				return fmt.Errorf("expected source and destination hosts")
+			}
+
+			credentials := local.Extract(ctx.Context).Credentials()
+
+			sourceConn, err := components.GRPCClientFor(ctx.Context, credentials, ctx.Args().Get(0))
+			if err != nil {
+				return err
+			}
+			defer sourceConn.Close()
+
+			destinationConn, err := components.GRPCClientFor(ctx.Context, credentials, ctx.Args().Get(1))
+			if err != nil {
+				return err
+			}
+			defer destinationConn.Close()
+
+			m := &mirror.Mirror{
+				Source: mirror.Hub{
+					Name:       ctx.Args().Get(0),
+					BlockAPI:   blockv1.NewBlockAPIClient(sourceConn),
+					DatasetAPI: datasetv1.NewDatasetAPIClient(sourceConn),
+				},
+				Destination: mirror.Hub{
+					Name:       ctx.Args().Get(1),
+					BlockAPI:   blockv1.NewBlockAPIClient(destinationConn),
+					DatasetAPI: datasetv1.NewDatasetAPIClient(destinationConn),
+				},
+				Datasets: cfg.Dataset,
+				Tags:     cfg.Tags,
+			}
+
+			result, err := m.Sync(ctx.Context)
+			if err != nil {
+				return err
+			}
+
+			err = output.Write(ctx.Context, ctx.App.Writer, result, func(w io.Writer) error {
+				tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+				for _, tag := range result.Mirrored {
+					fmt.Fprintf(tw, "MIRRORED\t%s\n", tag)
+				}
+
+				for _, tag := range result.UpToDate {
+					fmt.Fprintf(tw, "UP TO DATE\t%s\n", tag)
+				}
+
+				for _, failure := range result.Failed {
+					fmt.Fprintf(tw, "FAILED\t%s\t%s\n", failure.Tag, failure.Error)
+				}
+
+				fmt.Fprintf(tw, "COPIED\t%d blocks (%s)\n", result.Blocks, humanize.IBytes(uint64(result.Bytes)))
+
+				return tw.Flush()
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(result.Failed) > 0 {
+				return cli.Exit(fmt.Sprintf("%d tag(s) failed to mirror", len(result.Failed)), 1)
+			}
+
+			return nil
+		},
+		HideHelpCommand: true,
+	}
+}
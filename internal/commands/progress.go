@@ -0,0 +1,85 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v2"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	"github.com/mjpitz/aetherfs/internal/agent"
+)
+
+const progressBarWidth = 30
+
+// progressBar renders a single line summary of the job's progress.
+func progressBar(job *agentv1.Job) string {
+	progress := job.GetProgress()
+
+	ratio := 0.0
+	if progress.GetBytesTotal() > 0 {
+		ratio = float64(progress.GetBytesDone()) / float64(progress.GetBytesTotal())
+	} else if job.State == agentv1.JobState_JOB_STATE_SUCCEEDED {
+		ratio = 1
+	}
+
+	filled := int(ratio * progressBarWidth)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+
+	line := fmt.Sprintf("[%s%s] %5.1f%% %s / %s",
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), ratio*100,
+		humanize.IBytes(uint64(progress.GetBytesDone())), humanize.IBytes(uint64(progress.GetBytesTotal())))
+
+	switch job.Kind {
+	case agentv1.JobKind_JOB_KIND_PUBLISH:
+		line += fmt.Sprintf("  blocks %d/%d", progress.GetBlocksDone(), progress.GetBlocksTotal())
+	case agentv1.JobKind_JOB_KIND_SUBSCRIBE:
+		line += fmt.Sprintf("  files %d/%d", progress.GetFilesDone(), progress.GetFilesTotal())
+	}
+
+	return line
+}
+
+// waitForJob blocks until the job completes. While waiting, a live progress bar is rendered to stderr when it's
+// attached to a terminal. An error is returned if the job failed.
+func waitForJob(ctx *cli.Context, svc *agent.Service, id string) (*agentv1.Job, error) {
+	var out io.Writer
+	if f, ok := ctx.App.ErrWriter.(*os.File); ok && isatty.IsTerminal(f.Fd()) {
+		out = f
+	}
+
+	var last *agentv1.Job
+	err := svc.WatchJobUpdates(ctx.Context, id, func(job *agentv1.Job) error {
+		last = job
+
+		if out != nil {
+			_, _ = fmt.Fprintf(out, "\r\033[K%s", progressBar(job))
+		}
+
+		return nil
+	})
+
+	if out != nil {
+		_, _ = fmt.Fprintln(out)
+	}
+
+	switch {
+	case err != nil:
+		return nil, err
+	case last.State == agentv1.JobState_JOB_STATE_FAILED:
+		return last, fmt.Errorf("%s", last.Error)
+	case last.State == agentv1.JobState_JOB_STATE_CANCELLED:
+		return last, fmt.Errorf("job was cancelled")
+	}
+
+	return last, nil
+}
@@ -0,0 +1,142 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/template"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	"github.com/mjpitz/aetherfs/internal/agent"
+	"github.com/mjpitz/aetherfs/internal/output"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/flagset"
+	"github.com/mjpitz/myago/zaputil"
+)
+
+// PruneConfig encapsulates all the configuration required to prune previously pulled datasets.
+type PruneConfig struct {
+	KeepLast     int              `json:"keep_last" alias:"keep-last"           usage:"the number of versions of each dataset to keep, unlimited when zero"`
+	MaxDiskUsage string           `json:"max_disk_usage" alias:"max-disk-usage" usage:"the maximum amount of disk pulled datasets may occupy (e.g. 20GiB)"`
+	MinFreeSpace string           `json:"min_free_space" alias:"min-free-space" usage:"the amount of disk space to keep free on the filesystem (e.g. 5GiB)"`
+	DryRun       bool             `json:"dry_run" alias:"dry-run"               usage:"report what would be removed without removing anything"`
+	Symlinks     *cli.StringSlice `json:"symlink"                               usage:"a symlink outside of the path whose target must be kept (repeatable)"`
+}
+
+// parseSize parses a human readable size (e.g. 5GiB). Empty sizes are treated as zero.
+func parseSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+
+	v, err := humanize.ParseBytes(size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+
+	return int64(v), nil
+}
+
+// prunePolicy converts the configured limits into a request that can be applied to any path.
+func prunePolicy(keepLast int, maxDiskUsage, minFreeSpace string) (*agentv1.PruneRequest, error) {
+	maxUsage, err := parseSize(maxDiskUsage)
+	if err != nil {
+		return nil, err
+	}
+
+	minFree, err := parseSize(minFreeSpace)
+	if err != nil {
+		return nil, err
+	}
+
+	return &agentv1.PruneRequest{
+		KeepLast:     int32(keepLast),
+		MaxDiskUsage: maxUsage,
+		MinFreeSpace: minFree,
+	}, nil
+}
+
+// Prune returns a command that removes previously pulled datasets that are no longer needed.
+func Prune() *cli.Command {
+	cfg := &PruneConfig{}
+
+	return &cli.Command{
+		Name:  "prune",
+		Usage: "Removes old versions of pulled datasets",
+		UsageText: flagset.ExampleString(
+			"aetherfs prune [options] <path>",
+			"aetherfs prune --keep-last 2 /var/datasets",
+			"aetherfs prune --max-disk-usage 20GiB --min-free-space 5GiB /var/datasets",
+			"aetherfs prune --dry-run --keep-last 1 --symlink /srv/app/current /var/datasets",
+		),
+		Flags: flagset.Extract(cfg),
+		Action: func(ctx *cli.Context) error {
+			root := ctx.Args().Get(0)
+			if root == "" {
+				return fmt.Errorf("missing path argument")
+			}
+
+			root, err := filepath.Abs(root)
+			if err != nil {
+				return err
+			}
+
+			pruneRequest, err := prunePolicy(cfg.KeepLast, cfg.MaxDiskUsage, cfg.MinFreeSpace)
+			if err != nil {
+				return err
+			}
+
+			pruneRequest.Path = root
+			pruneRequest.DryRun = cfg.DryRun
+			pruneRequest.Symlinks = cfg.Symlinks.Value()
+
+			zaputil.Extract(ctx.Context).Debug("prune", zap.Stringer("request", pruneRequest))
+
+			agentService := &agent.Service{
+				Credentials:   local.Extract(ctx.Context).Credentials(),
+				Subscriptions: local.Extract(ctx.Context).Subscriptions(),
+			}
+
+			resp, err := agentService.Prune(ctx.Context, pruneRequest)
+			if err != nil {
+				return err
+			}
+
+			t, err := template.New("summary").Funcs(template.FuncMap{
+				"bytes": func(v int64) string { return humanize.IBytes(uint64(v)) },
+			}).Parse(pruneSummary)
+			if err != nil {
+				return err
+			}
+
+			return output.Write(ctx.Context, ctx.App.Writer, resp, func(w io.Writer) error {
+				return t.Execute(w, &pruneSummaryData{
+					DryRun:   cfg.DryRun,
+					Response: resp,
+				})
+			})
+		},
+		HideHelpCommand: true,
+	}
+}
+
+type pruneSummaryData struct {
+	DryRun   bool
+	Response *agentv1.PruneResponse
+}
+
+const pruneSummary = `
+{{- range $removed := .Response.Removed }}
+{{ if $.DryRun }}WOULD REMOVE{{ else }}REMOVED{{ end }}: {{ $removed.Tag }} ({{ bytes $removed.Size }}, {{ $removed.Reason }})
+{{- end }}
+RECLAIMED:             {{ bytes .Response.Reclaimed }}{{ if .DryRun }} (dry run){{ end }}
+BLOCK STORE RECLAIMED: {{ bytes .Response.StoreReclaimed }}
+DISK USAGE:            {{ bytes .Response.DiskUsage }}
+`
@@ -5,13 +5,17 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
+	"sort"
+	"text/tabwriter"
 
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
 
 	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
 	"github.com/mjpitz/aetherfs/internal/agent"
+	"github.com/mjpitz/aetherfs/internal/output"
 	"github.com/mjpitz/aetherfs/internal/storage/local"
 	"github.com/mjpitz/myago/flagset"
 	"github.com/mjpitz/myago/zaputil"
@@ -19,6 +23,12 @@ import (
 
 // PullConfig encapsulates all the configuration required to pull datasets from AetherFS.
 type PullConfig struct {
+	ConfigFile string           `json:"config_file" alias:"c" usage:"path to a pull file declaring the datasets to pull"`
+	Include    *cli.StringSlice `json:"include"               usage:"gitignore-style pattern of paths to download (repeatable)"`
+	Exclude    *cli.StringSlice `json:"exclude"               usage:"gitignore-style pattern of paths to skip (repeatable)"`
+	Layout     string           `json:"layout"                usage:"how files are written to disk (copy or hardlink)" default:"copy"`
+	Peers      *cli.StringSlice `json:"peer"                  usage:"address of an agent to download blocks from before the hub (repeatable)"`
+	Tracker    string           `json:"tracker"               usage:"the hub used to find agents to download blocks from before the hub"`
 }
 
 // Pull returns a command that downloads datasets from upstream servers
@@ -31,27 +41,58 @@ func Pull() *cli.Command {
 		UsageText: flagset.ExampleString(
 			"aetherfs pull [options] <path> [dataset...]",
 			"aetherfs pull /var/datasets maxmind:v1 private.company.io/maxmind:v2",
+			"aetherfs pull --include 'models/prod/*.onnx' /var/datasets models:v1",
 			"aetherfs pull -c path/to/application.afs.yaml /var/datasets",
+			"aetherfs pull --layout hardlink /var/datasets maxmind:v1 maxmind:v2",
+			"aetherfs pull --tracker private.company.io /var/datasets private.company.io/maxmind:v2",
 		),
 		Flags: flagset.Extract(cfg),
 		Action: func(ctx *cli.Context) error {
 			args := ctx.Args().Slice()
-			switch len(args) {
-			case 0:
+			switch {
+			case len(args) == 0:
 				return fmt.Errorf("missing required path")
-			case 1:
+			case len(args) == 1 && cfg.ConfigFile == "":
 				return fmt.Errorf("missing datasets")
 			}
 
+			var layout agentv1.Layout
+			switch cfg.Layout {
+			case "", "copy":
+				layout = agentv1.Layout_LAYOUT_COPY
+			case "hardlink":
+				// hardlinked files are shared between versions and must not be modified in place
+				layout = agentv1.Layout_LAYOUT_HARDLINK
+			default:
+				return fmt.Errorf("unrecognized layout: %s", cfg.Layout)
+			}
+
 			root, err := filepath.Abs(args[0])
 			if err != nil {
 				return err
 			}
 
+			configFile := ""
+			if cfg.ConfigFile != "" {
+				// validate up front so problems are reported without the surrounding rpc status
+				_, err = agent.LoadPullFile(ctx.Context, cfg.ConfigFile)
+				if err != nil {
+					return err
+				}
+
+				configFile, err = filepath.Abs(cfg.ConfigFile)
+				if err != nil {
+					return err
+				}
+			}
+
 			subscribeRequest := &agentv1.SubscribeRequest{
-				Sync: true,
-				Path: root,
-				Tags: args[1:],
+				Path:       root,
+				Tags:       args[1:],
+				ConfigFile: configFile,
+				Include:    cfg.Include.Value(),
+				Exclude:    cfg.Exclude.Value(),
+				Layout:     layout,
 			}
 
 			zaputil.Extract(ctx.Context).Debug("subscribe", zap.Stringer("request", subscribeRequest))
@@ -60,9 +101,43 @@ func Pull() *cli.Command {
 				Credentials: local.Extract(ctx.Context).Credentials(),
 			}
 
-			_, err = agentService.Subscribe(ctx.Context, subscribeRequest)
+			if len(cfg.Peers.Value()) > 0 || cfg.Tracker != "" {
+				agentService.Peers = &agent.Peers{
+					Static:  cfg.Peers.Value(),
+					Tracker: cfg.Tracker,
+				}
+			}
+
+			resp, err := agentService.Subscribe(ctx.Context, subscribeRequest)
+			if err != nil {
+				return err
+			}
+
+			job, err := waitForJob(ctx, agentService, resp.JobId)
+			if err != nil {
+				return err
+			}
+
+			result := job.GetSubscribe()
+			if result == nil {
+				result = &agentv1.SubscribeResponse{}
+			}
+
+			return output.Write(ctx.Context, ctx.App.Writer, result, func(w io.Writer) error {
+				tags := make([]string, 0, len(result.Paths))
+				for tag := range result.Paths {
+					tags = append(tags, tag)
+				}
+
+				sort.Strings(tags)
+
+				tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+				for _, tag := range tags {
+					fmt.Fprintf(tw, "%s\t%s\n", tag, result.Paths[tag])
+				}
 
-			return err
+				return tw.Flush()
+			})
 		},
 		HideHelpCommand: true,
 	}
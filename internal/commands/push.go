@@ -5,15 +5,22 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
 	"github.com/mjpitz/aetherfs/internal/agent"
 	"github.com/mjpitz/aetherfs/internal/blocks"
 	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/output"
 	"github.com/mjpitz/aetherfs/internal/storage/local"
 	"github.com/mjpitz/myago/flagset"
 	"github.com/mjpitz/myago/zaputil"
@@ -21,8 +28,15 @@ import (
 
 // PushConfig encapsulates all the configuration required to push datasets to AetherFS.
 type PushConfig struct {
-	BlockSize int32           `json:"block_size"     usage:"the maximum number of bytes per block in MiB"`
-	Tags      *dataset.TagSet `json:"tags" alias:"t" usage:"name and tag of the dataset being pushed"`
+	BlockSize      int32            `json:"block_size"              usage:"the maximum number of bytes per block in MiB"`
+	Tags           *dataset.TagSet  `json:"tags" alias:"t"          usage:"name and tag of the dataset being pushed"`
+	DryRun         bool             `json:"dry_run" alias:"dry-run" usage:"report how much data would be uploaded without pushing anything"`
+	Include        *cli.StringSlice `json:"include"                 usage:"gitignore-style pattern of paths to include (repeatable)"`
+	Exclude        *cli.StringSlice `json:"exclude"                 usage:"gitignore-style pattern of paths to exclude, applied after the root .afsignore (repeatable)"`
+	Watch          bool             `json:"watch"                   usage:"watch the path for changes and republish the dataset until interrupted"`
+	Debounce       time.Duration    `json:"debounce"                usage:"how long to wait for changes to settle before republishing" default:"5s"`
+	Resume         bool             `json:"resume"                  usage:"continue an interrupted push using the tags and settings it was started with"`
+	ExpectPrevious *cli.StringSlice `json:"expect_previous" alias:"expect-previous" usage:"TAG=DIGEST the tag must point at before it's updated, empty when it must not exist (repeatable)"`
 }
 
 // Push returns a command used to push datasets to upstream servers.
@@ -37,6 +51,11 @@ func Push() *cli.Command {
 		UsageText: flagset.ExampleString(
 			"aetherfs push [options] <path>",
 			"aetherfs push -t maxmind:v1 -t private.company.io/maxmind:v2 /tmp/maxmind",
+			"aetherfs push --dry-run -t maxmind:v1 /tmp/maxmind",
+			"aetherfs push --exclude '*.tmp' --include 'models/**' -t models:v1 /tmp/models",
+			"aetherfs push --watch -t etl:latest /var/etl/output",
+			"aetherfs push --resume --dry-run /tmp/maxmind",
+			"aetherfs push -t models:latest --expect-previous models:latest=sha256:<hex> /tmp/models",
 		),
 		Flags: flagset.Extract(cfg),
 		Action: func(ctx *cli.Context) error {
@@ -51,25 +70,119 @@ func Push() *cli.Command {
 			}
 
 			publishRequest := &agentv1.PublishRequest{
-				Sync:      true,
+				// watching never completes, so there's no progress to report
+				Sync:      cfg.Watch,
 				Path:      root,
 				BlockSize: cfg.BlockSize * int32(blocks.Mebibyte),
+				DryRun:    cfg.DryRun,
+				Include:   cfg.Include.Value(),
+				Exclude:   cfg.Exclude.Value(),
+				Watch:     cfg.Watch,
+				Debounce:  durationpb.New(cfg.Debounce),
+				Resume:    cfg.Resume,
+			}
+
+			if cfg.Resume && !ctx.IsSet("block_size") {
+				// use the block size the push was started with
+				publishRequest.BlockSize = 0
 			}
 
 			for _, tag := range cfg.Tags.Value() {
 				publishRequest.Tags = append(publishRequest.Tags, tag.String())
 			}
 
+			publishRequest.ExpectedPrevious, err = parseExpectedPrevious(cfg.ExpectPrevious.Value())
+			if err != nil {
+				return err
+			}
+
 			zaputil.Extract(ctx.Context).Debug("publish", zap.Stringer("request", publishRequest))
 
 			agentService := &agent.Service{
 				Credentials: local.Extract(ctx.Context).Credentials(),
+				Journals:    local.Extract(ctx.Context).Journals(),
+			}
+
+			resp, err := agentService.Publish(ctx.Context, publishRequest)
+			if err != nil {
+				return err
 			}
 
-			_, err = agentService.Publish(ctx.Context, publishRequest)
+			if !publishRequest.Sync {
+				job, err := waitForJob(ctx, agentService, resp.JobId)
+				if err != nil {
+					return err
+				}
+
+				resp = job.Publish
+			}
+
+			t, err := template.New("summary").Funcs(template.FuncMap{
+				"bytes":   func(v int64) string { return humanize.IBytes(uint64(v)) },
+				"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+			}).Parse(publishSummary)
+			if err != nil {
+				return err
+			}
 
-			return err
+			return output.Write(ctx.Context, ctx.App.Writer, resp, func(w io.Writer) error {
+				return t.Execute(w, &publishSummaryData{
+					DryRun:    cfg.DryRun,
+					Resume:    cfg.Resume,
+					Summaries: resp.Summaries,
+				})
+			})
 		},
 		HideHelpCommand: true,
 	}
 }
+
+// parseExpectedPrevious parses TAG=DIGEST pairs into the digests the tags are expected to point at.
+func parseExpectedPrevious(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	expected := make(map[string]string, len(values))
+	for _, value := range values {
+		idx := strings.Index(value, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid expected previous digest %q, expected TAG=DIGEST", value)
+		}
+
+		tag, err := dataset.ParseTag(value[:idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag %q: %v", value[:idx], err)
+		}
+
+		digest := value[idx+1:]
+		if digest != "" && !dataset.IsDigest(digest) {
+			return nil, fmt.Errorf("invalid digest %q", digest)
+		}
+
+		expected[tag.String()] = digest
+	}
+
+	return expected, nil
+}
+
+type publishSummaryData struct {
+	DryRun    bool
+	Resume    bool
+	Summaries map[string]*agentv1.PublishSummary
+}
+
+const publishSummary = `
+{{- range $host, $summary := .Summaries }}
+HOST:            {{ $host }}{{ if $.DryRun }} (dry run){{ end }}
+FILES:           {{ $summary.FileCount }}
+SIZE:            {{ bytes $summary.TotalSize }}
+BLOCKS:          {{ $summary.BlockCount }}
+NEW BLOCKS:      {{ $summary.NewBlocks }}
+EXISTING BLOCKS: {{ $summary.ExistingBlocks }}
+{{- if $.Resume }}
+RESUMED BLOCKS:  {{ $summary.ResumedBlocks }}
+{{- end }}
+UPLOAD SIZE:     {{ bytes $summary.UploadSize }}
+DEDUP RATIO:     {{ percent $summary.DedupRatio }}
+{{ end }}`
@@ -16,14 +16,18 @@ import (
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
 
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
 	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
 	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
 	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	trackerv1 "github.com/mjpitz/aetherfs/api/aetherfs/tracker/v1"
 	"github.com/mjpitz/aetherfs/internal/afs"
 	"github.com/mjpitz/aetherfs/internal/agent"
 	"github.com/mjpitz/aetherfs/internal/components"
+	"github.com/mjpitz/aetherfs/internal/mirror"
 	"github.com/mjpitz/aetherfs/internal/storage"
 	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/aetherfs/internal/tracker"
 	"github.com/mjpitz/aetherfs/internal/web"
 	"github.com/mjpitz/myago/config"
 	"github.com/mjpitz/myago/flagset"
@@ -38,7 +42,9 @@ type RunConfig struct {
 
 	NFS     components.NFSServerConfig `json:"nfs"`
 	Agent   agent.Config               `json:"agent"`
+	Mirror  mirror.Config              `json:"mirror"`
 	Storage storage.Config             `json:"storage"`
+	Tracker tracker.Config             `json:"tracker"`
 	Web     web.Config                 `json:"web"`
 }
 
@@ -101,12 +107,39 @@ func Run() (cmd *cli.Command) {
 				// setup api routes
 				_ = blockv1.RegisterBlockAPIHandler(ctx.Context, apiServer, serverConn)
 				_ = datasetv1.RegisterDatasetAPIHandler(ctx.Context, apiServer, serverConn)
+
+				if cfg.Storage.Admin.Enable {
+					log.Info("enabling", zap.Strings("components", []string{"admin"}))
+
+					adminv1.RegisterAdminAPIServer(grpcServer, stores.AdminAPIServer)
+					_ = adminv1.RegisterAdminAPIHandler(ctx.Context, apiServer, serverConn)
+				}
+			}
+
+			if cfg.Mirror.Interval > 0 {
+				switch {
+				case cfg.Mirror.Source == "":
+					return fmt.Errorf("mirror requires a source hub")
+				case stores == nil && cfg.Mirror.Destination == "":
+					// without storage, this process doesn't serve the block and dataset apis
+					return fmt.Errorf("mirror requires a destination hub when storage is disabled")
+				}
 			}
 
+			if cfg.Tracker.Enable {
+				log.Info("enabling", zap.Strings("components", []string{"tracker"}))
+
+				trackerv1.RegisterTrackerAPIServer(grpcServer, &tracker.Service{TTL: cfg.Tracker.TTL})
+				_ = trackerv1.RegisterTrackerAPIHandler(ctx.Context, apiServer, serverConn)
+			}
+
+			var agentService *agent.Service
 			if cfg.Agent.Enable {
 				log.Info("enabling", zap.Strings("components", []string{"agent"}))
-				agentService := &agent.Service{
-					Credentials: local.Extract(ctx.Context).Credentials(),
+				agentService = &agent.Service{
+					Credentials:   local.Extract(ctx.Context).Credentials(),
+					Journals:      local.Extract(ctx.Context).Journals(),
+					Subscriptions: local.Extract(ctx.Context).Subscriptions(),
 				}
 
 				if cfg.Agent.Shutdown.Enable {
@@ -114,6 +147,43 @@ func Run() (cmd *cli.Command) {
 					ctx.Context, agentService.InitiateShutdown = context.WithCancel(ctx.Context)
 				}
 
+				if prune := cfg.Agent.Prune; prune.Interval > 0 {
+					policy, err := prunePolicy(prune.KeepLast, prune.MaxDiskUsage, prune.MinFreeSpace)
+					if err != nil {
+						return err
+					}
+
+					log.Info("enabling pruning", zap.Strings("components", []string{"agent"}), zap.Duration("interval", prune.Interval))
+					go agentService.PruneEvery(ctx.Context, prune.Interval, policy)
+				}
+
+				if peers := cfg.Agent.Peers; peers.Address != "" || peers.Static != "" || peers.Tracker != "" {
+					agentService.Peers = &agent.Peers{
+						Address: peers.Address,
+						Tracker: peers.Tracker,
+					}
+
+					for _, peer := range strings.Split(peers.Static, ",") {
+						if peer = strings.TrimSpace(peer); peer != "" {
+							agentService.Peers.Static = append(agentService.Peers.Static, peer)
+						}
+					}
+
+					log.Info("enabling peers",
+						zap.Strings("components", []string{"agent"}),
+						zap.Strings("static", agentService.Peers.Static),
+						zap.String("tracker", peers.Tracker))
+
+					// hubs serve blocks from storage, so the agent only shares its own when running alongside one
+					if peers.Address != "" && stores == nil {
+						blockv1.RegisterBlockAPIServer(grpcServer, &agent.PeerServer{Service: agentService})
+					}
+
+					if peers.Tracker != "" && peers.Interval > 0 {
+						go agentService.AnnounceEvery(ctx.Context, peers.Interval)
+					}
+				}
+
 				agentv1.RegisterAgentAPIServer(grpcServer, agentService)
 				_ = agentv1.RegisterAgentAPIHandler(ctx.Context, apiServer, serverConn)
 			}
@@ -202,6 +272,67 @@ func Run() (cmd *cli.Command) {
 				return err
 			}
 
+			if agentService != nil {
+				// pick up any publishes that were interrupted when the agent was last stopped
+				err = agentService.ResumePublishing(ctx.Context)
+				if err != nil {
+					log.Error("failed to resume publishing", zap.Error(err))
+				}
+
+				// bring persisted subscriptions up to date with any changes made while the agent was stopped
+				err = agentService.ReconcileSubscriptions(ctx.Context)
+				if err != nil {
+					log.Error("failed to reconcile subscriptions", zap.Error(err))
+				}
+			}
+
+			if cfg.Mirror.Interval > 0 {
+				credentials := local.Extract(ctx.Context).Credentials()
+
+				sourceConn, err := components.GRPCClientFor(ctx.Context, credentials, cfg.Mirror.Source)
+				if err != nil {
+					return err
+				}
+				defer sourceConn.Close()
+
+				destination := mirror.Hub{
+					Name:       "local",
+					BlockAPI:   blockAPI,
+					DatasetAPI: datasetAPI,
+				}
+
+				if cfg.Mirror.Destination != "" {
+					destinationConn, err := components.GRPCClientFor(ctx.Context, credentials, cfg.Mirror.Destination)
+					if err != nil {
+						return err
+					}
+					defer destinationConn.Close()
+
+					destination = mirror.Hub{
+						Name:       cfg.Mirror.Destination,
+						BlockAPI:   blockv1.NewBlockAPIClient(destinationConn),
+						DatasetAPI: datasetv1.NewDatasetAPIClient(destinationConn),
+					}
+				}
+
+				log.Info("enabling",
+					zap.Strings("components", []string{"mirror"}),
+					zap.String("source", cfg.Mirror.Source),
+					zap.String("destination", destination.Name),
+					zap.Duration("interval", cfg.Mirror.Interval))
+
+				go (&mirror.Mirror{
+					Source: mirror.Hub{
+						Name:       cfg.Mirror.Source,
+						BlockAPI:   blockv1.NewBlockAPIClient(sourceConn),
+						DatasetAPI: datasetv1.NewDatasetAPIClient(sourceConn),
+					},
+					Destination: destination,
+					Datasets:    cfg.Mirror.Datasets,
+					Tags:        cfg.Mirror.Tags,
+				}).Run(ctx.Context, cfg.Mirror.Interval)
+			}
+
 			log.Info("running aetherfs")
 			<-ctx.Done()
 			return nil
@@ -30,14 +30,44 @@ const configSnapshot = `{
       "certificate_authority": ""
     }
   },
+  "basic": {
+    "password_file": "",
+    "token_file": ""
+  },
+  "nfs": {
+    "enable": false,
+    "port": 0
+  },
   "agent": {
     "enable": false,
     "shutdown": {
       "enable": false
+    },
+    "prune": {
+      "interval": 0,
+      "keep_last": 0,
+      "max_disk_usage": "",
+      "min_free_space": ""
+    },
+    "peers": {
+      "address": "",
+      "static": "",
+      "tracker": "",
+      "interval": 0
     }
   },
+  "mirror": {
+    "interval": 0,
+    "source": "",
+    "destination": "",
+    "datasets": "",
+    "tags": ""
+  },
   "storage": {
     "driver": "",
+    "admin": {
+      "enable": false
+    },
     "s3": {
       "endpoint": "",
       "tls": {
@@ -52,8 +82,37 @@ const configSnapshot = `{
       "secret_access_key": "",
       "region": "",
       "bucket": ""
+    },
+    "proxy": {
+      "target": "",
+      "tls": {
+        "enable": false,
+        "cert_path": "",
+        "ca_file": "",
+        "cert_file": "",
+        "key_file": "",
+        "reload_interval": 0
+      }
+    },
+    "composite": {
+      "replicas": 0,
+      "children": null
+    },
+    "tiered": {
+      "demote_after": 0,
+      "old_tags_after": 0,
+      "interval": 0,
+      "promote": false,
+      "tiers": null
+    },
+    "routed": {
+      "routes": null
     }
   },
+  "tracker": {
+    "enable": false,
+    "ttl": 0
+  },
   "web": {
     "enable": false
   }
@@ -0,0 +1,129 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/template"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+
+	agentv1 "github.com/mjpitz/aetherfs/api/aetherfs/agent/v1"
+	"github.com/mjpitz/aetherfs/internal/agent"
+	"github.com/mjpitz/aetherfs/internal/output"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+	"github.com/mjpitz/myago/flagset"
+	"github.com/mjpitz/myago/zaputil"
+)
+
+// VerifyConfig encapsulates all the configuration required to verify previously pulled datasets.
+type VerifyConfig struct {
+	Repair bool `json:"repair" usage:"re-download the blocks of modified and missing files"`
+}
+
+// Verify returns a command that checks previously pulled datasets against the snapshots they were pulled with.
+func Verify() *cli.Command {
+	cfg := &VerifyConfig{}
+
+	return &cli.Command{
+		Name:  "verify",
+		Usage: "Verifies pulled datasets haven't been modified",
+		UsageText: flagset.ExampleString(
+			"aetherfs verify [options] <path> [dataset...]",
+			"aetherfs verify /var/datasets",
+			"aetherfs verify /var/datasets maxmind:v1",
+			"aetherfs verify --repair /var/datasets maxmind:v1",
+		),
+		Flags: flagset.Extract(cfg),
+		Action: func(ctx *cli.Context) error {
+			args := ctx.Args().Slice()
+			if len(args) == 0 {
+				return fmt.Errorf("missing path argument")
+			}
+
+			root, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+
+			verifyRequest := &agentv1.VerifyRequest{
+				Path:   root,
+				Tags:   args[1:],
+				Repair: cfg.Repair,
+			}
+
+			zaputil.Extract(ctx.Context).Debug("verify", zap.Stringer("request", verifyRequest))
+
+			agentService := &agent.Service{
+				Credentials: local.Extract(ctx.Context).Credentials(),
+			}
+
+			resp, err := agentService.Verify(ctx.Context, verifyRequest)
+			if err != nil {
+				return err
+			}
+
+			t, err := template.New("summary").Funcs(template.FuncMap{
+				"bytes": func(v int64) string { return humanize.IBytes(uint64(v)) },
+			}).Parse(verifySummary)
+			if err != nil {
+				return err
+			}
+
+			err = output.Write(ctx.Context, ctx.App.Writer, resp, func(w io.Writer) error {
+				return t.Execute(w, resp)
+			})
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, ds := range resp.Datasets {
+				if len(ds.Modified)+len(ds.Missing) > len(ds.Repaired) {
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return cli.Exit(fmt.Sprintf("%d dataset(s) failed verification", failed), 1)
+			}
+
+			return nil
+		},
+		HideHelpCommand: true,
+	}
+}
+
+const verifySummary = `
+{{- range $ds := .Datasets }}
+{{ $ds.Tag }} ({{ $ds.Dir }})
+{{- if $ds.Skipped }}
+  SKIPPED: {{ $ds.Skipped }}
+{{- else if not (or $ds.Modified $ds.Missing $ds.Extra $ds.Unverified) }}
+  OK
+{{- end }}
+{{- range $ds.Modified }}
+  MODIFIED:   {{ . }}
+{{- end }}
+{{- range $ds.Missing }}
+  MISSING:    {{ . }}
+{{- end }}
+{{- range $ds.Extra }}
+  EXTRA:      {{ . }}
+{{- end }}
+{{- range $ds.Unverified }}
+  UNVERIFIED: {{ . }}
+{{- end }}
+{{- range $ds.Repaired }}
+  REPAIRED:   {{ . }}
+{{- end }}
+{{- if $ds.Repaired }}
+  REPAIRED BYTES: {{ bytes $ds.RepairedBytes }}
+{{- end }}
+{{- end }}
+`
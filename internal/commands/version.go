@@ -4,13 +4,23 @@
 package commands
 
 import (
+	"io"
 	"text/template"
 
 	"github.com/urfave/cli/v2"
+
+	"github.com/mjpitz/aetherfs/internal/output"
 )
 
 const versionTemplate = "{{ .Name }} {{ .Version }} {{ range $key, $value := .Metadata }}{{ $key }}={{ $value }} {{ end }}\n"
 
+// versionInfo is the structured output of the version command.
+type versionInfo struct {
+	Name     string                 `json:"name"`
+	Version  string                 `json:"version"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
 // Version returns a command that outputs version information for the application.
 func Version() *cli.Command {
 	return &cli.Command{
@@ -18,9 +28,17 @@ func Version() *cli.Command {
 		Usage:     "Print the binary version information",
 		UsageText: "aetherfs version",
 		Action: func(ctx *cli.Context) error {
-			return template.
-				Must(template.New("version").Parse(versionTemplate)).
-				Execute(ctx.App.Writer, ctx.App)
+			info := &versionInfo{
+				Name:     ctx.App.Name,
+				Version:  ctx.App.Version,
+				Metadata: ctx.App.Metadata,
+			}
+
+			return output.Write(ctx.Context, ctx.App.Writer, info, func(w io.Writer) error {
+				return template.
+					Must(template.New("version").Parse(versionTemplate)).
+					Execute(w, ctx.App)
+			})
 		},
 		HideHelpCommand: true,
 	}
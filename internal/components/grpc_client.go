@@ -5,8 +5,10 @@ package components
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/dgraph-io/badger/v3"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
@@ -41,6 +43,24 @@ type GRPCClientConfig struct {
 	Basic basicauth.ClientConfig `json:"basic"`
 }
 
+// GRPCClientFor connects to the target using the credentials stored for it, if any were configured using the auth
+// command.
+func GRPCClientFor(ctx context.Context, credentials *local.Store, target string) (*grpc.ClientConn, error) {
+	cfg := GRPCClientConfig{}
+
+	err := credentials.Get(ctx, target, &cfg)
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		cfg = GRPCClientConfig{
+			Target: target,
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	return GRPCClient(ctx, cfg)
+}
+
 func GRPCClient(ctx context.Context, cfg GRPCClientConfig) (*grpc.ClientConn, error) {
 	tokens := local.Extract(ctx).Tokens()
 
@@ -0,0 +1,205 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package dataset
+
+import (
+	"sort"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+)
+
+// BlockSizes returns the expected size of each block in the dataset.
+func BlockSizes(ds *datasetv1.Dataset) []int64 {
+	remaining := int64(0)
+	for _, file := range ds.Files {
+		remaining += file.Size
+	}
+
+	sizes := make([]int64, len(ds.Blocks))
+	for i := range ds.Blocks {
+		sizes[i] = remaining
+		if sizes[i] > int64(ds.BlockSize) {
+			sizes[i] = int64(ds.BlockSize)
+		}
+
+		remaining -= sizes[i]
+	}
+
+	return sizes
+}
+
+// layout locates a file within the stream of bytes the dataset's blocks are computed from.
+type layout struct {
+	ds     *datasetv1.Dataset
+	offset int64
+	size   int64
+	total  int64
+}
+
+// block returns the signature of the i-th block containing the file and whether the file is the only one in it.
+func (l layout) block(i int64) (string, bool) {
+	blockSize := int64(l.ds.BlockSize)
+	idx := l.offset/blockSize + i
+
+	start := idx * blockSize
+	end := start + blockSize
+	if end > l.total {
+		end = l.total
+	}
+
+	if idx >= int64(len(l.ds.Blocks)) {
+		return "", false
+	}
+
+	return l.ds.Blocks[idx], start >= l.offset && end <= l.offset+l.size
+}
+
+func layouts(ds *datasetv1.Dataset) map[string]layout {
+	total := int64(0)
+	for _, file := range ds.Files {
+		total += file.Size
+	}
+
+	offset := int64(0)
+	files := make(map[string]layout, len(ds.Files))
+
+	for _, file := range ds.Files {
+		files[file.Name] = layout{
+			ds:     ds,
+			offset: offset,
+			size:   file.Size,
+			total:  total,
+		}
+
+		offset += file.Size
+	}
+
+	return files
+}
+
+// compare determines whether two files of the same size have the same contents. When the blocks that differ are
+// shared with other files, or the files sit at different positions within their blocks, it can't be known from the
+// manifests alone, and ok is false.
+func compare(base, target layout) (same, ok bool) {
+	if base.size == 0 {
+		return true, true
+	}
+
+	baseBlockSize := int64(base.ds.BlockSize)
+	targetBlockSize := int64(target.ds.BlockSize)
+
+	if baseBlockSize <= 0 || baseBlockSize != targetBlockSize || base.offset%baseBlockSize != target.offset%targetBlockSize {
+		return false, false
+	}
+
+	count := (base.offset%baseBlockSize+base.size-1)/baseBlockSize + 1
+	same, ok = true, true
+
+	for i := int64(0); i < count; i++ {
+		baseSignature, baseOwned := base.block(i)
+		targetSignature, targetOwned := target.block(i)
+
+		switch {
+		case baseSignature == targetSignature:
+		case baseOwned && targetOwned:
+			// the file is the only thing in both blocks, so its contents must differ
+			return false, true
+		default:
+			same, ok = false, false
+		}
+	}
+
+	return same, ok
+}
+
+// uniqueBlocks returns the size of each distinct block in the dataset.
+func uniqueBlocks(ds *datasetv1.Dataset) map[string]int64 {
+	sizes := BlockSizes(ds)
+	unique := make(map[string]int64, len(ds.Blocks))
+
+	for i, signature := range ds.Blocks {
+		if _, ok := unique[signature]; !ok {
+			unique[signature] = sizes[i]
+		}
+	}
+
+	return unique
+}
+
+// Diff compares the target dataset against the base dataset. Files are reported in name order. The digests are left to
+// the caller.
+func Diff(base, target *datasetv1.Dataset) *datasetv1.DiffResponse {
+	resp := &datasetv1.DiffResponse{
+		Blocks: &datasetv1.BlockOverlap{},
+	}
+
+	baseFiles := layouts(base)
+	targetFiles := layouts(target)
+
+	for name, targetFile := range targetFiles {
+		baseFile, ok := baseFiles[name]
+
+		switch {
+		case !ok:
+			resp.Added = append(resp.Added, name)
+		case baseFile.size != targetFile.size:
+			resp.Resized = append(resp.Resized, &datasetv1.ResizedFile{
+				Name:       name,
+				BaseSize:   baseFile.size,
+				TargetSize: targetFile.size,
+			})
+		default:
+			same, ok := compare(baseFile, targetFile)
+
+			switch {
+			case !ok:
+				resp.Unverified = append(resp.Unverified, name)
+			case same:
+				resp.Unchanged++
+			default:
+				resp.Changed = append(resp.Changed, name)
+			}
+		}
+	}
+
+	for name := range baseFiles {
+		if _, ok := targetFiles[name]; !ok {
+			resp.Removed = append(resp.Removed, name)
+		}
+	}
+
+	sort.Strings(resp.Added)
+	sort.Strings(resp.Removed)
+	sort.Strings(resp.Changed)
+	sort.Strings(resp.Unverified)
+	sort.Slice(resp.Resized, func(i, j int) bool {
+		return resp.Resized[i].Name < resp.Resized[j].Name
+	})
+
+	baseBlocks := uniqueBlocks(base)
+	targetBlocks := uniqueBlocks(target)
+
+	for signature, size := range targetBlocks {
+		if _, ok := baseBlocks[signature]; ok {
+			resp.Blocks.Shared++
+			resp.Blocks.SharedBytes += size
+		} else {
+			resp.Blocks.Added++
+			resp.Blocks.AddedBytes += size
+		}
+	}
+
+	for signature, size := range baseBlocks {
+		if _, ok := targetBlocks[signature]; !ok {
+			resp.Blocks.Removed++
+			resp.Blocks.RemovedBytes += size
+		}
+	}
+
+	if total := resp.Blocks.SharedBytes + resp.Blocks.AddedBytes; total > 0 {
+		resp.Blocks.Ratio = float64(resp.Blocks.SharedBytes) / float64(total)
+	}
+
+	return resp
+}
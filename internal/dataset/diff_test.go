@@ -0,0 +1,131 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package dataset_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+)
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		name   string
+		base   *datasetv1.Dataset
+		target *datasetv1.Dataset
+		diff   *datasetv1.DiffResponse
+	}{
+		{
+			name: "identical",
+			base: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files:     []*datasetv1.File{{Name: "a", Size: 10}, {Name: "b", Size: 5}},
+				Blocks:    []string{"x", "y"},
+			},
+			target: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files:     []*datasetv1.File{{Name: "a", Size: 10}, {Name: "b", Size: 5}},
+				Blocks:    []string{"x", "y"},
+			},
+			diff: &datasetv1.DiffResponse{
+				Unchanged: 2,
+				Blocks:    &datasetv1.BlockOverlap{Shared: 2, SharedBytes: 15, Ratio: 1},
+			},
+		},
+		{
+			name: "files",
+			base: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files: []*datasetv1.File{
+					{Name: "changed", Size: 10},
+					{Name: "removed", Size: 10},
+					{Name: "resized", Size: 10},
+				},
+				Blocks: []string{"a", "b", "c"},
+			},
+			target: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files: []*datasetv1.File{
+					{Name: "changed", Size: 10},
+					{Name: "added", Size: 10},
+					{Name: "resized", Size: 20},
+				},
+				Blocks: []string{"d", "e", "c", "f"},
+			},
+			diff: &datasetv1.DiffResponse{
+				Added:   []string{"added"},
+				Removed: []string{"removed"},
+				Resized: []*datasetv1.ResizedFile{
+					{Name: "resized", BaseSize: 10, TargetSize: 20},
+				},
+				Changed: []string{"changed"},
+				Blocks: &datasetv1.BlockOverlap{
+					Shared:       1,
+					Added:        3,
+					Removed:      2,
+					SharedBytes:  10,
+					AddedBytes:   30,
+					RemovedBytes: 20,
+					Ratio:        0.25,
+				},
+			},
+		},
+		{
+			name: "shared blocks",
+			base: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files:     []*datasetv1.File{{Name: "a", Size: 5}, {Name: "b", Size: 5}},
+				Blocks:    []string{"x"},
+			},
+			target: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files:     []*datasetv1.File{{Name: "a", Size: 5}, {Name: "b", Size: 5}},
+				Blocks:    []string{"y"},
+			},
+			diff: &datasetv1.DiffResponse{
+				Unverified: []string{"a", "b"},
+				Blocks: &datasetv1.BlockOverlap{
+					Added:        1,
+					Removed:      1,
+					AddedBytes:   10,
+					RemovedBytes: 10,
+				},
+			},
+		},
+		{
+			name: "shifted",
+			base: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files:     []*datasetv1.File{{Name: "b", Size: 10}},
+				Blocks:    []string{"x"},
+			},
+			target: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files:     []*datasetv1.File{{Name: "a", Size: 5}, {Name: "b", Size: 10}},
+				Blocks:    []string{"y", "z"},
+			},
+			diff: &datasetv1.DiffResponse{
+				Added:      []string{"a"},
+				Unverified: []string{"b"},
+				Blocks: &datasetv1.BlockOverlap{
+					Added:        2,
+					Removed:      1,
+					AddedBytes:   15,
+					RemovedBytes: 10,
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			diff := dataset.Diff(testCase.base, testCase.target)
+
+			require.Equal(t, testCase.diff.String(), diff.String())
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package dataset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const digestPrefix = "sha256:"
+
+// Digest computes the content digest of a serialized dataset manifest. Digests are formatted as "sha256:<hex>".
+func Digest(manifest []byte) string {
+	sum := sha256.Sum256(manifest)
+
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// IsDigest returns true when the provided version is a well-formed digest rather than a tag.
+func IsDigest(version string) bool {
+	if !strings.HasPrefix(version, digestPrefix) {
+		return false
+	}
+
+	value := strings.TrimPrefix(version, digestPrefix)
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(value)
+	return err == nil && strings.ToLower(value) == value
+}
@@ -0,0 +1,92 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package dataset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reference points at something on a hub: every dataset, the tags of a dataset, or the files under a path within a
+// tagged dataset. References are formatted as [host/][dataset[:tag][/path]].
+type Reference struct {
+	Tag
+
+	// Path is the slash-separated path of a file or directory within the dataset. Empty refers to the root.
+	Path string
+}
+
+// hostname returns the host and port of the segment when it looks like an address. Hosts contain a dot or are
+// localhost, and may have a numeric port.
+func hostname(segment string) (host, port string, ok bool) {
+	host = segment
+
+	if idx := strings.LastIndex(segment, ":"); idx >= 0 {
+		host, port = segment[:idx], segment[idx+1:]
+
+		if port == "" || strings.Trim(port, "0123456789") != "" {
+			return "", "", false
+		}
+	}
+
+	return host, port, host == "localhost" || strings.Contains(host, ".")
+}
+
+// isHost returns true when the leading segment of a reference names a host rather than a dataset. A host on its own
+// must either have a port or be followed by a slash, otherwise datasets whose names contain a dot would be mistaken
+// for hosts.
+func isHost(segments []string) bool {
+	_, port, ok := hostname(segments[0])
+
+	return ok && (port != "" || len(segments) > 1)
+}
+
+// ParseReference parses a reference. When no host is provided, DefaultHost is used. The version is left empty when no
+// tag is provided, and the path is left empty when it refers to the root of the dataset.
+func ParseReference(val string) (ref Reference, err error) {
+	ref.Host = DefaultHost
+
+	segments := strings.Split(val, "/")
+
+	if isHost(segments) {
+		ref.Host = segments[0]
+		segments = segments[1:]
+	}
+
+	if len(segments) == 0 || segments[0] == "" {
+		if len(segments) > 1 && strings.Join(segments[1:], "") != "" {
+			return ref, fmt.Errorf("missing dataset")
+		}
+
+		return ref, nil
+	}
+
+	name := segments[0]
+	segments = segments[1:]
+
+	if strings.HasPrefix(name, "@") {
+		if len(segments) == 0 || segments[0] == "" {
+			return ref, fmt.Errorf("missing dataset within scope %s", name)
+		}
+
+		name = name + "/" + segments[0]
+		segments = segments[1:]
+	}
+
+	if strings.Contains(name, ":") || strings.LastIndex(name, "@") > 0 {
+		tag, err := splitDatasetTag(name)
+		if err != nil {
+			return ref, err
+		}
+
+		ref.Dataset = tag.Dataset
+		ref.Version = tag.Version
+	} else {
+		ref.Dataset = name
+	}
+
+	ref.Path = strings.Trim(strings.Join(segments, "/"), "/")
+
+	return ref, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package dataset_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjpitz/aetherfs/internal/dataset"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := dataset.Digest([]byte("{}"))
+
+	testCases := []struct {
+		reference string
+		host      string
+		dataset   string
+		version   string
+		path      string
+		error     string
+	}{
+		{reference: "", host: "localhost:8080"},
+		{reference: "private.company.io/", host: "private.company.io"},
+		{reference: "localhost:9090", host: "localhost:9090"},
+		{reference: "maxmind", host: "localhost:8080", dataset: "maxmind"},
+		{reference: "maxmind.geo", host: "localhost:8080", dataset: "maxmind.geo"},
+		{reference: "maxmind:v1", host: "localhost:8080", dataset: "maxmind", version: "v1"},
+		{
+			reference: "private.company.io/maxmind:v1/geo/city.mmdb",
+			host:      "private.company.io",
+			dataset:   "maxmind",
+			version:   "v1",
+			path:      "geo/city.mmdb",
+		},
+		{
+			reference: "localhost:8080/@scope/maxmind:v1/geo/",
+			host:      "localhost:8080",
+			dataset:   "@scope/maxmind",
+			version:   "v1",
+			path:      "geo",
+		},
+		{
+			reference: "@scope/maxmind@" + digest + "/geo",
+			host:      "localhost:8080",
+			dataset:   "@scope/maxmind",
+			version:   digest,
+			path:      "geo",
+		},
+		{reference: "maxmind/geo", host: "localhost:8080", dataset: "maxmind", path: "geo"},
+		{reference: "@scope", error: "missing dataset within scope @scope"},
+		{reference: "private.company.io//geo", error: "missing dataset"},
+		{reference: "maxmind:v1:v2/geo", error: "too many parts"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.reference, func(t *testing.T) {
+			ref, err := dataset.ParseReference(testCase.reference)
+
+			if testCase.error != "" {
+				require.Error(t, err)
+				require.Equal(t, testCase.error, err.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.host, ref.Host)
+			require.Equal(t, testCase.dataset, ref.Dataset)
+			require.Equal(t, testCase.version, ref.Version)
+			require.Equal(t, testCase.path, ref.Path)
+		})
+	}
+}
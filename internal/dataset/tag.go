@@ -12,9 +12,27 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
-const tagSetPrefix = "json:"
+const (
+	tagSetPrefix = "json:"
+
+	// DefaultHost is the host used when a tag does not specify one.
+	DefaultHost = "localhost:8080"
+)
 
 func splitDatasetTag(dataset string) (tag Tag, err error) {
+	// datasets can be referenced by digest (name@sha256:<hex>). scopes also start with an @, so only consider the last
+	// one when it isn't the first character.
+	if idx := strings.LastIndex(dataset, "@"); idx > 0 {
+		tag.Dataset = dataset[:idx]
+		tag.Version = dataset[idx+1:]
+
+		if !IsDigest(tag.Version) {
+			err = fmt.Errorf("invalid digest")
+		}
+
+		return tag, err
+	}
+
 	parts := strings.Split(dataset, ":")
 
 	switch {
@@ -61,7 +79,7 @@ func (t *Tag) UnmarshalText(text []byte) (err error) {
 	case len(parts) <= 2:
 		tmp, err = splitDatasetTag(url)
 		if err == nil {
-			tmp.Host = "localhost:8080"
+			tmp.Host = DefaultHost
 		}
 	}
 
@@ -83,7 +101,10 @@ func (t *Tag) MarshalText() (text []byte, err error) {
 func (t *Tag) String() string {
 	str := t.Dataset
 
-	if len(t.Version) > 0 {
+	switch {
+	case IsDigest(t.Version):
+		str = str + "@" + t.Version
+	case len(t.Version) > 0:
 		str = str + ":" + t.Version
 	}
 
@@ -12,6 +12,8 @@ import (
 )
 
 func TestTag(t *testing.T) {
+	digest := dataset.Digest([]byte("{}"))
+
 	testCases := []struct {
 		url     string
 		host    string
@@ -61,6 +63,22 @@ func TestTag(t *testing.T) {
 			dataset: "dataset",
 			version: "latest",
 		},
+		{
+			url:     "custom.domain/@scope/dataset@" + digest,
+			host:    "custom.domain",
+			dataset: "@scope/dataset",
+			version: digest,
+		},
+		{
+			url:     "dataset@" + digest,
+			host:    "localhost:8080",
+			dataset: "dataset",
+			version: digest,
+		},
+		{
+			url:   "dataset@sha256:abc",
+			error: "invalid digest",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -76,6 +94,10 @@ func TestTag(t *testing.T) {
 			require.Equal(t, testCase.host, ref.Host)
 			require.Equal(t, testCase.dataset, ref.Dataset)
 			require.Equal(t, testCase.version, ref.Version)
+
+			roundTrip, err := dataset.ParseTag(ref.String())
+			require.NoError(t, err)
+			require.Equal(t, ref, roundTrip)
 		}
 	}
 }
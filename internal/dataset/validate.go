@@ -0,0 +1,125 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package dataset
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+)
+
+// signatureAlphabet contains the characters that can appear in a block signature.
+const signatureAlphabet = "abcdefghijklmnopqrstuvwxyz234567="
+
+// ValidationError lists the problems found with a dataset manifest.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid dataset: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidSignature returns true when the signature could have been produced by one of the supported algorithms.
+func ValidSignature(signature string) bool {
+	if strings.Trim(signature, signatureAlphabet) != "" {
+		return false
+	}
+
+	for _, algorithm := range blocks.Algorithms {
+		computed, _ := blocks.ComputeSignature(algorithm, nil)
+		if len(computed) == len(signature) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validFileName returns true when the name is a clean, relative path that stays within the dataset.
+func validFileName(name string) bool {
+	return name != "" &&
+		path.Clean(name) == name &&
+		!path.IsAbs(name) &&
+		name != "." &&
+		name != ".." &&
+		!strings.HasPrefix(name, "../") &&
+		!strings.Contains(name, "\\")
+}
+
+// Validate checks the structure of a dataset manifest. The files must have safe, unique names and the blocks must be
+// exactly what's needed to hold the files at the dataset's block size. Whether the blocks exist is left to the caller.
+func Validate(ds *datasetv1.Dataset) error {
+	if ds == nil {
+		return &ValidationError{Problems: []string{"missing dataset"}}
+	}
+
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case ds.BlockSize <= 0:
+		problem("block size must be positive")
+	case ds.BlockSize > int32(blocks.MaxBlockSize):
+		problem("block size %d exceeds the maximum of %d", ds.BlockSize, blocks.MaxBlockSize)
+	}
+
+	names := make(map[string]bool, len(ds.Files))
+	dirs := make(map[string]bool)
+	totalSize := int64(0)
+
+	for _, file := range ds.Files {
+		switch {
+		case !validFileName(file.Name):
+			problem("invalid file name %q", file.Name)
+			continue
+		case names[file.Name]:
+			problem("duplicate file %s", file.Name)
+			continue
+		case file.Size < 0:
+			problem("file %s has a negative size", file.Name)
+			continue
+		}
+
+		names[file.Name] = true
+		totalSize += file.Size
+
+		for dir := path.Dir(file.Name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	for _, file := range ds.Files {
+		if names[file.Name] && dirs[file.Name] {
+			problem("file %s is also a directory", file.Name)
+		}
+	}
+
+	for i, signature := range ds.Blocks {
+		if !ValidSignature(signature) {
+			problem("block %d has an invalid signature %q", i, signature)
+		}
+	}
+
+	if ds.BlockSize > 0 {
+		blockSize := int64(ds.BlockSize)
+		expected := (totalSize + blockSize - 1) / blockSize
+
+		if int64(len(ds.Blocks)) != expected {
+			problem("files totaling %d bytes require %d blocks of %d bytes, found %d",
+				totalSize, expected, blockSize, len(ds.Blocks))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
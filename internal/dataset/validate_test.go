@@ -0,0 +1,126 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package dataset_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+)
+
+func TestValidate(t *testing.T) {
+	signature, err := blocks.ComputeSignature("sha256", []byte("block"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		dataset *datasetv1.Dataset
+		error   string
+	}{
+		{
+			name: "valid",
+			dataset: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files: []*datasetv1.File{
+					{Name: "a.txt", Size: 5},
+					{Name: "dir/b.txt", Size: 10},
+					{Name: "empty", Size: 0},
+				},
+				Blocks: []string{signature, signature},
+			},
+		},
+		{
+			name:    "empty",
+			dataset: &datasetv1.Dataset{BlockSize: 10},
+		},
+		{
+			name:    "missing",
+			dataset: nil,
+			error:   "invalid dataset: missing dataset",
+		},
+		{
+			name: "block size",
+			dataset: &datasetv1.Dataset{
+				BlockSize: 0,
+			},
+			error: "invalid dataset: block size must be positive",
+		},
+		{
+			name: "absurd block size",
+			dataset: &datasetv1.Dataset{
+				BlockSize: int32(blocks.MaxBlockSize) + 1,
+			},
+			error: "invalid dataset: block size 1073741825 exceeds the maximum of 1073741824",
+		},
+		{
+			name: "unsafe names",
+			dataset: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files: []*datasetv1.File{
+					{Name: "../escape"},
+					{Name: "/absolute"},
+					{Name: "dir/../../escape"},
+					{Name: "./a"},
+					{Name: ""},
+				},
+			},
+			error: `invalid dataset: invalid file name "../escape"; invalid file name "/absolute"; ` +
+				`invalid file name "dir/../../escape"; invalid file name "./a"; invalid file name ""`,
+		},
+		{
+			name: "duplicate names",
+			dataset: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files: []*datasetv1.File{
+					{Name: "a", Size: 1},
+					{Name: "a", Size: 1},
+					{Name: "dir", Size: 1},
+					{Name: "dir/b", Size: 1},
+				},
+				Blocks: []string{signature},
+			},
+			error: "invalid dataset: duplicate file a; file dir is also a directory",
+		},
+		{
+			name: "size arithmetic",
+			dataset: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files: []*datasetv1.File{
+					{Name: "a", Size: 25},
+					{Name: "b", Size: -1},
+				},
+				Blocks: []string{signature, signature},
+			},
+			error: "invalid dataset: file b has a negative size; files totaling 25 bytes require 3 blocks of 10 bytes, found 2",
+		},
+		{
+			name: "invalid signatures",
+			dataset: &datasetv1.Dataset{
+				BlockSize: 10,
+				Files: []*datasetv1.File{
+					{Name: "a", Size: 20},
+				},
+				Blocks: []string{"", "../../etc"},
+			},
+			error: `invalid dataset: block 0 has an invalid signature ""; block 1 has an invalid signature "../../etc"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := dataset.Validate(testCase.dataset)
+
+			if testCase.error != "" {
+				require.Error(t, err)
+				require.Equal(t, testCase.error, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mjpitz/myago"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+)
+
+// maxReportedBlocks bounds the number of missing blocks listed when a publish is rejected.
+const maxReportedBlocks = 10
+
+const blocksVerifiedKey = myago.ContextKey("dataset.blocks_verified")
+
+// WithBlocksVerified marks a publish whose blocks have already been checked by the caller. Drivers that only hold some
+// of the blocks (e.g. the children of a composite driver) can't check them on their own.
+func WithBlocksVerified(ctx context.Context) context.Context {
+	return context.WithValue(ctx, blocksVerifiedKey, true)
+}
+
+// BlocksVerified returns true when the caller has already checked that the blocks of the dataset being published
+// exist.
+func BlocksVerified(ctx context.Context) bool {
+	verified, _ := ctx.Value(blocksVerifiedKey).(bool)
+	return verified
+}
+
+// MissingBlocks returns the blocks referenced by the dataset that the block api doesn't have.
+func MissingBlocks(ctx context.Context, blockAPI blockv1.BlockAPIServer, ds *datasetv1.Dataset) ([]string, error) {
+	seen := make(map[string]bool, len(ds.Blocks))
+	signatures := make([]string, 0, len(ds.Blocks))
+
+	for _, signature := range ds.Blocks {
+		if !seen[signature] {
+			seen[signature] = true
+			signatures = append(signatures, signature)
+		}
+	}
+
+	var missing []string
+	for start := 0; start < len(signatures); start += blocks.LookupBatchSize {
+		end := start + blocks.LookupBatchSize
+		if end > len(signatures) {
+			end = len(signatures)
+		}
+
+		resp, err := blockAPI.LookupBatch(ctx, &blockv1.LookupBatchRequest{
+			Signatures: signatures[start:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		missing = append(missing, resp.Missing...)
+	}
+
+	return missing, nil
+}
+
+// MissingBlocksError rejects a publish that references blocks that haven't been uploaded.
+func MissingBlocksError(missing []string) error {
+	reported := missing
+	if len(reported) > maxReportedBlocks {
+		reported = reported[:maxReportedBlocks]
+	}
+
+	msg := strings.Join(reported, ", ")
+	if len(missing) > len(reported) {
+		msg += fmt.Sprintf(" (and %d more)", len(missing)-len(reported))
+	}
+
+	return status.Errorf(codes.FailedPrecondition, "dataset references blocks that have not been uploaded: %s", msg)
+}
@@ -0,0 +1,132 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package filter
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// IgnoreFile is the name of the file used to exclude paths from a dataset. It follows the same format as a .gitignore,
+// but only the file at the root of the dataset is read. The file itself is excluded from the dataset.
+const IgnoreFile = ".afsignore"
+
+// Filter selects which paths within a dataset are included. Exclusions follow gitignore semantics where the last
+// matching pattern wins and patterns prefixed with "!" re-include a previously excluded path. When include patterns are
+// provided, a path must also match one of them (either directly or through one of its parent directories).
+type Filter struct {
+	include []*pattern
+	exclude []*pattern
+}
+
+// New compiles the provided include and exclude patterns into a Filter.
+func New(include, exclude []string) (*Filter, error) {
+	f := &Filter{}
+
+	for _, raw := range include {
+		p, err := compile(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		f.include = append(f.include, p)
+	}
+
+	return f, f.Exclude(exclude...)
+}
+
+// Exclude appends additional exclusion patterns to the filter. Patterns added later take precedence.
+func (f *Filter) Exclude(patterns ...string) error {
+	for _, raw := range patterns {
+		p, err := compile(raw)
+		if err != nil {
+			return err
+		}
+
+		f.exclude = append(f.exclude, p)
+	}
+
+	return nil
+}
+
+// Empty returns true when the filter selects every path.
+func (f *Filter) Empty() bool {
+	return f == nil || (len(f.include) == 0 && len(f.exclude) == 0)
+}
+
+func (f *Filter) excludes(name string, isDir bool) bool {
+	excluded := false
+	for _, p := range f.exclude {
+		if p.matches(name, isDir) {
+			excluded = !p.negate
+		}
+	}
+
+	return excluded
+}
+
+// Excluded reports whether the provided slash separated path has been excluded, either directly or by one of its
+// parent directories.
+func (f *Filter) Excluded(name string, isDir bool) bool {
+	if f == nil {
+		return false
+	}
+
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		if f.excludes(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return f.excludes(name, isDir)
+}
+
+func (f *Filter) included(name string) bool {
+	if len(f.include) == 0 {
+		return true
+	}
+
+	parts := strings.Split(name, "/")
+	for i := 1; i <= len(parts); i++ {
+		prefix := strings.Join(parts[:i], "/")
+		isDir := i < len(parts)
+
+		for _, p := range f.include {
+			if p.matches(prefix, isDir) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Match reports whether the file at the provided slash separated path is selected by the filter.
+func (f *Filter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+
+	return f.included(name) && !f.Excluded(name, false)
+}
+
+// ParseIgnore reads patterns from an ignore file, skipping blank lines and comments.
+func ParseIgnore(r io.Reader) ([]string, error) {
+	var patterns []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	return patterns, scanner.Err()
+}
@@ -0,0 +1,101 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package filter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjpitz/aetherfs/internal/filter"
+)
+
+func TestFilter(t *testing.T) {
+	testCases := []struct {
+		name    string
+		include []string
+		exclude []string
+		match   []string
+		skip    []string
+	}{
+		{
+			name:  "empty",
+			match: []string{"a.txt", "dir/b.txt", ".git/config"},
+		},
+		{
+			name:    "unanchored",
+			exclude: []string{".git", "__pycache__/", "*.swp"},
+			match:   []string{"a.txt", "src/main.py", "git/config"},
+			skip:    []string{".git/config", "src/__pycache__/main.pyc", "src/.main.py.swp", "a.swp"},
+		},
+		{
+			name:    "anchored",
+			exclude: []string{"/build", "docs/*.md"},
+			match:   []string{"src/build/a.txt", "docs/nested/README.md", "README.md"},
+			skip:    []string{"build/a.txt", "docs/README.md"},
+		},
+		{
+			name:    "double star",
+			exclude: []string{"**/tmp/**", "logs/**/*.log"},
+			match:   []string{"tmp", "logs/a.txt"},
+			skip:    []string{"tmp/a", "a/b/tmp/c", "logs/a.log", "logs/a/b/c.log"},
+		},
+		{
+			name:    "negation",
+			exclude: []string{"*.csv", "!keep.csv"},
+			match:   []string{"keep.csv", "data/keep.csv", "a.txt"},
+			skip:    []string{"a.csv", "data/b.csv"},
+		},
+		{
+			name:    "root ignore file",
+			exclude: []string{"/" + filter.IgnoreFile},
+			match:   []string{"nested/" + filter.IgnoreFile, "a.txt"},
+			skip:    []string{filter.IgnoreFile},
+		},
+		{
+			name:    "include",
+			include: []string{"models/prod/*.onnx", "configs/"},
+			exclude: []string{"*.tmp.onnx"},
+			match:   []string{"models/prod/a.onnx", "configs/a.yaml", "configs/nested/b.yaml"},
+			skip:    []string{"models/prod/a.tmp.onnx", "models/dev/a.onnx", "models/prod/a.txt", "README.md"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.name)
+
+		f, err := filter.New(testCase.include, testCase.exclude)
+		require.NoError(t, err)
+
+		for _, name := range testCase.match {
+			require.True(t, f.Match(name), name)
+		}
+
+		for _, name := range testCase.skip {
+			require.False(t, f.Match(name), name)
+		}
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	_, err := filter.New([]string{"[a-"}, nil)
+	require.Error(t, err)
+
+	_, err = filter.New(nil, []string{"/"})
+	require.Error(t, err)
+}
+
+func TestParseIgnore(t *testing.T) {
+	patterns, err := filter.ParseIgnore(strings.NewReader(`
+# editor files
+*.swp
+
+.git/
+\#literal
+`))
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"*.swp", ".git/", `\#literal`}, patterns)
+}
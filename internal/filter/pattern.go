@@ -0,0 +1,101 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package filter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// pattern is a single compiled gitignore-style pattern.
+type pattern struct {
+	raw      string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	segments []string
+}
+
+func compile(raw string) (*pattern, error) {
+	p := &pattern{raw: raw}
+	value := raw
+
+	if strings.HasPrefix(value, "!") {
+		p.negate = true
+		value = value[1:]
+	} else if strings.HasPrefix(value, `\!`) || strings.HasPrefix(value, `\#`) {
+		value = value[1:]
+	}
+
+	if strings.HasSuffix(value, "/") {
+		p.dirOnly = true
+		value = strings.TrimSuffix(value, "/")
+	}
+
+	// a separator at the beginning or in the middle of the pattern anchors it to the root of the dataset. otherwise
+	// the pattern can match at any level.
+	p.anchored = strings.Contains(value, "/")
+	value = strings.TrimPrefix(value, "/")
+
+	if value == "" {
+		return nil, fmt.Errorf("invalid pattern: %q", raw)
+	}
+
+	p.segments = strings.Split(value, "/")
+	for _, segment := range p.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %q", raw)
+		}
+	}
+
+	return p, nil
+}
+
+// matches reports whether the pattern matches the provided slash separated path. Negation is not considered here.
+func (p *pattern) matches(name string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	parts := strings.Split(name, "/")
+	if !p.anchored {
+		return matchSegments(p.segments, parts[len(parts)-1:])
+	}
+
+	return matchSegments(p.segments, parts)
+}
+
+// matchSegments matches path segments against pattern segments where "**" matches zero or more segments. A trailing
+// "**" matches everything inside a directory, but not the directory itself.
+func matchSegments(patterns, parts []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			if len(patterns) == 1 {
+				return len(parts) > 0
+			}
+
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(patterns[1:], parts[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(patterns[0], parts[0]); !ok {
+			return false
+		}
+
+		patterns = patterns[1:]
+		parts = parts[1:]
+	}
+
+	return len(parts) == 0
+}
@@ -6,4 +6,5 @@ package headers
 const (
 	AetherFSBlockSignature = "X-AFS-Block-Signature"
 	AetherFSBlockSize      = "X-AFS-Block-Size"
+	AetherFSDataset        = "X-AFS-Dataset"
 )
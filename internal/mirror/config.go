@@ -0,0 +1,17 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package mirror
+
+import (
+	"time"
+)
+
+// Config defines the options available for continuously mirroring datasets from another hub.
+type Config struct {
+	Interval    time.Duration `json:"interval"    usage:"how often to mirror datasets from the source hub, disabled when zero"`
+	Source      string        `json:"source"      usage:"the hub to mirror datasets from"`
+	Destination string        `json:"destination" usage:"the hub to mirror datasets to, defaults to this process when it has storage configured"`
+	Datasets    string        `json:"datasets"    usage:"glob of dataset names to mirror (e.g. maxmind-*), all when empty"`
+	Tags        string        `json:"tags"        usage:"glob of tag versions to mirror (e.g. v1.*), all when empty"`
+}
@@ -0,0 +1,62 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package mirror
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var labels = []string{"source", "destination"}
+
+var (
+	tagsMirrored = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aetherfs",
+		Subsystem: "mirror",
+		Name:      "tags_total",
+		Help:      "The number of tags published to the destination hub.",
+	}, labels)
+
+	blocksMirrored = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aetherfs",
+		Subsystem: "mirror",
+		Name:      "blocks_total",
+		Help:      "The number of blocks copied to the destination hub.",
+	}, labels)
+
+	bytesMirrored = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aetherfs",
+		Subsystem: "mirror",
+		Name:      "bytes_total",
+		Help:      "The number of block bytes copied to the destination hub.",
+	}, labels)
+
+	failures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aetherfs",
+		Subsystem: "mirror",
+		Name:      "failures_total",
+		Help:      "The number of tags or listings that failed to mirror.",
+	}, labels)
+
+	tagsBehind = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "aetherfs",
+		Subsystem: "mirror",
+		Name:      "tags_behind",
+		Help:      "The number of tags that were known to still differ from the source hub at the end of the last sync.",
+	}, labels)
+
+	lastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "aetherfs",
+		Subsystem: "mirror",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "The unix time of the last sync that left the destination hub up to date.",
+	}, labels)
+
+	lag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "aetherfs",
+		Subsystem: "mirror",
+		Name:      "lag_seconds",
+		Help:      "The time since the destination hub was last up to date, as of the last sync.",
+	}, labels)
+)
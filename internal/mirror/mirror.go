@@ -0,0 +1,299 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/myago/clocks"
+)
+
+// Hub is one end of a mirror.
+type Hub struct {
+	Name       string
+	BlockAPI   blockv1.BlockAPIClient
+	DatasetAPI datasetv1.DatasetAPIClient
+}
+
+// Failure records a tag that could not be mirrored.
+type Failure struct {
+	Tag   string `json:"tag"`
+	Error string `json:"error"`
+}
+
+// Result summarizes a single pass over the source hub.
+type Result struct {
+	Mirrored []string  `json:"mirrored"`
+	UpToDate []string  `json:"up_to_date"`
+	Failed   []Failure `json:"failed"`
+	// Behind counts the failed tags that were known to differ from the source. Tags that failed before they could be
+	// compared aren't counted.
+	Behind int   `json:"behind"`
+	Blocks int64 `json:"blocks"`
+	Bytes  int64 `json:"bytes"`
+}
+
+// Mirror copies datasets from one hub to another. Only the blocks the destination is missing are copied, one block at
+// a time, and tags are published under the same names they have on the source.
+type Mirror struct {
+	Source      Hub
+	Destination Hub
+
+	// Datasets and Tags are globs that limit which dataset names and tag versions are mirrored. Empty globs match
+	// everything.
+	Datasets string
+	Tags     string
+
+	// upToDate is when the destination was last known to match the source.
+	upToDate time.Time
+}
+
+func match(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+func (m *Mirror) labels() prometheus.Labels {
+	return prometheus.Labels{"source": m.Source.Name, "destination": m.Destination.Name}
+}
+
+// tags lists the tags on the source hub that should be mirrored. Digests aren't tags and are skipped.
+func (m *Mirror) tags(ctx context.Context) ([]*datasetv1.Tag, error) {
+	resp, err := m.Source.DatasetAPI.List(ctx, &datasetv1.ListRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list datasets: %w", err)
+	}
+
+	var tags []*datasetv1.Tag
+	for _, ds := range resp.Datasets {
+		if !match(m.Datasets, ds.Name) {
+			continue
+		}
+
+		tagsResp, err := m.Source.DatasetAPI.ListTags(ctx, &datasetv1.ListTagsRequest{
+			Name: ds.Name,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for %s: %w", ds.Name, err)
+		}
+
+		for _, tag := range tagsResp.Tags {
+			if dataset.IsDigest(tag.Version) || !match(m.Tags, tag.Version) {
+				continue
+			}
+
+			tags = append(tags, &datasetv1.Tag{Name: ds.Name, Version: tag.Version})
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Name != tags[j].Name {
+			return tags[i].Name < tags[j].Name
+		}
+
+		return tags[i].Version < tags[j].Version
+	})
+
+	return tags, nil
+}
+
+// mirror copies the tag to the destination. It returns whether the destination differed from the source, which is
+// false for errors that occur before the two could be compared.
+func (m *Mirror) mirror(ctx context.Context, tag *datasetv1.Tag, result *Result) (bool, error) {
+	logger := ctxzap.Extract(ctx)
+	ctx = afs.WithDataset(ctx, tag.Name)
+	labels := m.labels()
+
+	src, err := m.Source.DatasetAPI.Lookup(ctx, &datasetv1.LookupRequest{Tag: tag})
+	if err != nil {
+		return false, fmt.Errorf("failed to lookup on source: %w", err)
+	}
+
+	err = dataset.Validate(src.Dataset)
+	if err != nil {
+		return false, err
+	}
+
+	request := &datasetv1.PublishRequest{
+		Dataset: src.Dataset,
+		Tags:    []*datasetv1.Tag{tag},
+	}
+
+	dst, err := m.Destination.DatasetAPI.Lookup(ctx, &datasetv1.LookupRequest{Tag: tag})
+	switch {
+	case status.Code(err) == codes.NotFound:
+		request.ExpectedPrevious = map[string]string{tag.Name + ":" + tag.Version: ""}
+	case err != nil:
+		return false, fmt.Errorf("failed to lookup on destination: %w", err)
+	case src.Digest != "" && dst.Digest == src.Digest:
+		return false, nil
+	case dst.Digest != "":
+		// don't clobber a tag that was pushed to the destination while we were copying blocks
+		request.ExpectedPrevious = map[string]string{tag.Name + ":" + tag.Version: dst.Digest}
+	}
+
+	missing, err := afs.MissingBlocks(ctx, m.Destination.BlockAPI, src.Dataset.Blocks)
+	if err != nil {
+		return true, fmt.Errorf("failed to lookup blocks on destination: %w", err)
+	}
+
+	sizes := dataset.BlockSizes(src.Dataset)
+	buffer := make([]byte, src.Dataset.BlockSize)
+
+	for i, signature := range src.Dataset.Blocks {
+		if !missing[signature] {
+			continue
+		}
+
+		// blocks can repeat within a dataset
+		delete(missing, signature)
+
+		data := buffer[:sizes[i]]
+
+		n, err := afs.Download(ctx, m.Source.BlockAPI, &blockv1.DownloadRequest{Signature: signature}, data)
+		switch {
+		case err != nil:
+			return true, fmt.Errorf("failed to download block %s: %w", signature, err)
+		case n < len(data):
+			return true, fmt.Errorf("block %s is truncated on the source", signature)
+		}
+
+		err = blocks.VerifySignature(signature, data)
+		if err != nil {
+			return true, err
+		}
+
+		logger.Debug("copying block", zap.String("signature", signature))
+
+		err = afs.Upload(ctx, m.Destination.BlockAPI, signature, data)
+		if err != nil {
+			return true, fmt.Errorf("failed to upload block %s: %w", signature, err)
+		}
+
+		result.Blocks++
+		result.Bytes += int64(len(data))
+		blocksMirrored.With(labels).Inc()
+		bytesMirrored.With(labels).Add(float64(len(data)))
+	}
+
+	_, err = m.Destination.DatasetAPI.Publish(ctx, request)
+	if err != nil {
+		return true, fmt.Errorf("failed to publish: %w", err)
+	}
+
+	tagsMirrored.With(labels).Inc()
+	return true, nil
+}
+
+// Sync makes a single pass over the source hub, copying every matching tag that differs on the destination. A tag that
+// fails to mirror doesn't stop the others from being copied and is reported in the result instead.
+func (m *Mirror) Sync(ctx context.Context) (*Result, error) {
+	logger := ctxzap.Extract(ctx)
+	clock := clocks.Extract(ctx)
+	labels := m.labels()
+	start := clock.Now()
+
+	if m.upToDate.IsZero() {
+		m.upToDate = start
+	}
+
+	defer func() {
+		lag.With(labels).Set(clock.Now().Sub(m.upToDate).Seconds())
+	}()
+
+	for _, pattern := range []string{m.Datasets, m.Tags} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	tags, err := m.tags(ctx)
+	if err != nil {
+		failures.With(labels).Inc()
+		return nil, err
+	}
+
+	result := &Result{
+		Mirrored: []string{},
+		UpToDate: []string{},
+		Failed:   []Failure{},
+	}
+
+	for _, tag := range tags {
+		name := (&dataset.Tag{Dataset: tag.Name, Version: tag.Version}).String()
+
+		differed, err := m.mirror(ctx, tag, result)
+		switch {
+		case err != nil:
+			logger.Error("failed to mirror tag", zap.String("tag", name), zap.Error(err))
+			failures.With(labels).Inc()
+			result.Failed = append(result.Failed, Failure{Tag: name, Error: err.Error()})
+
+			if differed {
+				result.Behind++
+			}
+		case differed:
+			logger.Info("mirrored tag", zap.String("tag", name))
+			result.Mirrored = append(result.Mirrored, name)
+		default:
+			result.UpToDate = append(result.UpToDate, name)
+		}
+	}
+
+	tagsBehind.With(labels).Set(float64(result.Behind))
+
+	if len(result.Failed) == 0 {
+		m.upToDate = start
+		lastSuccess.With(labels).Set(float64(start.Unix()))
+	}
+
+	return result, nil
+}
+
+// Run syncs immediately and then on every interval until the context is cancelled.
+func (m *Mirror) Run(ctx context.Context, interval time.Duration) {
+	logger := ctxzap.Extract(ctx)
+	clock := clocks.Extract(ctx)
+
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := m.Sync(ctx)
+		if err != nil {
+			logger.Error("failed to mirror datasets", zap.Error(err))
+		} else {
+			logger.Info("mirrored datasets",
+				zap.Int("mirrored", len(result.Mirrored)),
+				zap.Int("failed", len(result.Failed)),
+				zap.Int64("blocks", result.Blocks),
+				zap.Int64("bytes", result.Bytes))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+	}
+}
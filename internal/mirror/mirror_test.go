@@ -0,0 +1,344 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package mirror_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/headers"
+	"github.com/mjpitz/aetherfs/internal/mirror"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+)
+
+// blockServer keeps blocks in memory, recording the ones that are uploaded.
+type blockServer struct {
+	blockv1.UnimplementedBlockAPIServer
+
+	mu       sync.Mutex
+	blocks   map[string][]byte
+	uploaded []string
+}
+
+func (b *blockServer) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	resp := &blockv1.LookupBatchResponse{}
+	for _, signature := range request.Signatures {
+		if _, ok := b.blocks[signature]; !ok {
+			resp.Missing = append(resp.Missing, signature)
+		}
+	}
+
+	return resp, nil
+}
+
+func (b *blockServer) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
+	b.mu.Lock()
+	data, ok := b.blocks[request.Signature]
+	b.mu.Unlock()
+
+	if !ok {
+		return status.Error(codes.NotFound, "block not found")
+	}
+
+	data = data[request.Offset:]
+	if request.Size > 0 && request.Size < int64(len(data)) {
+		data = data[:request.Size]
+	}
+
+	return call.Send(&blockv1.DownloadResponse{Part: data})
+}
+
+func (b *blockServer) Upload(call blockv1.BlockAPI_UploadServer) error {
+	md, _ := metadata.FromIncomingContext(call.Context())
+	signature := md.Get(headers.AetherFSBlockSignature)[0]
+
+	data := &bytes.Buffer{}
+	for {
+		req, err := call.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		data.Write(req.Part)
+	}
+
+	b.mu.Lock()
+	b.blocks[signature] = data.Bytes()
+	b.uploaded = append(b.uploaded, signature)
+	b.mu.Unlock()
+
+	return call.SendAndClose(&blockv1.UploadResponse{Signature: signature})
+}
+
+// datasetClient keeps manifests in memory, keyed by name:version.
+type datasetClient struct {
+	datasetv1.DatasetAPIClient
+
+	mu     sync.Mutex
+	tags   map[string]*datasetv1.Dataset
+	broken map[string]bool
+
+	// published is called before a publish is applied so tests can move tags in the meantime
+	published func()
+}
+
+func digest(ds *datasetv1.Dataset) string {
+	manifest, _ := proto.MarshalOptions{Deterministic: true}.Marshal(ds)
+	return dataset.Digest(manifest)
+}
+
+func (d *datasetClient) List(ctx context.Context, in *datasetv1.ListRequest, opts ...grpc.CallOption) (*datasetv1.ListResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := make(map[string]bool)
+	for tag := range d.tags {
+		names[strings.SplitN(tag, ":", 2)[0]] = true
+	}
+
+	resp := &datasetv1.ListResponse{}
+	for name := range names {
+		resp.Datasets = append(resp.Datasets, &datasetv1.Tag{Name: name})
+	}
+
+	return resp, nil
+}
+
+func (d *datasetClient) ListTags(ctx context.Context, in *datasetv1.ListTagsRequest, opts ...grpc.CallOption) (*datasetv1.ListTagsResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	resp := &datasetv1.ListTagsResponse{}
+	for tag := range d.tags {
+		parts := strings.SplitN(tag, ":", 2)
+		if parts[0] == in.Name {
+			resp.Tags = append(resp.Tags, &datasetv1.Tag{Name: parts[0], Version: parts[1]})
+		}
+	}
+
+	return resp, nil
+}
+
+func (d *datasetClient) Lookup(ctx context.Context, in *datasetv1.LookupRequest, opts ...grpc.CallOption) (*datasetv1.LookupResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := in.Tag.Name + ":" + in.Tag.Version
+
+	ds, ok := d.tags[key]
+	switch {
+	case d.broken[key]:
+		return nil, status.Error(codes.Internal, "broken")
+	case !ok:
+		return nil, status.Error(codes.NotFound, "tag not found")
+	}
+
+	return &datasetv1.LookupResponse{Dataset: ds, Digest: digest(ds)}, nil
+}
+
+func (d *datasetClient) Publish(ctx context.Context, in *datasetv1.PublishRequest, opts ...grpc.CallOption) (*datasetv1.PublishResponse, error) {
+	if d.published != nil {
+		d.published()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for tag, expected := range in.ExpectedPrevious {
+		current := ""
+		if ds, ok := d.tags[tag]; ok {
+			current = digest(ds)
+		}
+
+		if current != expected {
+			return nil, status.Errorf(codes.FailedPrecondition, "%s has moved", tag)
+		}
+	}
+
+	for _, tag := range in.Tags {
+		d.tags[tag.Name+":"+tag.Version] = in.Dataset
+	}
+
+	return &datasetv1.PublishResponse{}, nil
+}
+
+// newDataset returns a single file dataset made up of the provided blocks.
+func newDataset(t *testing.T, parts ...string) (*datasetv1.Dataset, map[string][]byte) {
+	ds := &datasetv1.Dataset{BlockSize: 4}
+	data := make(map[string][]byte)
+	size := int64(0)
+
+	for _, part := range parts {
+		signature, err := blocks.ComputeSignature("sha256", []byte(part))
+		require.NoError(t, err)
+
+		ds.Blocks = append(ds.Blocks, signature)
+		data[signature] = []byte(part)
+		size += int64(len(part))
+	}
+
+	ds.Files = []*datasetv1.File{{Name: "data.bin", Size: size}}
+	return ds, data
+}
+
+func TestSync(t *testing.T) {
+	v1, v1Blocks := newDataset(t, "aaaa", "bbbb", "cc")
+	v2, v2Blocks := newDataset(t, "aaaa", "dddd")
+	v3, v3Blocks := newDataset(t, "eeee")
+
+	merge := func(sets ...map[string][]byte) map[string][]byte {
+		out := make(map[string][]byte)
+		for _, set := range sets {
+			for signature, data := range set {
+				out[signature] = data
+			}
+		}
+
+		return out
+	}
+
+	testCases := []struct {
+		name              string
+		source            map[string]*datasetv1.Dataset
+		sourceBlocks      map[string][]byte
+		broken            []string
+		destination       map[string]*datasetv1.Dataset
+		destinationBlocks map[string][]byte
+		moved             *datasetv1.Dataset
+		mirrored          []string
+		upToDate          []string
+		failed            []string
+		behind            int
+		copied            []string
+		expected          map[string]*datasetv1.Dataset
+	}{
+		{
+			name:              "up to date",
+			source:            map[string]*datasetv1.Dataset{"ds:v1": v1},
+			sourceBlocks:      v1Blocks,
+			destination:       map[string]*datasetv1.Dataset{"ds:v1": v1},
+			destinationBlocks: v1Blocks,
+			upToDate:          []string{"ds:v1"},
+			expected:          map[string]*datasetv1.Dataset{"ds:v1": v1},
+		},
+		{
+			name:              "missing blocks only",
+			source:            map[string]*datasetv1.Dataset{"ds:v1": v1, "ds:v2": v2},
+			sourceBlocks:      merge(v1Blocks, v2Blocks),
+			destination:       map[string]*datasetv1.Dataset{"ds:v2": v2},
+			destinationBlocks: v2Blocks,
+			mirrored:          []string{"ds:v1"},
+			upToDate:          []string{"ds:v2"},
+			copied:            v1.Blocks[1:],
+			expected:          map[string]*datasetv1.Dataset{"ds:v1": v1, "ds:v2": v2},
+		},
+		{
+			name:              "moved while copying",
+			source:            map[string]*datasetv1.Dataset{"ds:v1": v1},
+			sourceBlocks:      v1Blocks,
+			destination:       map[string]*datasetv1.Dataset{"ds:v1": v2},
+			destinationBlocks: merge(v2Blocks, v3Blocks),
+			moved:             v3,
+			failed:            []string{"ds:v1"},
+			behind:            1,
+			copied:            v1.Blocks[1:],
+			expected:          map[string]*datasetv1.Dataset{"ds:v1": v3},
+		},
+		{
+			name:   "failures",
+			source: map[string]*datasetv1.Dataset{"ds:v1": v1, "ds:v2": v2, "ds:v3": v3},
+			// the last block of v1 was lost on the source
+			sourceBlocks: merge(v2Blocks, v3Blocks, map[string][]byte{v1.Blocks[1]: v1Blocks[v1.Blocks[1]]}),
+			broken:       []string{"ds:v3"},
+			destination:  map[string]*datasetv1.Dataset{},
+			mirrored:     []string{"ds:v2"},
+			failed:       []string{"ds:v1", "ds:v3"},
+			behind:       1,
+			copied:       []string{v1.Blocks[0], v1.Blocks[1], v2.Blocks[1]},
+			expected:     map[string]*datasetv1.Dataset{"ds:v2": v2},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			sourceBlocks, err := inprocess.BlockAPI(ctx, &blockServer{blocks: testCase.sourceBlocks})
+			require.NoError(t, err)
+
+			broken := make(map[string]bool)
+			for _, tag := range testCase.broken {
+				broken[tag] = true
+			}
+
+			destinationBlocks := &blockServer{blocks: merge(testCase.destinationBlocks)}
+			destinationBlockAPI, err := inprocess.BlockAPI(ctx, destinationBlocks)
+			require.NoError(t, err)
+
+			destination := &datasetClient{tags: testCase.destination}
+			if testCase.moved != nil {
+				destination.published = func() {
+					destination.mu.Lock()
+					defer destination.mu.Unlock()
+
+					destination.tags["ds:v1"] = testCase.moved
+				}
+			}
+
+			m := &mirror.Mirror{
+				Source: mirror.Hub{
+					Name:       "source",
+					BlockAPI:   sourceBlocks,
+					DatasetAPI: &datasetClient{tags: testCase.source, broken: broken},
+				},
+				Destination: mirror.Hub{
+					Name:       "destination",
+					BlockAPI:   destinationBlockAPI,
+					DatasetAPI: destination,
+				},
+			}
+
+			result, err := m.Sync(ctx)
+			require.NoError(t, err)
+
+			failed := make([]string, 0, len(result.Failed))
+			for _, failure := range result.Failed {
+				failed = append(failed, failure.Tag)
+			}
+
+			require.ElementsMatch(t, testCase.mirrored, result.Mirrored)
+			require.ElementsMatch(t, testCase.upToDate, result.UpToDate)
+			require.ElementsMatch(t, testCase.failed, failed)
+			require.Equal(t, testCase.behind, result.Behind)
+			require.Equal(t, int64(len(testCase.copied)), result.Blocks)
+			require.ElementsMatch(t, testCase.copied, destinationBlocks.uploaded)
+
+			require.Len(t, destination.tags, len(testCase.expected))
+			for tag, ds := range testCase.expected {
+				require.Equal(t, digest(ds), digest(destination.tags[tag]), tag)
+			}
+		})
+	}
+}
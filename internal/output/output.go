@@ -0,0 +1,116 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+// Package output writes the results of commands in the format requested by the user. Text is meant for people, while
+// json and yaml are meant for scripts. Structured results use the field names declared by their protobuf messages (or
+// json tags), so they remain stable between releases. Protobuf messages follow the same json mapping as the REST API.
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjpitz/myago"
+)
+
+// Format identifies how the results of a command are written.
+type Format string
+
+const (
+	// Text renders results for people to read.
+	Text Format = "text"
+	// JSON renders results as indented json.
+	JSON Format = "json"
+	// YAML renders results as yaml.
+	YAML Format = "yaml"
+)
+
+const contextKey = myago.ContextKey("output.format")
+
+// ParseFormat validates the provided format. An empty value defaults to Text.
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(value); format {
+	case "":
+		return Text, nil
+	case Text, JSON, YAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unrecognized output format: %s", value)
+	}
+}
+
+// ToContext attaches the format to the context.
+func ToContext(ctx context.Context, format Format) context.Context {
+	return context.WithValue(ctx, contextKey, format)
+}
+
+// Extract returns the format on the context, defaulting to Text.
+func Extract(ctx context.Context) Format {
+	v := ctx.Value(contextKey)
+	if v == nil {
+		return Text
+	}
+
+	return v.(Format)
+}
+
+// marshalJSON encodes the value as compact json. Protobuf messages use their declared field names.
+func marshalJSON(value interface{}) ([]byte, error) {
+	if msg, ok := value.(proto.Message); ok {
+		return protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	}
+
+	return json.Marshal(value)
+}
+
+// Write writes the value to w in the format found on the context. Text is delegated to render, while json and yaml are
+// encoded from value.
+func Write(ctx context.Context, w io.Writer, value interface{}, render func(w io.Writer) error) error {
+	format := Extract(ctx)
+	if format == Text {
+		return render(w)
+	}
+
+	data, err := marshalJSON(value)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case JSON:
+		// protojson randomizes its whitespace, so the output is always re-indented
+		buf := &bytes.Buffer{}
+		if err := json.Indent(buf, data, "", "  "); err != nil {
+			return err
+		}
+
+		buf.WriteString("\n")
+		_, err = buf.WriteTo(w)
+		return err
+
+	case YAML:
+		// converting through json keeps the field names the same in both formats
+		var generic interface{}
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return err
+		}
+
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+
+		if err := enc.Encode(generic); err != nil {
+			return err
+		}
+
+		return enc.Close()
+	}
+
+	return fmt.Errorf("unrecognized output format: %s", format)
+}
@@ -0,0 +1,82 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package output_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/output"
+)
+
+func TestWrite(t *testing.T) {
+	type entry struct {
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+	}
+
+	testCases := []struct {
+		name   string
+		format string
+		value  interface{}
+		output string
+	}{
+		{
+			name:   "text",
+			format: "",
+			value:  &entry{Name: "a", Size: 1},
+			output: "text\n",
+		},
+		{
+			name:   "json",
+			format: "json",
+			value:  &entry{Name: "a", Size: 1},
+			output: "{\n  \"name\": \"a\",\n  \"size\": 1\n}\n",
+		},
+		{
+			name:   "yaml",
+			format: "yaml",
+			value:  &entry{Name: "a", Size: 1},
+			output: "name: a\nsize: 1\n",
+		},
+		{
+			name:   "proto json",
+			format: "json",
+			value:  &datasetv1.Tag{Name: "maxmind"},
+			output: "{\n  \"name\": \"maxmind\",\n  \"version\": \"\"\n}\n",
+		},
+		{
+			name:   "proto yaml",
+			format: "yaml",
+			value:  &datasetv1.ResizedFile{Name: "a", BaseSize: 1, TargetSize: 2},
+			output: "base_size: \"1\"\nname: a\ntarget_size: \"2\"\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			format, err := output.ParseFormat(testCase.format)
+			require.NoError(t, err)
+
+			ctx := output.ToContext(context.Background(), format)
+			buf := &bytes.Buffer{}
+
+			err = output.Write(ctx, buf, testCase.value, func(w io.Writer) error {
+				_, err := io.WriteString(w, "text\n")
+				return err
+			})
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.output, buf.String())
+		})
+	}
+
+	_, err := output.ParseFormat("xml")
+	require.Error(t, err)
+}
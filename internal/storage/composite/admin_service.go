@@ -0,0 +1,24 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package composite
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+)
+
+type adminService struct {
+	adminv1.UnsafeAdminAPIServer
+}
+
+// Fsck isn't supported since each child only holds some of the blocks and would report the rest as missing.
+func (a *adminService) Fsck(ctx context.Context, request *adminv1.FsckRequest) (*adminv1.FsckResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "fsck is not supported by the composite driver")
+}
+
+var _ adminv1.AdminAPIServer = &adminService{}
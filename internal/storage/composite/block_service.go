@@ -0,0 +1,367 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package composite
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/headers"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+)
+
+type blockService struct {
+	blockv1.UnsafeBlockAPIServer
+
+	// ctx outlives any single request and is used to re-replicate blocks in the background
+	ctx      context.Context
+	ring     *Ring
+	replicas int
+	children []blockv1.BlockAPIClient
+	health   *health
+
+	repairing sync.Map
+}
+
+// order returns every child in the order they should be read from. Healthy replicas for the block come first, followed
+// by the rest in case the block was written before the children changed. Children that recently failed are tried last.
+func (b *blockService) order(ctx context.Context, signature string) (children []int, owned map[int]bool) {
+	replicas := b.ring.Replicas(signature, b.replicas)
+
+	owned = make(map[int]bool, len(replicas))
+	for _, child := range replicas {
+		owned[child] = true
+	}
+
+	children = append(children, replicas...)
+	for child := range b.children {
+		if !owned[child] {
+			children = append(children, child)
+		}
+	}
+
+	return b.health.sort(ctx, children), owned
+}
+
+func (b *blockService) Lookup(ctx context.Context, request *blockv1.LookupRequest) (*blockv1.LookupResponse, error) {
+	children, _ := b.order(ctx, request.Signature)
+
+	notFound := false
+	for _, child := range children {
+		resp, err := b.children[child].Lookup(ctx, request)
+		b.health.observe(ctx, child, err)
+
+		switch {
+		case err == nil:
+			return resp, nil
+		case status.Code(err) == codes.NotFound:
+			notFound = true
+		default:
+			ctxzap.Extract(ctx).Warn("failed to lookup block",
+				zap.String("signature", request.Signature), zap.Int("child", child), zap.Error(err))
+		}
+	}
+
+	if !notFound {
+		return nil, status.Error(codes.Unavailable, "failed to lookup block")
+	}
+
+	return nil, status.Error(codes.NotFound, "not found")
+}
+
+func (b *blockService) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	logger := ctxzap.Extract(ctx)
+
+	found := make(map[string]bool, len(request.Signatures))
+
+	// check the replicas for each block first, only looking elsewhere for the blocks they're missing
+	assigned := make(map[int][]string)
+	for _, signature := range request.Signatures {
+		for _, child := range b.ring.Replicas(signature, b.replicas) {
+			assigned[child] = append(assigned[child], signature)
+		}
+	}
+
+	failures := 0
+	lookup := func(child int, signatures []string) {
+		resp, err := b.children[child].LookupBatch(ctx, &blockv1.LookupBatchRequest{Signatures: signatures})
+		b.health.observe(ctx, child, err)
+
+		if err != nil {
+			logger.Warn("failed to lookup blocks", zap.Int("child", child), zap.Error(err))
+			failures++
+			return
+		}
+
+		missing := make(map[string]bool, len(resp.Missing))
+		for _, signature := range resp.Missing {
+			missing[signature] = true
+		}
+
+		for _, signature := range signatures {
+			if !missing[signature] {
+				found[signature] = true
+			}
+		}
+	}
+
+	for child, signatures := range assigned {
+		lookup(child, signatures)
+	}
+
+	var remaining []string
+	for _, signature := range request.Signatures {
+		if !found[signature] {
+			remaining = append(remaining, signature)
+		}
+	}
+
+	if len(remaining) > 0 && len(b.children) > b.replicas {
+		for child := range b.children {
+			lookup(child, remaining)
+		}
+	}
+
+	if failures > 0 && len(found) == 0 {
+		return nil, status.Error(codes.Unavailable, "failed to lookup blocks")
+	}
+
+	resp := &blockv1.LookupBatchResponse{}
+	for _, signature := range request.Signatures {
+		if !found[signature] {
+			resp.Missing = append(resp.Missing, signature)
+		}
+	}
+
+	return resp, nil
+}
+
+// Download reads the block from any replica that has it. When a replica is missing the block, it's copied back to it
+// in the background. Replicas that fail part way through are resumed from another replica.
+func (b *blockService) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
+	logger := ctxzap.Extract(call.Context()).With(zap.String("signature", request.Signature))
+
+	children, owned := b.order(call.Context(), request.Signature)
+
+	var missed []int
+	var err error
+
+	sent := int64(0)
+	for _, child := range children {
+		next := &blockv1.DownloadRequest{
+			Signature: request.Signature,
+			Offset:    request.Offset + sent,
+		}
+
+		if request.Size > 0 {
+			next.Size = request.Size - sent
+		}
+
+		var n int64
+		n, err = inprocess.Forward(b.children[child], next, call)
+		sent += n
+
+		if call.Context().Err() != nil {
+			return err
+		}
+
+		b.health.observe(call.Context(), child, err)
+
+		switch {
+		case err == nil:
+			if len(missed) > 0 {
+				go b.repair(request.Signature, child, missed)
+			}
+
+			return nil
+		case status.Code(err) == codes.NotFound && n == 0:
+			if owned[child] {
+				missed = append(missed, child)
+			}
+		default:
+			logger.Warn("failed to download block", zap.Int("child", child), zap.Error(err))
+		}
+	}
+
+	if status.Code(err) == codes.NotFound {
+		return err
+	}
+
+	return status.Error(codes.Unavailable, "no replica could provide the block")
+}
+
+// repair copies the block from the source to the replicas that are missing it.
+func (b *blockService) repair(signature string, source int, replicas []int) {
+	if _, loaded := b.repairing.LoadOrStore(signature, true); loaded {
+		return
+	}
+	defer b.repairing.Delete(signature)
+
+	logger := ctxzap.Extract(b.ctx).With(zap.String("signature", signature))
+
+	stream, err := b.children[source].Download(b.ctx, &blockv1.DownloadRequest{Signature: signature})
+	if err != nil {
+		logger.Error("failed to read block for re-replication", zap.Error(err))
+		return
+	}
+
+	data := &bytes.Buffer{}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			logger.Error("failed to read block for re-replication", zap.Error(err))
+			return
+		}
+
+		data.Write(resp.Part)
+	}
+
+	for _, child := range replicas {
+		err = afs.Upload(b.ctx, b.children[child], signature, data.Bytes())
+		if err != nil {
+			logger.Error("failed to re-replicate block", zap.Int("child", child), zap.Error(err))
+			continue
+		}
+
+		logger.Info("re-replicated block", zap.Int("child", child))
+	}
+}
+
+// upload is an upload of a block to a single replica.
+type upload struct {
+	child  int
+	stream blockv1.BlockAPI_UploadClient
+	done   bool
+	err    error
+}
+
+// finish closes the upload, recording the result.
+func (u *upload) finish() {
+	if u.done {
+		return
+	}
+
+	u.done = true
+	_, u.err = u.stream.CloseAndRecv()
+	if u.err == io.EOF {
+		u.err = nil
+	}
+}
+
+// Upload writes the block to each of its replicas at once. The upload succeeds as long as one replica stores it,
+// replicas that failed are repaired the next time the block is read.
+func (b *blockService) Upload(call blockv1.BlockAPI_UploadServer) error {
+	ctx := call.Context()
+	logger := ctxzap.Extract(ctx)
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.New(nil)
+	}
+
+	signatures := md.Get(headers.AetherFSBlockSignature)
+	sizes := md.Get(headers.AetherFSBlockSize)
+
+	if len(signatures) == 0 || len(sizes) == 0 {
+		return status.Errorf(codes.InvalidArgument,
+			"missing %s or %s header", headers.AetherFSBlockSignature, headers.AetherFSBlockSize)
+	}
+
+	if _, err := strconv.ParseInt(sizes[0], 10, 64); err != nil {
+		return status.Errorf(codes.InvalidArgument, "%s is not a number", headers.AetherFSBlockSize)
+	}
+
+	signature := signatures[0]
+
+	uploadContext := metadata.AppendToOutgoingContext(ctx,
+		headers.AetherFSBlockSignature, signature,
+		headers.AetherFSBlockSize, sizes[0],
+	)
+
+	replicas := b.ring.Replicas(signature, b.replicas)
+	uploads := make([]*upload, 0, len(replicas))
+
+	for _, child := range replicas {
+		stream, err := b.children[child].Upload(uploadContext)
+		if err != nil {
+			uploads = append(uploads, &upload{child: child, done: true, err: err})
+			continue
+		}
+
+		uploads = append(uploads, &upload{child: child, stream: stream})
+	}
+
+	active := func() bool {
+		for _, u := range uploads {
+			if !u.done {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for active() {
+		req, err := call.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		for _, u := range uploads {
+			if u.done {
+				continue
+			}
+
+			// replicas that already have the block end the stream early
+			if err := u.stream.Send(req); err != nil {
+				u.finish()
+			}
+		}
+	}
+
+	stored, exists := 0, 0
+	var err error
+
+	for _, u := range uploads {
+		u.finish()
+		b.health.observe(ctx, u.child, u.err)
+
+		switch {
+		case u.err == nil:
+			stored++
+		case status.Code(u.err) == codes.AlreadyExists:
+			exists++
+		default:
+			err = u.err
+			logger.Warn("failed to upload block to replica",
+				zap.String("signature", signature), zap.Int("child", u.child), zap.Error(u.err))
+		}
+	}
+
+	switch {
+	case stored > 0:
+		return call.SendAndClose(&blockv1.UploadResponse{Signature: signature})
+	case exists > 0:
+		return status.Errorf(codes.AlreadyExists, "already exists")
+	}
+
+	return err
+}
+
+var _ blockv1.BlockAPIServer = &blockService{}
@@ -0,0 +1,61 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package composite
+
+import (
+	"context"
+	"fmt"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+)
+
+// Child is one of the drivers data is spread across.
+type Child struct {
+	BlockAPIServer   blockv1.BlockAPIServer
+	DatasetAPIServer datasetv1.DatasetAPIServer
+}
+
+// ObtainStores spreads blocks across the children, storing each on the configured number of replicas. Manifests are
+// small and needed by every read, so they're kept on every child and repaired in the background until the context is
+// cancelled.
+func ObtainStores(ctx context.Context, replicas int, children []Child) (blockv1.BlockAPIServer, datasetv1.DatasetAPIServer, adminv1.AdminAPIServer, error) {
+	switch {
+	case len(children) == 0:
+		return nil, nil, nil, fmt.Errorf("composite driver requires at least one child")
+	case replicas <= 0 || replicas > len(children):
+		return nil, nil, nil, fmt.Errorf("replicas must be between 1 and %d", len(children))
+	}
+
+	blockSvc := &blockService{
+		ctx:      ctx,
+		ring:     NewRing(len(children)),
+		replicas: replicas,
+		children: make([]blockv1.BlockAPIClient, 0, len(children)),
+		health:   &health{},
+	}
+
+	datasetSvc := &datasetService{
+		blocks:   blockSvc,
+		children: make([]datasetv1.DatasetAPIServer, 0, len(children)),
+	}
+
+	for _, child := range children {
+		client, err := inprocess.BlockAPI(ctx, child.BlockAPIServer)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		blockSvc.children = append(blockSvc.children, client)
+		datasetSvc.children = append(datasetSvc.children, child.DatasetAPIServer)
+	}
+
+	if len(children) > 1 {
+		go datasetSvc.run(ctx, RepairEvery)
+	}
+
+	return blockSvc, datasetSvc, &adminService{}, nil
+}
@@ -0,0 +1,371 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/mjpitz/myago/clocks"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+)
+
+// RepairEvery is how often the manifests held by the children are compared and re-replicated.
+const RepairEvery = 5 * time.Minute
+
+type datasetService struct {
+	datasetv1.UnsafeDatasetAPIServer
+
+	blocks   *blockService
+	children []datasetv1.DatasetAPIServer
+
+	// publishing is held for reading by publishes and for writing by repairs, so a repair never acts on manifests that
+	// changed while it was comparing them
+	publishing sync.RWMutex
+
+	// lagging are the children that missed a publish since the driver started. They're read from last and don't decide
+	// publishes until they've been repaired.
+	mu      sync.Mutex
+	lagging map[int]bool
+}
+
+// order returns every child in the order they should be used. Children that are lagging or recently failed go last.
+func (d *datasetService) order(ctx context.Context) []int {
+	d.mu.Lock()
+	current := make([]int, 0, len(d.children))
+	var lagging []int
+
+	for i := range d.children {
+		if d.lagging[i] {
+			lagging = append(lagging, i)
+		} else {
+			current = append(current, i)
+		}
+	}
+	d.mu.Unlock()
+
+	return append(d.blocks.health.sort(ctx, current), d.blocks.health.sort(ctx, lagging)...)
+}
+
+// setLagging records whether the child is missing publishes.
+func (d *datasetService) setLagging(child int, lagging bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.lagging == nil {
+		d.lagging = make(map[int]bool)
+	}
+
+	if lagging {
+		d.lagging[child] = true
+	} else {
+		delete(d.lagging, child)
+	}
+}
+
+func (d *datasetService) isLagging(child int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.lagging[child]
+}
+
+// read calls fn against each child until one succeeds, starting with the healthy ones that are up to date. Every child
+// holds the manifests, so a child that's unavailable or missing a manifest from a failed publish is skipped.
+func (d *datasetService) read(ctx context.Context, fn func(child datasetv1.DatasetAPIServer) error) error {
+	var err error
+	for _, i := range d.order(ctx) {
+		err = fn(d.children[i])
+		d.blocks.health.observe(ctx, i, err)
+
+		if err == nil {
+			return nil
+		}
+
+		if status.Code(err) != codes.NotFound {
+			ctxzap.Extract(ctx).Warn("failed to read from child", zap.Int("child", i), zap.Error(err))
+		}
+	}
+
+	return err
+}
+
+func (d *datasetService) List(ctx context.Context, request *datasetv1.ListRequest) (resp *datasetv1.ListResponse, err error) {
+	err = d.read(ctx, func(child datasetv1.DatasetAPIServer) (err error) {
+		resp, err = child.List(ctx, request)
+		return err
+	})
+
+	return resp, err
+}
+
+func (d *datasetService) ListTags(ctx context.Context, request *datasetv1.ListTagsRequest) (resp *datasetv1.ListTagsResponse, err error) {
+	err = d.read(ctx, func(child datasetv1.DatasetAPIServer) (err error) {
+		resp, err = child.ListTags(ctx, request)
+		return err
+	})
+
+	return resp, err
+}
+
+func (d *datasetService) Lookup(ctx context.Context, request *datasetv1.LookupRequest) (resp *datasetv1.LookupResponse, err error) {
+	err = d.read(ctx, func(child datasetv1.DatasetAPIServer) (err error) {
+		resp, err = child.Lookup(ctx, request)
+		return err
+	})
+
+	return resp, err
+}
+
+func (d *datasetService) Diff(ctx context.Context, request *datasetv1.DiffRequest) (*datasetv1.DiffResponse, error) {
+	base, err := d.Lookup(ctx, &datasetv1.LookupRequest{Tag: request.Base})
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := d.Lookup(ctx, &datasetv1.LookupRequest{Tag: request.Target})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := dataset.Diff(base.Dataset, target.Dataset)
+	resp.BaseDigest = base.Digest
+	resp.TargetDigest = target.Digest
+
+	return resp, nil
+}
+
+// Publish checks the blocks across every child before publishing the manifest to each of them. The first healthy child
+// that's up to date decides whether the publish is accepted (e.g. when the expected previous digests no longer match),
+// falling over to the next one when it can't be reached. The rest follow it. Once the publish is accepted it succeeds,
+// and the children that missed it are repaired in the background.
+func (d *datasetService) Publish(ctx context.Context, request *datasetv1.PublishRequest) (*datasetv1.PublishResponse, error) {
+	logger := ctxzap.Extract(ctx)
+
+	err := dataset.Validate(request.Dataset)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	missing, err := dataset.MissingBlocks(ctx, d.blocks, request.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		return nil, dataset.MissingBlocksError(missing)
+	}
+
+	// each child only holds some of the blocks, so they can't check them on their own
+	ctx = dataset.WithBlocksVerified(ctx)
+
+	d.publishing.RLock()
+	defer d.publishing.RUnlock()
+
+	children := d.order(ctx)
+
+	var resp *datasetv1.PublishResponse
+	committed := 0
+
+	for ; committed < len(children); committed++ {
+		child := children[committed]
+
+		resp, err = d.children[child].Publish(ctx, request)
+		d.blocks.health.observe(ctx, child, err)
+
+		if !failed(err) {
+			break
+		}
+
+		logger.Warn("failed to publish to child, trying the next", zap.Int("child", child), zap.Error(err))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	replica := &datasetv1.PublishRequest{
+		Dataset: request.Dataset,
+		Tags:    request.Tags,
+	}
+
+	for i, child := range children {
+		if i == committed {
+			continue
+		}
+
+		_, err = d.children[child].Publish(ctx, replica)
+		d.blocks.health.observe(ctx, child, err)
+
+		if err != nil {
+			logger.Warn("failed to replicate manifest, it will be repaired later", zap.Int("child", child), zap.Error(err))
+			d.setLagging(child, true)
+		}
+	}
+
+	return resp, nil
+}
+
+type tagKey struct {
+	name    string
+	version string
+}
+
+// manifests returns the manifest for every tag held by the child. Digests aren't tags and are skipped.
+func manifests(ctx context.Context, child datasetv1.DatasetAPIServer) (map[tagKey]*datasetv1.LookupResponse, error) {
+	datasets, err := child.List(ctx, &datasetv1.ListRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list datasets: %w", err)
+	}
+
+	held := make(map[tagKey]*datasetv1.LookupResponse)
+	for _, ds := range datasets.Datasets {
+		tags, err := child.ListTags(ctx, &datasetv1.ListTagsRequest{Name: ds.Name})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for %s: %w", ds.Name, err)
+		}
+
+		for _, tag := range tags.Tags {
+			if dataset.IsDigest(tag.Version) {
+				continue
+			}
+
+			resp, err := child.Lookup(ctx, &datasetv1.LookupRequest{
+				Tag: &datasetv1.Tag{Name: ds.Name, Version: tag.Version},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to lookup %s:%s: %w", ds.Name, tag.Version, err)
+			}
+
+			held[tagKey{name: ds.Name, version: tag.Version}] = resp
+		}
+	}
+
+	return held, nil
+}
+
+// repair copies manifests between the children so they all hold the same tags. A tag missing from a child is copied
+// from the first child that holds it. A tag that differs follows the first child that isn't lagging, since it has seen
+// every publish made since the driver started. Children that are repaired without errors are no longer lagging.
+func (d *datasetService) repair(ctx context.Context) error {
+	logger := ctxzap.Extract(ctx)
+	ctx = dataset.WithBlocksVerified(ctx)
+
+	d.publishing.Lock()
+	defer d.publishing.Unlock()
+
+	children := d.order(ctx)
+	held := make(map[int]map[tagKey]*datasetv1.LookupResponse, len(children))
+	authority := -1
+
+	for _, child := range children {
+		tags, err := manifests(ctx, d.children[child])
+		d.blocks.health.observe(ctx, child, err)
+
+		if err != nil {
+			logger.Warn("failed to read manifests from child", zap.Int("child", child), zap.Error(err))
+			continue
+		}
+
+		held[child] = tags
+
+		if authority < 0 && !d.isLagging(child) {
+			authority = child
+		}
+	}
+
+	if len(held) == 0 {
+		return fmt.Errorf("failed to read manifests from every child")
+	}
+
+	desired := make(map[tagKey]*datasetv1.LookupResponse)
+	for tag, resp := range held[authority] {
+		desired[tag] = resp
+	}
+
+	for _, child := range children {
+		for tag, resp := range held[child] {
+			if _, ok := desired[tag]; !ok {
+				desired[tag] = resp
+			}
+		}
+	}
+
+	for _, child := range children {
+		if held[child] == nil {
+			continue
+		}
+
+		repaired := true
+		for tag, want := range desired {
+			have, ok := held[child][tag]
+			switch {
+			case ok && have.Digest == want.Digest:
+				continue
+			case ok && authority < 0:
+				// none of the children can be trusted to settle the difference
+				repaired = false
+				continue
+			}
+
+			_, err := d.children[child].Publish(ctx, &datasetv1.PublishRequest{
+				Dataset: want.Dataset,
+				Tags:    []*datasetv1.Tag{{Name: tag.name, Version: tag.version}},
+			})
+			d.blocks.health.observe(ctx, child, err)
+
+			if err != nil {
+				logger.Warn("failed to repair manifest", zap.Int("child", child),
+					zap.String("tag", tag.name+":"+tag.version), zap.Error(err))
+				repaired = false
+				continue
+			}
+
+			logger.Info("repaired manifest", zap.Int("child", child), zap.String("tag", tag.name+":"+tag.version))
+		}
+
+		if repaired {
+			d.setLagging(child, false)
+		}
+	}
+
+	return nil
+}
+
+// run repairs the manifests held by the children immediately and then on every interval until the context is
+// cancelled.
+func (d *datasetService) run(ctx context.Context, interval time.Duration) {
+	logger := ctxzap.Extract(ctx)
+	clock := clocks.Extract(ctx)
+
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := d.repair(ctx)
+		if err != nil {
+			logger.Error("failed to repair manifests", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+	}
+}
+
+func (d *datasetService) Subscribe(call datasetv1.DatasetAPI_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "unimplemented")
+}
+
+var _ datasetv1.DatasetAPIServer = &datasetService{}
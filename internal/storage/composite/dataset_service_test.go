@@ -0,0 +1,195 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package composite_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/storage/composite"
+	"github.com/mjpitz/myago/clocks"
+)
+
+func digest(ds *datasetv1.Dataset) string {
+	manifest, _ := proto.MarshalOptions{Deterministic: true}.Marshal(ds)
+	return dataset.Digest(manifest)
+}
+
+// datasetServer keeps the manifests of a single dataset in memory, keyed by name:version. While it's down, every call
+// is unavailable.
+type datasetServer struct {
+	datasetv1.UnimplementedDatasetAPIServer
+
+	mu   sync.Mutex
+	down bool
+	tags map[string]*datasetv1.Dataset
+}
+
+func (d *datasetServer) setDown(down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.down = down
+}
+
+func (d *datasetServer) digest(tag string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if ds, ok := d.tags[tag]; ok {
+		return digest(ds)
+	}
+
+	return ""
+}
+
+func (d *datasetServer) List(ctx context.Context, request *datasetv1.ListRequest) (*datasetv1.ListResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.down {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+
+	resp := &datasetv1.ListResponse{}
+	if len(d.tags) > 0 {
+		resp.Datasets = append(resp.Datasets, &datasetv1.Tag{Name: "ds"})
+	}
+
+	return resp, nil
+}
+
+func (d *datasetServer) ListTags(ctx context.Context, request *datasetv1.ListTagsRequest) (*datasetv1.ListTagsResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.down {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+
+	resp := &datasetv1.ListTagsResponse{}
+	if _, ok := d.tags["ds:latest"]; ok {
+		resp.Tags = append(resp.Tags, &datasetv1.Tag{Name: "ds", Version: "latest"})
+	}
+
+	return resp, nil
+}
+
+func (d *datasetServer) Lookup(ctx context.Context, request *datasetv1.LookupRequest) (*datasetv1.LookupResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ds, ok := d.tags[request.Tag.Name+":"+request.Tag.Version]
+	switch {
+	case d.down:
+		return nil, status.Error(codes.Unavailable, "down")
+	case !ok:
+		return nil, status.Error(codes.NotFound, "tag not found")
+	}
+
+	return &datasetv1.LookupResponse{Dataset: ds, Digest: digest(ds)}, nil
+}
+
+func (d *datasetServer) Publish(ctx context.Context, request *datasetv1.PublishRequest) (*datasetv1.PublishResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.down {
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+
+	for tag, expected := range request.ExpectedPrevious {
+		current := ""
+		if ds, ok := d.tags[tag]; ok {
+			current = digest(ds)
+		}
+
+		if current != expected {
+			return nil, status.Errorf(codes.FailedPrecondition, "%s has moved", tag)
+		}
+	}
+
+	for _, tag := range request.Tags {
+		d.tags[tag.Name+":"+tag.Version] = request.Dataset
+	}
+
+	return &datasetv1.PublishResponse{}, nil
+}
+
+func TestPublish(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+
+	ctx, cancel := context.WithCancel(clocks.ToContext(context.Background(), clock))
+	defer cancel()
+
+	servers := make([]*datasetServer, 3)
+	children := make([]composite.Child, 0, len(servers))
+
+	for i := range servers {
+		servers[i] = &datasetServer{tags: make(map[string]*datasetv1.Dataset)}
+		children = append(children, composite.Child{
+			BlockAPIServer:   &blockv1.UnimplementedBlockAPIServer{},
+			DatasetAPIServer: servers[i],
+		})
+	}
+
+	_, datasetAPI, _, err := composite.ObtainStores(ctx, 1, children)
+	require.NoError(t, err)
+
+	latest := &datasetv1.Tag{Name: "ds", Version: "latest"}
+	v1 := &datasetv1.Dataset{BlockSize: 4, Files: []*datasetv1.File{{Name: "v1.txt"}}}
+	v2 := &datasetv1.Dataset{BlockSize: 4, Files: []*datasetv1.File{{Name: "v2.txt"}}}
+
+	_, err = datasetAPI.Publish(ctx, &datasetv1.PublishRequest{Dataset: v1, Tags: []*datasetv1.Tag{latest}})
+	require.NoError(t, err)
+
+	// the publish is decided by the next child, and succeeds even though a child missed it
+	servers[0].setDown(true)
+
+	_, err = datasetAPI.Publish(ctx, &datasetv1.PublishRequest{
+		Dataset:          v2,
+		Tags:             []*datasetv1.Tag{latest},
+		ExpectedPrevious: map[string]string{"ds:latest": digest(v1)},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, digest(v1), servers[0].digest("ds:latest"))
+	require.Equal(t, digest(v2), servers[1].digest("ds:latest"))
+	require.Equal(t, digest(v2), servers[2].digest("ds:latest"))
+
+	// the child that missed the publish is read from last, even once it's healthy again
+	servers[0].setDown(false)
+	clock.Advance(composite.UnhealthyFor)
+
+	resp, err := datasetAPI.Lookup(ctx, &datasetv1.LookupRequest{Tag: latest})
+	require.NoError(t, err)
+	require.Equal(t, digest(v2), resp.Digest)
+
+	// and it isn't trusted to decide publishes
+	_, err = datasetAPI.Publish(ctx, &datasetv1.PublishRequest{
+		Dataset:          v2,
+		Tags:             []*datasetv1.Tag{latest},
+		ExpectedPrevious: map[string]string{"ds:latest": digest(v1)},
+	})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// until it's repaired
+	clock.BlockUntil(1)
+	clock.Advance(composite.RepairEvery)
+
+	require.Eventually(t, func() bool {
+		return servers[0].digest("ds:latest") == digest(v2)
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,74 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package composite
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mjpitz/myago/clocks"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnhealthyFor is how long a child is tried last after it fails a request.
+const UnhealthyFor = 30 * time.Second
+
+// health tracks the children that recently failed so reads go to the healthy ones first instead of waiting on a
+// child that's down.
+type health struct {
+	mu        sync.Mutex
+	unhealthy map[int]time.Time
+}
+
+// failed returns true when the error means the child couldn't handle the call. Missing data and rejected requests
+// aren't failures of the child.
+func failed(err error) bool {
+	switch status.Code(err) {
+	case codes.OK, codes.NotFound, codes.AlreadyExists, codes.FailedPrecondition, codes.InvalidArgument, codes.Canceled:
+		return false
+	}
+
+	return true
+}
+
+// observe records the outcome of a call to the child.
+func (h *health) observe(ctx context.Context, child int, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case status.Code(err) == codes.Canceled:
+	case !failed(err):
+		delete(h.unhealthy, child)
+	default:
+		if h.unhealthy == nil {
+			h.unhealthy = make(map[int]time.Time)
+		}
+
+		h.unhealthy[child] = clocks.Extract(ctx).Now().Add(UnhealthyFor)
+	}
+}
+
+// sort moves the children that are unhealthy to the end, otherwise preserving their order.
+func (h *health) sort(ctx context.Context, children []int) []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := clocks.Extract(ctx).Now()
+
+	sorted := make([]int, 0, len(children))
+	var unhealthy []int
+
+	for _, child := range children {
+		if until, ok := h.unhealthy[child]; ok && now.Before(until) {
+			unhealthy = append(unhealthy, child)
+		} else {
+			sorted = append(sorted, child)
+		}
+	}
+
+	return append(sorted, unhealthy...)
+}
@@ -0,0 +1,82 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package composite
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// virtualNodes is the number of points each child owns on the ring. More points spread blocks more evenly.
+const virtualNodes = 128
+
+func hash(key string) uint64 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// Ring assigns blocks to children using consistent hashing, so adding a child only moves the blocks it takes over.
+// Children are identified by their position, so new children should be added to the end of the list.
+type Ring struct {
+	children int
+	points   []uint64
+	owners   map[uint64]int
+}
+
+// NewRing returns a ring containing the provided number of children.
+func NewRing(children int) *Ring {
+	r := &Ring{
+		children: children,
+		points:   make([]uint64, 0, children*virtualNodes),
+		owners:   make(map[uint64]int, children*virtualNodes),
+	}
+
+	for child := 0; child < children; child++ {
+		for node := 0; node < virtualNodes; node++ {
+			point := hash(strconv.Itoa(child) + "-" + strconv.Itoa(node))
+			if _, ok := r.owners[point]; ok {
+				continue
+			}
+
+			r.owners[point] = child
+			r.points = append(r.points, point)
+		}
+	}
+
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i] < r.points[j]
+	})
+
+	return r
+}
+
+// Replicas returns the distinct children the signature is stored on, in order of preference.
+func (r *Ring) Replicas(signature string, n int) []int {
+	if n > r.children {
+		n = r.children
+	}
+
+	replicas := make([]int, 0, n)
+	if n <= 0 {
+		return replicas
+	}
+
+	point := hash(signature)
+	start := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= point
+	})
+
+	seen := make(map[int]bool, n)
+	for i := 0; len(replicas) < n; i++ {
+		owner := r.owners[r.points[(start+i)%len(r.points)]]
+		if !seen[owner] {
+			seen[owner] = true
+			replicas = append(replicas, owner)
+		}
+	}
+
+	return replicas
+}
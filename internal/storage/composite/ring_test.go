@@ -0,0 +1,49 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package composite_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/mjpitz/aetherfs/internal/storage/composite"
+)
+
+func TestRing(t *testing.T) {
+	const signatures = 10000
+
+	three := composite.NewRing(3)
+	four := composite.NewRing(4)
+
+	counts := make(map[int]int)
+	moved := 0
+
+	for i := 0; i < signatures; i++ {
+		signature := strconv.Itoa(i)
+
+		replicas := three.Replicas(signature, 2)
+		require.Len(t, replicas, 2)
+		require.NotEqual(t, replicas[0], replicas[1])
+		require.Equal(t, replicas, three.Replicas(signature, 2))
+
+		counts[replicas[0]]++
+
+		if four.Replicas(signature, 1)[0] != replicas[0] {
+			moved++
+		}
+	}
+
+	// blocks are spread evenly across children
+	for child := 0; child < 3; child++ {
+		require.InDelta(t, signatures/3, counts[child], signatures/10)
+	}
+
+	// adding a child only moves the blocks it takes over
+	require.InDelta(t, signatures/4, moved, signatures/10)
+
+	// replicas are capped by the number of children
+	require.Len(t, three.Replicas("a", 5), 3)
+}
@@ -7,34 +7,144 @@ import (
 	"context"
 	"fmt"
 
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
 	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
 	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/storage/composite"
 	"github.com/mjpitz/aetherfs/internal/storage/proxy"
+	"github.com/mjpitz/aetherfs/internal/storage/routed"
 	"github.com/mjpitz/aetherfs/internal/storage/s3"
+	"github.com/mjpitz/aetherfs/internal/storage/tiered"
 )
 
 type Config struct {
 	Driver string `json:"driver" usage:"configure how information is stored" default:"s3"`
 
+	Admin struct {
+		Enable bool `json:"enable" usage:"enable the admin API for checking the integrity of storage"`
+	} `json:"admin"`
+
+	// S3 stores datasets in a bucket. Publishes are only committed atomically within a single hub, so only one hub
+	// should publish to a bucket at a time.
 	S3    s3.Config    `json:"s3"`
 	Proxy proxy.Config `json:"proxy"`
+
+	// Composite spreads blocks across the child drivers. Children can only be configured using a config file.
+	Composite struct {
+		Replicas int      `json:"replicas" usage:"the number of children each block is stored on" default:"2"`
+		Children []Config `json:"children"`
+	} `json:"composite"`
+
+	// Tiered keeps recently read blocks on the hot tier and moves the rest to the cold tier. The tiers (hot followed by
+	// cold) can only be configured using a config file.
+	Tiered struct {
+		tiered.Config
+		Tiers []Config `json:"tiers"`
+	} `json:"tiered"`
+
+	// Routed sends each dataset to the first route whose pattern (e.g. @ml/*) matches its name. A route without a
+	// pattern matches every dataset. Routes can only be configured using a config file.
+	Routed struct {
+		Routes []Route `json:"routes"`
+	} `json:"routed"`
+}
+
+// Route sends the datasets matching the pattern to the driver.
+type Route struct {
+	Pattern string `json:"pattern"`
+	Config
 }
 
 type Stores struct {
 	BlockAPIServer   blockv1.BlockAPIServer
 	DatasetAPIServer datasetv1.DatasetAPIServer
+	AdminAPIServer   adminv1.AdminAPIServer
+}
+
+// obtainChildren obtains the stores for each of the drivers a driver wraps.
+func obtainChildren(ctx context.Context, configs []Config) ([]*Stores, error) {
+	children := make([]*Stores, 0, len(configs))
+
+	for i, childConfig := range configs {
+		child, err := ObtainStores(ctx, childConfig)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("child %d: %w", i, err)
+		case child == nil:
+			return nil, fmt.Errorf("child %d: a driver is required", i)
+		}
+
+		children = append(children, child)
+	}
+
+	return children, nil
 }
 
 func ObtainStores(ctx context.Context, cfg Config) (*Stores, error) {
 	var blockAPI blockv1.BlockAPIServer
 	var datasetAPI datasetv1.DatasetAPIServer
+	var adminAPI adminv1.AdminAPIServer
 	var err error
 
 	switch cfg.Driver {
 	case "s3":
-		blockAPI, datasetAPI, err = s3.ObtainStores(ctx, cfg.S3)
+		blockAPI, datasetAPI, adminAPI, err = s3.ObtainStores(ctx, cfg.S3)
 	case "proxy":
-		blockAPI, datasetAPI, err = proxy.ObtainStores(ctx, cfg.Proxy)
+		blockAPI, datasetAPI, adminAPI, err = proxy.ObtainStores(ctx, cfg.Proxy)
+	case "composite":
+		var stores []*Stores
+		stores, err = obtainChildren(ctx, cfg.Composite.Children)
+		if err != nil {
+			return nil, err
+		}
+
+		children := make([]composite.Child, 0, len(stores))
+		for _, child := range stores {
+			children = append(children, composite.Child{
+				BlockAPIServer:   child.BlockAPIServer,
+				DatasetAPIServer: child.DatasetAPIServer,
+			})
+		}
+
+		blockAPI, datasetAPI, adminAPI, err = composite.ObtainStores(ctx, cfg.Composite.Replicas, children)
+	case "tiered":
+		if len(cfg.Tiered.Tiers) != 2 {
+			return nil, fmt.Errorf("tiered driver requires a hot and a cold tier")
+		}
+
+		var tiers []*Stores
+		tiers, err = obtainChildren(ctx, cfg.Tiered.Tiers)
+		if err != nil {
+			return nil, err
+		}
+
+		hot := tiered.Tier{BlockAPIServer: tiers[0].BlockAPIServer, DatasetAPIServer: tiers[0].DatasetAPIServer}
+		cold := tiered.Tier{BlockAPIServer: tiers[1].BlockAPIServer, DatasetAPIServer: tiers[1].DatasetAPIServer}
+
+		blockAPI, datasetAPI, adminAPI, err = tiered.ObtainStores(ctx, cfg.Tiered.Config, hot, cold)
+	case "routed":
+		configs := make([]Config, 0, len(cfg.Routed.Routes))
+		for _, route := range cfg.Routed.Routes {
+			configs = append(configs, route.Config)
+		}
+
+		var stores []*Stores
+		stores, err = obtainChildren(ctx, configs)
+		if err != nil {
+			return nil, err
+		}
+
+		routes := make([]routed.Route, 0, len(stores))
+		for i, route := range stores {
+			routes = append(routes, routed.Route{
+				Pattern:          cfg.Routed.Routes[i].Pattern,
+				BlockAPIServer:   route.BlockAPIServer,
+				DatasetAPIServer: route.DatasetAPIServer,
+				AdminAPIServer:   route.AdminAPIServer,
+			})
+		}
+
+		blockAPI, datasetAPI, adminAPI, err = routed.ObtainStores(ctx, routes)
 	case "", "none":
 		return nil, nil
 	default:
@@ -48,5 +158,6 @@ func ObtainStores(ctx context.Context, cfg Config) (*Stores, error) {
 	return &Stores{
 		BlockAPIServer:   blockAPI,
 		DatasetAPIServer: datasetAPI,
+		AdminAPIServer:   adminAPI,
 	}, nil
 }
@@ -0,0 +1,160 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+// Package inprocess lets drivers that wrap other drivers call them using the same clients they'd use over the network,
+// and forwards block streams between those clients and the calls being handled.
+package inprocess
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/aetherfs/internal/headers"
+)
+
+// bufferSize is the size of the in-memory connection used to stream blocks to and from a driver.
+const bufferSize = 1 << 20
+
+// BlockAPI serves the block api over an in-memory connection so the driver's streams can be used like any other
+// client's. Calls are handled using the logger on the provided context.
+func BlockAPI(ctx context.Context, blockAPI blockv1.BlockAPIServer) (blockv1.BlockAPIClient, error) {
+	logger := ctxzap.Extract(ctx)
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(ctxzap.ToContext(ctx, logger), req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			wrapped := grpc_middleware.WrapServerStream(ss)
+			wrapped.WrappedContext = ctxzap.ToContext(ss.Context(), logger)
+
+			return handler(srv, wrapped)
+		}),
+	)
+
+	blockv1.RegisterBlockAPIServer(server, blockAPI)
+
+	listener := bufconn.Listen(bufferSize)
+	go func() { _ = server.Serve(listener) }()
+
+	conn, err := grpc.DialContext(ctx, "in-process",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	if err != nil {
+		server.Stop()
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+		server.Stop()
+	}()
+
+	return blockv1.NewBlockAPIClient(conn), nil
+}
+
+// WithDataset copies the dataset the incoming call says it's working with onto the outgoing context so drivers that
+// route blocks by dataset can do so.
+func WithDataset(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if datasets := md.Get(headers.AetherFSDataset); len(datasets) > 0 {
+		return metadata.AppendToOutgoingContext(ctx, headers.AetherFSDataset, datasets[0])
+	}
+
+	return ctx
+}
+
+// Forward streams the block from the client to the call, returning the number of bytes sent.
+func Forward(client blockv1.BlockAPIClient, request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) (int64, error) {
+	stream, err := client.Download(WithDataset(call.Context()), request)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := int64(0)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return sent, nil
+		} else if err != nil {
+			return sent, err
+		}
+
+		err = call.Send(resp)
+		if err != nil {
+			return sent, err
+		}
+
+		sent += int64(len(resp.Part))
+	}
+}
+
+// ForwardUpload streams the block being uploaded by the call to the client, returning its signature. Clients that
+// already have the block return an AlreadyExists error.
+func ForwardUpload(client blockv1.BlockAPIClient, call blockv1.BlockAPI_UploadServer) (string, error) {
+	ctx := call.Context()
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.New(nil)
+	}
+
+	signatures := md.Get(headers.AetherFSBlockSignature)
+	sizes := md.Get(headers.AetherFSBlockSize)
+
+	if len(signatures) == 0 || len(sizes) == 0 {
+		return "", status.Errorf(codes.InvalidArgument,
+			"missing %s or %s header", headers.AetherFSBlockSignature, headers.AetherFSBlockSize)
+	}
+
+	if _, err := strconv.ParseInt(sizes[0], 10, 64); err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "%s is not a number", headers.AetherFSBlockSize)
+	}
+
+	signature := signatures[0]
+
+	stream, err := client.Upload(metadata.AppendToOutgoingContext(WithDataset(ctx),
+		headers.AetherFSBlockSignature, signature,
+		headers.AetherFSBlockSize, sizes[0],
+	))
+	if err != nil {
+		return signature, err
+	}
+
+	for {
+		req, err := call.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return signature, err
+		}
+
+		// clients end the stream early when they already have the block
+		if err := stream.Send(req); err != nil {
+			break
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	if err != nil && err != io.EOF {
+		return signature, err
+	}
+
+	return signature, call.SendAndClose(&blockv1.UploadResponse{Signature: signature})
+}
@@ -74,6 +74,27 @@ func (d *DB) Tokens() *Store {
 	}
 }
 
+func (d *DB) Journals() *Store {
+	return &Store{
+		db:     d.db,
+		prefix: "journals",
+	}
+}
+
+func (d *DB) Subscriptions() *Store {
+	return &Store{
+		db:     d.db,
+		prefix: "subscriptions",
+	}
+}
+
+func (d *DB) Tiers() *Store {
+	return &Store{
+		db:     d.db,
+		prefix: "tiers",
+	}
+}
+
 func (d *DB) Close() error {
 	return d.db.Close()
 }
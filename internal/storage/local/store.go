@@ -6,6 +6,7 @@ package local
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/dgraph-io/badger/v3"
 )
@@ -51,3 +52,51 @@ func (c *Store) Delete(ctx context.Context, key string) error {
 		return txn.Delete(k)
 	})
 }
+
+// DeletePrefix removes all values in the store whose keys start with the provided prefix.
+func (c *Store) DeletePrefix(ctx context.Context, keyPrefix string) error {
+	keys, err := c.ListPrefix(ctx, keyPrefix)
+	if err != nil {
+		return err
+	}
+
+	batch := c.db.NewWriteBatch()
+	defer batch.Cancel()
+
+	for _, key := range keys {
+		err = batch.Delete([]byte(c.prefix + "/" + key))
+		if err != nil {
+			return err
+		}
+	}
+
+	return batch.Flush()
+}
+
+// List returns the keys of all values in the store.
+func (c *Store) List(ctx context.Context) ([]string, error) {
+	return c.ListPrefix(ctx, "")
+}
+
+// ListPrefix returns the keys of all values in the store that start with the provided prefix, in order.
+func (c *Store) ListPrefix(ctx context.Context, keyPrefix string) ([]string, error) {
+	prefix := c.prefix + "/"
+	keys := make([]string, 0)
+
+	err := c.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = []byte(prefix + keyPrefix)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			keys = append(keys, strings.TrimPrefix(string(it.Item().Key()), prefix))
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
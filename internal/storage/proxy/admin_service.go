@@ -0,0 +1,22 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package proxy
+
+import (
+	"context"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+)
+
+type adminService struct {
+	adminv1.UnsafeAdminAPIServer
+
+	delegate adminv1.AdminAPIClient
+}
+
+func (a *adminService) Fsck(ctx context.Context, request *adminv1.FsckRequest) (*adminv1.FsckResponse, error) {
+	return a.delegate.Fsck(ctx, request)
+}
+
+var _ adminv1.AdminAPIServer = &adminService{}
@@ -5,9 +5,9 @@ package proxy
 
 import (
 	"context"
-	"io"
 
 	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
 )
 
 type blockService struct {
@@ -17,56 +17,21 @@ type blockService struct {
 }
 
 func (b *blockService) Lookup(ctx context.Context, request *blockv1.LookupRequest) (*blockv1.LookupResponse, error) {
-	return b.delegate.Lookup(ctx, request)
+	return b.delegate.Lookup(inprocess.WithDataset(ctx), request)
 }
 
-func (b *blockService) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
-	up, err := b.delegate.Download(call.Context(), request)
-	if err != nil {
-		return err
-	}
-
-	msg := &blockv1.DownloadResponse{}
-	for {
-		err := up.RecvMsg(msg)
-		if err != nil {
-			return err
-		}
+func (b *blockService) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	return b.delegate.LookupBatch(inprocess.WithDataset(ctx), request)
+}
 
-		err = call.Send(msg)
-		if err != nil {
-			return err
-		}
-	}
+func (b *blockService) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
+	_, err := inprocess.Forward(b.delegate, request, call)
+	return err
 }
 
 func (b *blockService) Upload(call blockv1.BlockAPI_UploadServer) error {
-	up, err := b.delegate.Upload(call.Context())
-	if err != nil {
-		return err
-	}
-
-	msg := &blockv1.UploadRequest{}
-	for {
-		err = call.RecvMsg(msg)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
-
-		err = up.Send(msg)
-		if err != nil {
-			return err
-		}
-	}
-
-	resp, err := up.CloseAndRecv()
-	if err != nil {
-		return err
-	}
-
-	return call.SendAndClose(resp)
+	_, err := inprocess.ForwardUpload(b.delegate, call)
+	return err
 }
 
 var _ blockv1.BlockAPIServer = &blockService{}
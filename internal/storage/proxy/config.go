@@ -6,6 +6,7 @@ package proxy
 import (
 	"context"
 
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
 	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
 	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
 	"github.com/mjpitz/aetherfs/internal/components"
@@ -17,13 +18,13 @@ type Config struct {
 	TLS    livetls.Config `json:"tls"`
 }
 
-func ObtainStores(ctx context.Context, cfg Config) (blockv1.BlockAPIServer, datasetv1.DatasetAPIServer, error) {
+func ObtainStores(ctx context.Context, cfg Config) (blockv1.BlockAPIServer, datasetv1.DatasetAPIServer, adminv1.AdminAPIServer, error) {
 	conn, err := components.GRPCClient(ctx, components.GRPCClientConfig{
 		Target: cfg.Target,
 		TLS:    cfg.TLS,
 	})
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	blockSvc := &blockService{
@@ -34,5 +35,9 @@ func ObtainStores(ctx context.Context, cfg Config) (blockv1.BlockAPIServer, data
 		delegate: datasetv1.NewDatasetAPIClient(conn),
 	}
 
-	return blockSvc, datasetSvc, nil
+	adminSvc := &adminService{
+		delegate: adminv1.NewAdminAPIClient(conn),
+	}
+
+	return blockSvc, datasetSvc, adminSvc, nil
 }
@@ -30,6 +30,10 @@ func (d *datasetService) Lookup(ctx context.Context, request *datasetv1.LookupRe
 	return d.delegate.Lookup(ctx, request)
 }
 
+func (d *datasetService) Diff(ctx context.Context, request *datasetv1.DiffRequest) (*datasetv1.DiffResponse, error) {
+	return d.delegate.Diff(ctx, request)
+}
+
 func (d *datasetService) Publish(ctx context.Context, request *datasetv1.PublishRequest) (*datasetv1.PublishResponse, error) {
 	return d.delegate.Publish(ctx, request)
 }
@@ -0,0 +1,57 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package routed
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+)
+
+type adminService struct {
+	adminv1.UnsafeAdminAPIServer
+
+	router *router
+}
+
+// Fsck checks each route, merging the results. Routes whose storage doesn't support checking are reported as unchecked
+// rather than failing the others.
+func (a *adminService) Fsck(ctx context.Context, request *adminv1.FsckRequest) (*adminv1.FsckResponse, error) {
+	resp := &adminv1.FsckResponse{}
+
+	for _, route := range a.router.routes {
+		pattern := route.Pattern
+		if pattern == "" {
+			pattern = "*"
+		}
+
+		checked, err := route.AdminAPIServer.Fsck(ctx, request)
+		switch {
+		case status.Code(err) == codes.Unimplemented:
+			ctxzap.Extract(ctx).Warn("storage does not support fsck", zap.String("pattern", pattern))
+			resp.Unchecked = append(resp.Unchecked, pattern)
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		resp.Datasets += checked.Datasets
+		resp.Blocks += checked.Blocks
+		resp.Broken = append(resp.Broken, checked.Broken...)
+		resp.Orphaned = append(resp.Orphaned, checked.Orphaned...)
+	}
+
+	if len(resp.Unchecked) == len(a.router.routes) {
+		return nil, status.Error(codes.Unimplemented, "fsck is not supported by any route")
+	}
+
+	return resp, nil
+}
+
+var _ adminv1.AdminAPIServer = &adminService{}
@@ -0,0 +1,91 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package routed_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/aetherfs/internal/storage/routed"
+)
+
+// adminServer reports a fixed result, or Unimplemented when it has none.
+type adminServer struct {
+	adminv1.UnimplementedAdminAPIServer
+
+	resp *adminv1.FsckResponse
+}
+
+func (a *adminServer) Fsck(ctx context.Context, request *adminv1.FsckRequest) (*adminv1.FsckResponse, error) {
+	if a.resp == nil {
+		return nil, status.Error(codes.Unimplemented, "fsck is not supported")
+	}
+
+	return a.resp, nil
+}
+
+func TestFsck(t *testing.T) {
+	checked := &adminv1.FsckResponse{Datasets: 2, Blocks: 5}
+
+	testCases := []struct {
+		name      string
+		routes    map[string]*adminv1.FsckResponse
+		datasets  int64
+		unchecked []string
+		code      codes.Code
+	}{
+		{
+			name:     "all supported",
+			routes:   map[string]*adminv1.FsckResponse{"@ml/*": checked, "": checked},
+			datasets: 4,
+		},
+		{
+			name:      "some unsupported",
+			routes:    map[string]*adminv1.FsckResponse{"@ml/*": nil, "": checked},
+			datasets:  2,
+			unchecked: []string{"@ml/*"},
+		},
+		{
+			name:   "none supported",
+			routes: map[string]*adminv1.FsckResponse{"@ml/*": nil, "": nil},
+			code:   codes.Unimplemented,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// the default route goes last
+			routes := make([]routed.Route, 0, len(testCase.routes))
+			for _, pattern := range []string{"@ml/*", ""} {
+				routes = append(routes, routed.Route{
+					Pattern:        pattern,
+					BlockAPIServer: &blockv1.UnimplementedBlockAPIServer{},
+					AdminAPIServer: &adminServer{resp: testCase.routes[pattern]},
+				})
+			}
+
+			_, _, adminAPI, err := routed.ObtainStores(ctx, routes)
+			require.NoError(t, err)
+
+			resp, err := adminAPI.Fsck(ctx, &adminv1.FsckRequest{})
+			if testCase.code != codes.OK {
+				require.Equal(t, testCase.code, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.datasets, resp.Datasets)
+			require.Equal(t, testCase.unchecked, resp.Unchecked)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package routed
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/aetherfs/internal/headers"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+)
+
+type blockService struct {
+	blockv1.UnsafeBlockAPIServer
+
+	router  *router
+	clients []blockv1.BlockAPIClient
+}
+
+func (b *blockService) Lookup(ctx context.Context, request *blockv1.LookupRequest) (*blockv1.LookupResponse, error) {
+	candidates, err := b.router.candidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range candidates {
+		var resp *blockv1.LookupResponse
+
+		resp, err = b.clients[i].Lookup(ctx, request)
+		if status.Code(err) != codes.NotFound {
+			return resp, err
+		}
+	}
+
+	return nil, err
+}
+
+func (b *blockService) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	candidates, err := b.router.candidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &blockv1.LookupBatchResponse{Missing: request.Signatures}
+	for _, i := range candidates {
+		if len(resp.Missing) == 0 {
+			break
+		}
+
+		resp, err = b.clients[i].LookupBatch(ctx, &blockv1.LookupBatchRequest{Signatures: resp.Missing})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (b *blockService) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
+	candidates, err := b.router.candidates(call.Context())
+	if err != nil {
+		return err
+	}
+
+	for _, i := range candidates {
+		var sent int64
+
+		sent, err = inprocess.Forward(b.clients[i], request, call)
+		if sent > 0 || status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	return err
+}
+
+// Upload writes the block to the route for the dataset being published. When there's more than one route, callers
+// must say which dataset they're publishing. Otherwise, the block could be written to a different route than the one
+// the dataset is published to.
+func (b *blockService) Upload(call blockv1.BlockAPI_UploadServer) error {
+	name := datasetName(call.Context())
+
+	i, err := b.router.route(name)
+	switch {
+	case name == "" && (err != nil || len(b.router.routes) > 1):
+		return status.Errorf(codes.InvalidArgument, "missing %s header", headers.AetherFSDataset)
+	case err != nil:
+		return err
+	}
+
+	_, err = inprocess.ForwardUpload(b.clients[i], call)
+
+	return err
+}
+
+var _ blockv1.BlockAPIServer = &blockService{}
@@ -0,0 +1,120 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package routed
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+)
+
+type datasetService struct {
+	datasetv1.UnsafeDatasetAPIServer
+
+	router *router
+}
+
+// List merges the datasets from every route. Datasets are only listed by the route they're routed to, so a default
+// route that still holds datasets that have since been routed elsewhere doesn't list them twice.
+func (d *datasetService) List(ctx context.Context, request *datasetv1.ListRequest) (*datasetv1.ListResponse, error) {
+	resp := &datasetv1.ListResponse{}
+
+	for i, route := range d.router.routes {
+		listed, err := route.DatasetAPIServer.List(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ds := range listed.Datasets {
+			if j, err := d.router.route(ds.Name); err == nil && i == j {
+				resp.Datasets = append(resp.Datasets, ds)
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+func (d *datasetService) ListTags(ctx context.Context, request *datasetv1.ListTagsRequest) (*datasetv1.ListTagsResponse, error) {
+	i, err := d.router.route(request.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.router.routes[i].DatasetAPIServer.ListTags(ctx, request)
+}
+
+func (d *datasetService) Lookup(ctx context.Context, request *datasetv1.LookupRequest) (*datasetv1.LookupResponse, error) {
+	if request.Tag == nil {
+		return nil, status.Error(codes.InvalidArgument, "tag is required")
+	}
+
+	i, err := d.router.route(request.Tag.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.router.routes[i].DatasetAPIServer.Lookup(ctx, request)
+}
+
+// Diff compares the datasets here since they may be routed to different drivers.
+func (d *datasetService) Diff(ctx context.Context, request *datasetv1.DiffRequest) (*datasetv1.DiffResponse, error) {
+	if request.Base == nil || request.Target == nil {
+		return nil, status.Error(codes.InvalidArgument, "base and target are required")
+	}
+
+	base, err := d.Lookup(ctx, &datasetv1.LookupRequest{Tag: request.Base})
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := d.Lookup(ctx, &datasetv1.LookupRequest{Tag: request.Target})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := dataset.Diff(base.Dataset, target.Dataset)
+	resp.BaseDigest = base.Digest
+	resp.TargetDigest = target.Digest
+
+	return resp, nil
+}
+
+// Publish sends the dataset to the route for its tags. Tags that are routed to different drivers can't be published
+// together since the blocks were only uploaded to one of them.
+func (d *datasetService) Publish(ctx context.Context, request *datasetv1.PublishRequest) (*datasetv1.PublishResponse, error) {
+	route := -1
+	for _, tag := range request.Tags {
+		i, err := d.router.route(tag.Name)
+		switch {
+		case err != nil:
+			return nil, err
+		case route >= 0 && i != route:
+			return nil, status.Errorf(codes.InvalidArgument, "%s is stored separately from the other tags and must be published on its own", tag.Name)
+		}
+
+		route = i
+	}
+
+	if route < 0 {
+		i, err := d.router.route("")
+		if err != nil {
+			return nil, err
+		}
+
+		route = i
+	}
+
+	return d.router.routes[route].DatasetAPIServer.Publish(ctx, request)
+}
+
+func (d *datasetService) Subscribe(call datasetv1.DatasetAPI_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "unimplemented")
+}
+
+var _ datasetv1.DatasetAPIServer = &datasetService{}
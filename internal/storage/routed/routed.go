@@ -0,0 +1,112 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package routed
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/headers"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+)
+
+// Route sends the datasets whose name matches the pattern (e.g. @ml/*) to a driver. An empty pattern matches every
+// dataset, making it the default.
+type Route struct {
+	Pattern          string
+	BlockAPIServer   blockv1.BlockAPIServer
+	DatasetAPIServer datasetv1.DatasetAPIServer
+	AdminAPIServer   adminv1.AdminAPIServer
+}
+
+// router picks the route for a dataset.
+type router struct {
+	routes []Route
+}
+
+// route returns the index of the first route matching the dataset.
+func (r *router) route(name string) (int, error) {
+	for i, route := range r.routes {
+		if route.Pattern == "" {
+			return i, nil
+		}
+
+		if ok, _ := path.Match(route.Pattern, name); ok {
+			return i, nil
+		}
+	}
+
+	return 0, status.Errorf(codes.NotFound, "no storage is configured for %s", name)
+}
+
+// datasetName returns the dataset the call says it's working with.
+func datasetName(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if datasets := md.Get(headers.AetherFSDataset); len(datasets) > 0 {
+		return datasets[0]
+	}
+
+	return ""
+}
+
+// candidates returns the routes that may hold the blocks for the call. Callers that say which dataset they're working
+// with only need the route for the dataset, otherwise every route is checked.
+func (r *router) candidates(ctx context.Context) ([]int, error) {
+	if name := datasetName(ctx); name != "" {
+		i, err := r.route(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return []int{i}, nil
+	}
+
+	all := make([]int, 0, len(r.routes))
+	for i := range r.routes {
+		all = append(all, i)
+	}
+
+	return all, nil
+}
+
+// ObtainStores routes each dataset to the first route whose pattern matches its name. Routes are checked in order, so
+// more specific patterns should come first and the default last.
+func ObtainStores(ctx context.Context, routes []Route) (blockv1.BlockAPIServer, datasetv1.DatasetAPIServer, adminv1.AdminAPIServer, error) {
+	if len(routes) == 0 {
+		return nil, nil, nil, fmt.Errorf("routed driver requires at least one route")
+	}
+
+	for _, route := range routes {
+		if _, err := path.Match(route.Pattern, ""); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid pattern %q: %w", route.Pattern, err)
+		}
+	}
+
+	r := &router{routes: routes}
+
+	blockSvc := &blockService{
+		router:  r,
+		clients: make([]blockv1.BlockAPIClient, 0, len(routes)),
+	}
+
+	for _, route := range routes {
+		client, err := inprocess.BlockAPI(ctx, route.BlockAPIServer)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		blockSvc.clients = append(blockSvc.clients, client)
+	}
+
+	return blockSvc, &datasetService{router: r}, &adminService{router: r}, nil
+}
@@ -0,0 +1,391 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package routed_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/headers"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+	"github.com/mjpitz/aetherfs/internal/storage/routed"
+)
+
+// patterns are the routes used by the tests. More specific patterns come first and the default goes last.
+var patterns = []string{"@ml/special", "@ml/*", ""}
+
+// blockServer keeps blocks in memory.
+type blockServer struct {
+	blockv1.UnimplementedBlockAPIServer
+
+	mu     sync.Mutex
+	blocks map[string][]byte
+}
+
+func (b *blockServer) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	resp := &blockv1.LookupBatchResponse{}
+	for _, signature := range request.Signatures {
+		if _, ok := b.blocks[signature]; !ok {
+			resp.Missing = append(resp.Missing, signature)
+		}
+	}
+
+	return resp, nil
+}
+
+func (b *blockServer) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
+	b.mu.Lock()
+	data, ok := b.blocks[request.Signature]
+	b.mu.Unlock()
+
+	if !ok {
+		return status.Error(codes.NotFound, "block not found")
+	}
+
+	return call.Send(&blockv1.DownloadResponse{Part: data})
+}
+
+func (b *blockServer) Upload(call blockv1.BlockAPI_UploadServer) error {
+	md, _ := metadata.FromIncomingContext(call.Context())
+	signature := md.Get(headers.AetherFSBlockSignature)[0]
+
+	data := &bytes.Buffer{}
+	for {
+		req, err := call.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		data.Write(req.Part)
+	}
+
+	b.mu.Lock()
+	b.blocks[signature] = data.Bytes()
+	b.mu.Unlock()
+
+	return call.SendAndClose(&blockv1.UploadResponse{Signature: signature})
+}
+
+// datasetServer keeps manifests in memory, keyed by name:version.
+type datasetServer struct {
+	datasetv1.UnimplementedDatasetAPIServer
+
+	mu   sync.Mutex
+	tags map[string]*datasetv1.Dataset
+}
+
+func (d *datasetServer) List(ctx context.Context, request *datasetv1.ListRequest) (*datasetv1.ListResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := make(map[string]bool)
+	resp := &datasetv1.ListResponse{}
+
+	for tag := range d.tags {
+		name := strings.SplitN(tag, ":", 2)[0]
+		if !names[name] {
+			names[name] = true
+			resp.Datasets = append(resp.Datasets, &datasetv1.Tag{Name: name})
+		}
+	}
+
+	return resp, nil
+}
+
+func (d *datasetServer) Lookup(ctx context.Context, request *datasetv1.LookupRequest) (*datasetv1.LookupResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ds, ok := d.tags[request.Tag.Name+":"+request.Tag.Version]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "tag not found")
+	}
+
+	return &datasetv1.LookupResponse{Dataset: ds}, nil
+}
+
+func (d *datasetServer) Publish(ctx context.Context, request *datasetv1.PublishRequest) (*datasetv1.PublishResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, tag := range request.Tags {
+		d.tags[tag.Name+":"+tag.Version] = request.Dataset
+	}
+
+	return &datasetv1.PublishResponse{}, nil
+}
+
+// driver is the routed driver along with the in-memory drivers behind each of its routes.
+type driver struct {
+	blocks   []*blockServer
+	datasets []*datasetServer
+
+	blockAPI   blockv1.BlockAPIClient
+	datasetAPI datasetv1.DatasetAPIServer
+}
+
+func newDriver(ctx context.Context, t *testing.T, patterns []string) *driver {
+	d := &driver{}
+	routes := make([]routed.Route, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		blocks := &blockServer{blocks: make(map[string][]byte)}
+		datasets := &datasetServer{tags: make(map[string]*datasetv1.Dataset)}
+
+		d.blocks = append(d.blocks, blocks)
+		d.datasets = append(d.datasets, datasets)
+
+		routes = append(routes, routed.Route{
+			Pattern:          pattern,
+			BlockAPIServer:   blocks,
+			DatasetAPIServer: datasets,
+		})
+	}
+
+	blockAPI, datasetAPI, _, err := routed.ObtainStores(ctx, routes)
+	require.NoError(t, err)
+
+	d.blockAPI, err = inprocess.BlockAPI(ctx, blockAPI)
+	require.NoError(t, err)
+
+	d.datasetAPI = datasetAPI
+	return d
+}
+
+func TestPublish(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		tags     []string
+		route    int
+		code     codes.Code
+	}{
+		{
+			name:  "first match",
+			tags:  []string{"@ml/special"},
+			route: 0,
+		},
+		{
+			name:  "pattern",
+			tags:  []string{"@ml/model", "@ml/other"},
+			route: 1,
+		},
+		{
+			name:  "default",
+			tags:  []string{"dataset"},
+			route: 2,
+		},
+		{
+			name: "across routes",
+			tags: []string{"@ml/model", "dataset"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name:     "no route",
+			patterns: []string{"@ml/*"},
+			tags:     []string{"dataset"},
+			code:     codes.NotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if testCase.patterns == nil {
+				testCase.patterns = patterns
+			}
+
+			d := newDriver(ctx, t, testCase.patterns)
+
+			request := &datasetv1.PublishRequest{Dataset: &datasetv1.Dataset{BlockSize: 4}}
+			for _, name := range testCase.tags {
+				request.Tags = append(request.Tags, &datasetv1.Tag{Name: name, Version: "v1"})
+			}
+
+			_, err := d.datasetAPI.Publish(ctx, request)
+			require.Equal(t, testCase.code, status.Code(err))
+
+			if testCase.code != codes.OK {
+				for _, datasets := range d.datasets {
+					require.Empty(t, datasets.tags)
+				}
+
+				return
+			}
+
+			for i, datasets := range d.datasets {
+				if i == testCase.route {
+					require.Len(t, datasets.tags, len(testCase.tags))
+				} else {
+					require.Empty(t, datasets.tags)
+				}
+			}
+
+			for _, name := range testCase.tags {
+				_, err := d.datasetAPI.Lookup(ctx, &datasetv1.LookupRequest{Tag: &datasetv1.Tag{Name: name, Version: "v1"}})
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := newDriver(ctx, t, patterns)
+
+	// the default route still holds datasets from before they were routed elsewhere
+	stored := [][]string{
+		{"@ml/special"},
+		{"@ml/model", "@ml/special"},
+		{"dataset", "@ml/model", "@ml/special"},
+	}
+
+	for i, names := range stored {
+		for _, name := range names {
+			d.datasets[i].tags[name+":v1"] = &datasetv1.Dataset{}
+		}
+	}
+
+	resp, err := d.datasetAPI.List(ctx, &datasetv1.ListRequest{})
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(resp.Datasets))
+	for _, ds := range resp.Datasets {
+		names = append(names, ds.Name)
+	}
+
+	sort.Strings(names)
+	require.Equal(t, []string{"@ml/model", "@ml/special", "dataset"}, names)
+}
+
+func TestBlocks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		patterns []string
+		dataset  string         // the dataset the caller says it's working with
+		stored   map[string]int // the route each block is stored on
+
+		// download the block
+		download string
+		data     string
+		code     codes.Code
+
+		// lookup the blocks
+		lookup  []string
+		missing []string
+
+		// upload the block
+		upload string
+		route  int
+	}{
+		{
+			name:     "download from any route",
+			stored:   map[string]int{"a": 2},
+			download: "a",
+			data:     "a",
+		},
+		{
+			name:     "download from the dataset's route",
+			dataset:  "@ml/model",
+			stored:   map[string]int{"a": 2},
+			download: "a",
+			code:     codes.NotFound,
+		},
+		{
+			name:    "lookup across routes",
+			stored:  map[string]int{"a": 0, "b": 2},
+			lookup:  []string{"a", "b", "c"},
+			missing: []string{"c"},
+		},
+		{
+			name:    "lookup on the dataset's route",
+			dataset: "@ml/model",
+			stored:  map[string]int{"a": 1, "b": 2},
+			lookup:  []string{"a", "b"},
+			missing: []string{"b"},
+		},
+		{
+			name:    "upload to the dataset's route",
+			dataset: "@ml/model",
+			upload:  "a",
+			route:   1,
+		},
+		{
+			name:   "upload without a dataset",
+			upload: "a",
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:     "upload without a dataset to the only route",
+			patterns: []string{""},
+			upload:   "a",
+			route:    0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if testCase.patterns == nil {
+				testCase.patterns = patterns
+			}
+
+			d := newDriver(ctx, t, testCase.patterns)
+			for signature, i := range testCase.stored {
+				d.blocks[i].blocks[signature] = []byte(signature)
+			}
+
+			if testCase.dataset != "" {
+				ctx = afs.WithDataset(ctx, testCase.dataset)
+			}
+
+			switch {
+			case testCase.download != "":
+				data := make([]byte, 16)
+
+				n, err := afs.Download(ctx, d.blockAPI, &blockv1.DownloadRequest{Signature: testCase.download}, data)
+				require.Equal(t, testCase.code, status.Code(err))
+				require.Equal(t, testCase.data, string(data[:n]))
+
+			case testCase.lookup != nil:
+				resp, err := d.blockAPI.LookupBatch(ctx, &blockv1.LookupBatchRequest{Signatures: testCase.lookup})
+				require.NoError(t, err)
+				require.Equal(t, testCase.missing, resp.Missing)
+
+			case testCase.upload != "":
+				err := afs.Upload(ctx, d.blockAPI, testCase.upload, []byte(testCase.upload))
+				require.Equal(t, testCase.code, status.Code(err))
+
+				for i, blocks := range d.blocks {
+					_, ok := blocks.blocks[testCase.upload]
+					require.Equal(t, testCase.code == codes.OK && i == testCase.route, ok, i)
+				}
+			}
+		})
+	}
+}
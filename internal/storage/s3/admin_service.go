@@ -0,0 +1,284 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/myago/clocks"
+)
+
+const (
+	brokenMissing   = "missing"
+	brokenSize      = "size"
+	brokenSignature = "signature"
+
+	// listPageSize is the number of objects s3 returns per list request.
+	listPageSize = 1000
+)
+
+// throttle spaces out work so no more than rate units are consumed per second. A rate of zero disables throttling.
+type throttle struct {
+	rate int64
+	next time.Time
+}
+
+// wait blocks until n units can be consumed.
+func (t *throttle) wait(ctx context.Context, n int64) error {
+	if t.rate <= 0 {
+		return nil
+	}
+
+	clock := clocks.Extract(ctx)
+	now := clock.Now()
+
+	if t.next.Before(now) {
+		t.next = now
+	}
+
+	delay := t.next.Sub(now)
+	t.next = t.next.Add(time.Duration(n) * time.Second / time.Duration(t.rate))
+
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clock.After(delay):
+		return nil
+	}
+}
+
+type adminService struct {
+	adminv1.UnsafeAdminAPIServer
+
+	s3Client   *minio.Client
+	bucketName string
+}
+
+// list returns every object under the prefix.
+func (a *adminService) list(ctx context.Context, requests *throttle, prefix string) ([]minio.ObjectInfo, error) {
+	opts := minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}
+
+	// listing is started before the first page can be throttled
+	if err := requests.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	var objects []minio.ObjectInfo
+	for info := range a.s3Client.ListObjects(ctx, a.bucketName, opts) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+
+		objects = append(objects, info)
+
+		if len(objects)%listPageSize == 0 {
+			if err := requests.wait(ctx, 1); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+func (a *adminService) read(ctx context.Context, objectKey string) ([]byte, error) {
+	obj, err := a.s3Client.GetObject(ctx, a.bucketName, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return ioutil.ReadAll(obj)
+}
+
+func (a *adminService) Fsck(ctx context.Context, request *adminv1.FsckRequest) (*adminv1.FsckResponse, error) {
+	if request.RequestsPerSecond < 0 || request.BytesPerSecond < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limits must not be negative")
+	}
+
+	logger := ctxzap.Extract(ctx)
+
+	requests := &throttle{rate: int64(request.RequestsPerSecond)}
+	bandwidth := &throttle{rate: request.BytesPerSecond}
+
+	type manifest struct {
+		dataset *datasetv1.Dataset
+		broken  *adminv1.BrokenDataset
+	}
+
+	resp := &adminv1.FsckResponse{}
+	manifests := make([]*manifest, 0)
+
+	// tags are stored under datasets while every version ever published is stored under manifests by its digest
+	for _, prefix := range []string{"datasets/", "manifests/"} {
+		objects, err := a.list(ctx, requests, prefix)
+		if err != nil {
+			logger.Error("failed to list manifests", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to list manifests")
+		}
+
+		for _, info := range objects {
+			key := strings.TrimPrefix(info.Key, prefix)
+
+			idx := strings.LastIndex(key, "/")
+			if idx < 0 {
+				continue
+			}
+
+			m := &manifest{
+				broken: &adminv1.BrokenDataset{
+					Tag: &datasetv1.Tag{
+						Name:    key[:idx],
+						Version: key[idx+1:],
+					},
+				},
+			}
+
+			manifests = append(manifests, m)
+			resp.Datasets++
+
+			if err := requests.wait(ctx, 1); err != nil {
+				return nil, status.FromContextError(err).Err()
+			}
+
+			data, err := a.read(ctx, info.Key)
+			if err != nil {
+				m.broken.Reason = "failed to read manifest"
+				continue
+			}
+
+			m.dataset = &datasetv1.Dataset{}
+			if err := json.Unmarshal(data, m.dataset); err != nil {
+				m.dataset = nil
+				m.broken.Reason = "failed to unmarshal manifest"
+			}
+		}
+	}
+
+	objects, err := a.list(ctx, requests, "blocks/")
+	if err != nil {
+		logger.Error("failed to list blocks", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list blocks")
+	}
+
+	stored := make(map[string]int64, len(objects))
+	for _, info := range objects {
+		signature := strings.ReplaceAll(strings.TrimPrefix(info.Key, "blocks/"), "/", "")
+		stored[signature] = info.Size
+	}
+
+	referenced := make(map[string]bool)
+	rehashed := make(map[string]string)
+
+	for _, m := range manifests {
+		if m.dataset == nil {
+			resp.Broken = append(resp.Broken, m.broken)
+			continue
+		}
+
+		sizes := dataset.BlockSizes(m.dataset)
+		reported := make(map[string]bool)
+
+		for i, signature := range m.dataset.Blocks {
+			referenced[signature] = true
+
+			size, ok := stored[signature]
+
+			reason := ""
+			switch {
+			case !ok:
+				reason = brokenMissing
+			case size != sizes[i]:
+				reason = brokenSize
+			case request.Rehash:
+				if _, ok := rehashed[signature]; !ok {
+					rehashed[signature], err = a.rehash(ctx, requests, bandwidth, signature, size)
+					if err != nil {
+						return nil, status.FromContextError(err).Err()
+					}
+				}
+
+				reason = rehashed[signature]
+			}
+
+			if reason != "" && !reported[signature] {
+				reported[signature] = true
+				m.broken.Blocks = append(m.broken.Blocks, &adminv1.BrokenBlock{
+					Signature: signature,
+					Reason:    reason,
+				})
+			}
+		}
+
+		if len(m.broken.Blocks) > 0 {
+			resp.Broken = append(resp.Broken, m.broken)
+		}
+	}
+
+	resp.Blocks = int64(len(referenced))
+
+	for signature, size := range stored {
+		if !referenced[signature] {
+			resp.Orphaned = append(resp.Orphaned, &adminv1.OrphanedBlock{
+				Signature: signature,
+				Size:      size,
+			})
+		}
+	}
+
+	sort.Slice(resp.Orphaned, func(i, j int) bool {
+		return resp.Orphaned[i].Signature < resp.Orphaned[j].Signature
+	})
+
+	return resp, nil
+}
+
+// rehash downloads the block and recomputes its signature. The reason the block is broken is returned, or an empty
+// string when it's intact. Errors are only returned when the context is cancelled.
+func (a *adminService) rehash(ctx context.Context, requests, bandwidth *throttle, signature string, size int64) (string, error) {
+	if err := requests.wait(ctx, 1); err != nil {
+		return "", err
+	}
+
+	if err := bandwidth.wait(ctx, size); err != nil {
+		return "", err
+	}
+
+	data, err := a.read(ctx, "blocks/"+signature[0:2]+"/"+signature[2:])
+	switch {
+	case ctx.Err() != nil:
+		return "", ctx.Err()
+	case err != nil:
+		ctxzap.Extract(ctx).Error("failed to read block", zap.String("signature", signature), zap.Error(err))
+		return brokenMissing, nil
+	case blocks.VerifySignature(signature, data) != nil:
+		return brokenSignature, nil
+	}
+
+	return "", nil
+}
+
+var _ adminv1.AdminAPIServer = &adminService{}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -23,6 +24,9 @@ import (
 	"github.com/mjpitz/aetherfs/internal/headers"
 )
 
+// lookupConcurrency bounds the number of concurrent stat calls issued to s3 by a single LookupBatch call.
+const lookupConcurrency = 16
+
 type blockService struct {
 	blockv1.UnsafeBlockAPIServer
 
@@ -30,6 +34,11 @@ type blockService struct {
 	bucketName string
 }
 
+// notFound returns true when s3 reports that the object does not exist.
+func notFound(err error) bool {
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
+
 func (b *blockService) Lookup(ctx context.Context, request *blockv1.LookupRequest) (*blockv1.LookupResponse, error) {
 	objectKey := "blocks/" + request.Signature[0:2] + "/" + request.Signature[2:]
 
@@ -48,6 +57,61 @@ func (b *blockService) Lookup(ctx context.Context, request *blockv1.LookupReques
 	return &blockv1.LookupResponse{}, nil
 }
 
+func (b *blockService) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	if len(request.Signatures) > blocks.LookupBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "too many signatures, max %d", blocks.LookupBatchSize)
+	}
+
+	for _, signature := range request.Signatures {
+		if len(signature) < 3 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid signature: %s", signature)
+		}
+	}
+
+	missing := make([]bool, len(request.Signatures))
+
+	// stat objects concurrently, but keep the number of in-flight requests to s3 bounded
+	limit := make(chan struct{}, lookupConcurrency)
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	for i, signature := range request.Signatures {
+		i, signature := i, signature
+
+		limit <- struct{}{}
+		group.Go(func() error {
+			defer func() { <-limit }()
+
+			_, err := b.Lookup(groupCtx, &blockv1.LookupRequest{
+				Signature: signature,
+			})
+
+			switch status.Code(err) {
+			case codes.OK:
+			case codes.NotFound:
+				missing[i] = true
+			default:
+				return err
+			}
+
+			return nil
+		})
+	}
+
+	err := group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &blockv1.LookupBatchResponse{}
+	for i, signature := range request.Signatures {
+		if missing[i] {
+			resp.Missing = append(resp.Missing, signature)
+		}
+	}
+
+	return resp, nil
+}
+
 func (b *blockService) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
 	logger := ctxzap.Extract(call.Context())
 
@@ -61,6 +125,10 @@ func (b *blockService) Download(request *blockv1.DownloadRequest, call blockv1.B
 
 	_, err = resp.Seek(request.Offset, io.SeekStart)
 	if err != nil {
+		if notFound(err) {
+			return status.Errorf(codes.NotFound, "not found")
+		}
+
 		logger.Error("seek failed", zap.Error(err))
 		return status.Errorf(codes.Internal, "internal server error")
 	}
@@ -68,26 +136,41 @@ func (b *blockService) Download(request *blockv1.DownloadRequest, call blockv1.B
 	// read 64KB blocks from resp until request.Size || the remaining file is read is read
 	// this should be the same same cache size
 	remaining := request.Size
+	if remaining <= 0 {
+		// a size of 0 reads the remainder of the block
+		remaining = math.MaxInt64
+	}
 
-	part := make([]byte, 0, blocks.PartSize)
+	part := make([]byte, blocks.PartSize)
 	for remaining > 0 {
 		length := int(math.Min(float64(blocks.PartSize), float64(remaining)))
 
-		_, err := resp.Read(part[:length])
-		if err != nil && err != io.EOF {
+		n, err := io.ReadFull(resp, part[:length])
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			if notFound(err) {
+				return status.Errorf(codes.NotFound, "not found")
+			}
+
 			logger.Error("read failed", zap.Error(err))
 			return status.Errorf(codes.Internal, "internal server error")
 		}
 
-		err = call.Send(&blockv1.DownloadResponse{
-			Part: part[:length],
-		})
-		if err != nil {
-			logger.Error("send failed", zap.Error(err))
-			return status.Errorf(codes.Internal, "")
+		if n > 0 {
+			err := call.Send(&blockv1.DownloadResponse{
+				Part: part[:n],
+			})
+			if err != nil {
+				logger.Error("send failed", zap.Error(err))
+				return status.Errorf(codes.Internal, "")
+			}
+		}
+
+		if n < length {
+			// reached the end of the block
+			break
 		}
 
-		remaining -= int64(length)
+		remaining -= int64(n)
 	}
 
 	return nil
@@ -141,4 +224,22 @@ func (b *blockService) Upload(call blockv1.BlockAPI_UploadServer) error {
 	return nil
 }
 
+// Delete removes the block from the bucket. It's not part of the block api and is only used by drivers that move blocks
+// between stores.
+func (b *blockService) Delete(ctx context.Context, signature string) error {
+	if len(signature) < 3 {
+		return status.Errorf(codes.InvalidArgument, "invalid signature: %s", signature)
+	}
+
+	objectKey := "blocks/" + signature[0:2] + "/" + signature[2:]
+
+	err := b.s3Client.RemoveObject(ctx, b.bucketName, objectKey, minio.RemoveObjectOptions{})
+	if err != nil {
+		ctxzap.Extract(ctx).Error("failed to remove object", zap.Error(err))
+		return status.Errorf(codes.Internal, "internal server error")
+	}
+
+	return nil
+}
+
 var _ blockv1.BlockAPIServer = &blockService{}
@@ -0,0 +1,120 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package s3_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/storage/s3"
+)
+
+// bucket serves just enough of the s3 api to create a bucket and stat the objects within it.
+type bucket struct {
+	objects map[string]bool
+}
+
+func (b *bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+
+	switch {
+	case len(key) == 1 || key[1] == "":
+		// bucket creation and existence checks
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodHead && b.objects[key[1]]:
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Length", "0")
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestLookupBatch(t *testing.T) {
+	ctx := context.Background()
+
+	present := []string{"aa01", "bb02", "cc03"}
+	objects := make(map[string]bool)
+	for _, signature := range present {
+		objects["blocks/"+signature[0:2]+"/"+signature[2:]] = true
+	}
+
+	server := httptest.NewServer(&bucket{objects: objects})
+	defer server.Close()
+
+	blockAPI, _, _, err := s3.ObtainStores(ctx, s3.Config{
+		Endpoint: strings.TrimPrefix(server.URL, "http://"),
+		Region:   "us-east-1",
+		Bucket:   "aetherfs",
+	})
+	require.NoError(t, err)
+
+	tooMany := make([]string, blocks.LookupBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%04d", i)
+	}
+
+	testCases := []struct {
+		name       string
+		signatures []string
+		missing    []string
+		code       codes.Code
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:       "all present",
+			signatures: present,
+		},
+		{
+			name:       "some missing",
+			signatures: []string{"aa01", "dd04", "bb02", "ee05"},
+			missing:    []string{"dd04", "ee05"},
+		},
+		{
+			name:       "at the limit",
+			signatures: tooMany[:blocks.LookupBatchSize],
+			missing:    tooMany[:blocks.LookupBatchSize],
+		},
+		{
+			name:       "over the limit",
+			signatures: tooMany,
+			code:       codes.InvalidArgument,
+		},
+		{
+			name:       "invalid signature",
+			signatures: []string{"aa01", "a"},
+			code:       codes.InvalidArgument,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			resp, err := blockAPI.LookupBatch(ctx, &blockv1.LookupBatchRequest{
+				Signatures: testCase.signatures,
+			})
+
+			if testCase.code != codes.OK {
+				require.Equal(t, testCase.code, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.missing, resp.Missing)
+		})
+	}
+}
@@ -13,24 +13,29 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/pkg/errors"
 
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
 	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
 	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
 	"github.com/mjpitz/myago/livetls"
 )
 
+// Config describes the bucket datasets are stored in. S3 can't conditionally write objects, so the tags updated by a
+// publish are only checked and committed atomically within a single hub. Hubs sharing a bucket can overwrite each
+// other's tags, and a hub that crashes part way through a publish can leave some of its tags updated. Only one hub
+// should publish to a bucket at a time.
 type Config struct {
 	Endpoint        string         `json:"endpoint"          usage:"location of s3 endpoint" default:"s3.amazonaws.com"`
 	TLS             livetls.Config `json:"tls"`
 	AccessKeyID     string         `json:"access_key_id"     usage:"the access key id used to identify the client"`
 	SecretAccessKey string         `json:"secret_access_key" usage:"the secret access key used to authenticate the client"`
 	Region          string         `json:"region"            usage:"the region where the bucket exists"`
-	Bucket          string         `json:"bucket"            usage:"the name of the bucket to use"`
+	Bucket          string         `json:"bucket"            usage:"the name of the bucket to use, only one hub should publish to it at a time"`
 }
 
-func ObtainStores(ctx context.Context, cfg Config) (blockv1.BlockAPIServer, datasetv1.DatasetAPIServer, error) {
+func ObtainStores(ctx context.Context, cfg Config) (blockv1.BlockAPIServer, datasetv1.DatasetAPIServer, adminv1.AdminAPIServer, error) {
 	tls, err := livetls.New(ctx, cfg.TLS)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	var rt http.RoundTripper
@@ -75,13 +80,13 @@ func ObtainStores(ctx context.Context, cfg Config) (blockv1.BlockAPIServer, data
 	})
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	err = s3Client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{})
 	exists, _ := s3Client.BucketExists(ctx, cfg.Bucket)
 	if !exists {
-		return nil, nil, errors.Wrap(err, "bucket does not exist")
+		return nil, nil, nil, errors.Wrap(err, "bucket does not exist")
 	}
 
 	blockSvc := &blockService{
@@ -92,7 +97,13 @@ func ObtainStores(ctx context.Context, cfg Config) (blockv1.BlockAPIServer, data
 	datasetSvc := &datasetService{
 		s3Client:   s3Client,
 		bucketName: cfg.Bucket,
+		blocks:     blockSvc,
 	}
 
-	return blockSvc, datasetSvc, nil
+	adminSvc := &adminService{
+		s3Client:   s3Client,
+		bucketName: cfg.Bucket,
+	}
+
+	return blockSvc, datasetSvc, adminSvc, nil
 }
@@ -9,12 +9,16 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"strings"
+	"sync"
 
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/minio/minio-go/v7"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
 )
 
 type datasetService struct {
@@ -22,6 +26,10 @@ type datasetService struct {
 
 	s3Client   *minio.Client
 	bucketName string
+	blocks     *blockService
+
+	// commit serializes tag updates so concurrent publishes can't interleave
+	commit sync.Mutex
 }
 
 func (d *datasetService) List(ctx context.Context, request *datasetv1.ListRequest) (*datasetv1.ListResponse, error) {
@@ -95,43 +103,160 @@ func (d *datasetService) ListTags(ctx context.Context, request *datasetv1.ListTa
 }
 
 func (d *datasetService) Lookup(ctx context.Context, request *datasetv1.LookupRequest) (*datasetv1.LookupResponse, error) {
-	objectKey := "datasets/" + request.Tag.Name + "/" + request.Tag.Version
+	var data []byte
+	var err error
 
-	obj, err := d.s3Client.GetObject(ctx, d.bucketName, objectKey, minio.GetObjectOptions{})
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to lookup dataset")
+	if dataset.IsDigest(request.Tag.Version) {
+		data, err = d.readManifest(ctx, request.Tag.Name, request.Tag.Version)
+	} else {
+		data, err = d.readTag(ctx, request.Tag)
 	}
 
-	data, err := ioutil.ReadAll(obj)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to read dataset")
+	switch {
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to lookup dataset")
+	case data == nil:
+		return nil, status.Errorf(codes.NotFound, "dataset not found")
 	}
 
-	dataset := &datasetv1.Dataset{}
+	ds := &datasetv1.Dataset{}
 
-	err = json.Unmarshal(data, dataset)
+	err = json.Unmarshal(data, ds)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to unmarshal dataset")
 	}
 
 	return &datasetv1.LookupResponse{
-		Dataset: dataset,
+		Dataset: ds,
+		Digest:  dataset.Digest(data),
 	}, nil
 }
 
+func (d *datasetService) Diff(ctx context.Context, request *datasetv1.DiffRequest) (*datasetv1.DiffResponse, error) {
+	if request.Base == nil || request.Target == nil {
+		return nil, status.Error(codes.InvalidArgument, "base and target are required")
+	}
+
+	base, err := d.Lookup(ctx, &datasetv1.LookupRequest{Tag: request.Base})
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := d.Lookup(ctx, &datasetv1.LookupRequest{Tag: request.Target})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := dataset.Diff(base.Dataset, target.Dataset)
+	resp.BaseDigest = base.Digest
+	resp.TargetDigest = target.Digest
+
+	return resp, nil
+}
+
 func (d *datasetService) Publish(ctx context.Context, request *datasetv1.PublishRequest) (*datasetv1.PublishResponse, error) {
+	err := dataset.Validate(request.Dataset)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var missing []string
+	if !dataset.BlocksVerified(ctx) {
+		missing, err = dataset.MissingBlocks(ctx, d.blocks, request.Dataset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, dataset.MissingBlocksError(missing)
+	}
+
+	// tags are committed in request order, skipping any duplicates
+	tags := make([]*datasetv1.Tag, 0, len(request.Tags))
+	requested := make(map[string]bool, len(request.Tags))
+
+	for _, tag := range request.Tags {
+		if dataset.IsDigest(tag.Version) {
+			return nil, status.Errorf(codes.InvalidArgument, "tag version cannot be a digest: %s", tag.Version)
+		}
+
+		if !requested[tagKey(tag)] {
+			requested[tagKey(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for key, digest := range request.ExpectedPrevious {
+		switch {
+		case !requested[key]:
+			return nil, status.Errorf(codes.InvalidArgument, "expected previous digest provided for a tag that isn't being published: %s", key)
+		case digest != "" && !dataset.IsDigest(digest):
+			return nil, status.Errorf(codes.InvalidArgument, "invalid expected previous digest for %s: %s", key, digest)
+		}
+	}
+
 	data, err := json.Marshal(request.Dataset)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to marshal dataset")
 	}
 
-	for _, tag := range request.Tags {
-		objectKey := "datasets/" + tag.Name + "/" + tag.Version
+	// manifests are stored by their digest so they can be resolved even after the tags move on. they're immutable, so
+	// writing them before the tags are committed is safe even when the publish fails.
+	digest := dataset.Digest(data)
+	written := make(map[string]bool)
+
+	for _, tag := range tags {
+		if written[tag.Name] {
+			continue
+		}
+
+		objectKey := "manifests/" + tag.Name + "/" + digest
 
 		_, err = d.s3Client.PutObject(ctx, d.bucketName, objectKey,
 			bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{})
 
 		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to write manifest")
+		}
+
+		written[tag.Name] = true
+	}
+
+	// s3 can't conditionally write objects, so tags are committed one publish at a time. this only holds within this
+	// process, see Config for the limitations this places on deployments.
+	d.commit.Lock()
+	defer d.commit.Unlock()
+
+	previous := make([][]byte, len(tags))
+	for i, tag := range tags {
+		previous[i], err = d.readTag(ctx, tag)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to read tag")
+		}
+
+		expected, ok := request.ExpectedPrevious[tagKey(tag)]
+		if !ok {
+			continue
+		}
+
+		actual := ""
+		if previous[i] != nil {
+			actual = dataset.Digest(previous[i])
+		}
+
+		if actual != expected {
+			return nil, status.Errorf(codes.FailedPrecondition, "tag %s has moved: expected %s, found %s",
+				tagKey(tag), describeDigest(expected), describeDigest(actual))
+		}
+	}
+
+	for i, tag := range tags {
+		err = d.writeTag(ctx, tag, data)
+		if err != nil {
+			ctxzap.Extract(ctx).Error("failed to write tag, rolling back", zap.String("tag", tagKey(tag)), zap.Error(err))
+			d.rollback(ctx, tags[:i], previous[:i])
+
 			return nil, status.Errorf(codes.Internal, "failed to write tag")
 		}
 	}
@@ -139,6 +264,118 @@ func (d *datasetService) Publish(ctx context.Context, request *datasetv1.Publish
 	return &datasetv1.PublishResponse{}, nil
 }
 
+// tagKey formats the tag as name:version.
+func tagKey(tag *datasetv1.Tag) string {
+	return tag.Name + ":" + tag.Version
+}
+
+// describeDigest formats the digest a tag points at for error messages.
+func describeDigest(digest string) string {
+	if digest == "" {
+		return "no dataset"
+	}
+
+	return digest
+}
+
+// readObject returns the contents of the object, or nil when the object does not exist.
+func (d *datasetService) readObject(ctx context.Context, objectKey string) ([]byte, error) {
+	obj, err := d.s3Client.GetObject(ctx, d.bucketName, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	data, err := ioutil.ReadAll(obj)
+	switch {
+	case minio.ToErrorResponse(err).Code == "NoSuchKey":
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// readTag returns the manifest the tag currently points at, or nil when the tag does not exist.
+func (d *datasetService) readTag(ctx context.Context, tag *datasetv1.Tag) ([]byte, error) {
+	return d.readObject(ctx, "datasets/"+tag.Name+"/"+tag.Version)
+}
+
+// readManifest returns the manifest of the dataset with the digest, or nil when there isn't one. Manifests are only
+// stored by their digest when they're published, so the tags published before then are searched instead. Once found,
+// the manifest is stored by its digest so later lookups don't need to search again.
+func (d *datasetService) readManifest(ctx context.Context, name, digest string) ([]byte, error) {
+	objectKey := "manifests/" + name + "/" + digest
+
+	data, err := d.readObject(ctx, objectKey)
+	if err != nil || data != nil {
+		return data, err
+	}
+
+	opts := minio.ListObjectsOptions{
+		Prefix: "datasets/" + name + "/",
+	}
+
+	// stop listing once the manifest is found
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for info := range d.s3Client.ListObjects(listCtx, d.bucketName, opts) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+
+		data, err := d.readObject(ctx, info.Key)
+		switch {
+		case err != nil:
+			return nil, err
+		case data == nil || dataset.Digest(data) != digest:
+			continue
+		}
+
+		_, err = d.s3Client.PutObject(ctx, d.bucketName, objectKey,
+			bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{})
+
+		if err != nil {
+			// the manifest was still found, the next lookup will search for it again
+			ctxzap.Extract(ctx).Warn("failed to store manifest by digest", zap.String("key", objectKey), zap.Error(err))
+		}
+
+		return data, nil
+	}
+
+	return nil, nil
+}
+
+func (d *datasetService) writeTag(ctx context.Context, tag *datasetv1.Tag, data []byte) error {
+	_, err := d.s3Client.PutObject(ctx, d.bucketName, "datasets/"+tag.Name+"/"+tag.Version,
+		bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{})
+
+	return err
+}
+
+// rollback restores the tags to the manifests they pointed at before the publish, removing the ones that didn't exist.
+func (d *datasetService) rollback(ctx context.Context, tags []*datasetv1.Tag, previous [][]byte) {
+	logger := ctxzap.Extract(ctx)
+
+	// the request may have been cancelled, but the tags still need to be restored
+	ctx = context.Background()
+
+	for i, tag := range tags {
+		var err error
+		if previous[i] == nil {
+			err = d.s3Client.RemoveObject(ctx, d.bucketName, "datasets/"+tag.Name+"/"+tag.Version, minio.RemoveObjectOptions{})
+		} else {
+			err = d.writeTag(ctx, tag, previous[i])
+		}
+
+		if err != nil {
+			logger.Error("failed to roll back tag", zap.String("tag", tagKey(tag)), zap.Error(err))
+		}
+	}
+}
+
 func (d *datasetService) Subscribe(call datasetv1.DatasetAPI_SubscribeServer) error {
 	return status.Errorf(codes.Unimplemented, "unimplemented")
 }
@@ -0,0 +1,232 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package s3_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+	"github.com/mjpitz/aetherfs/internal/storage/s3"
+)
+
+// listing is the response to a ListObjectsV2 request.
+type listing struct {
+	XMLName        xml.Name `xml:"ListBucketResult"`
+	Name           string
+	Prefix         string
+	Delimiter      string
+	KeyCount       int
+	MaxKeys        int
+	IsTruncated    bool
+	Contents       []listed
+	CommonPrefixes []struct{ Prefix string }
+}
+
+type listed struct {
+	Key          string
+	Size         int
+	ETag         string
+	LastModified string
+}
+
+// objectStore serves just enough of the s3 api to read, write, and list the objects in a bucket.
+type objectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (o *objectStore) object(key string) ([]byte, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	data, ok := o.objects[key]
+	return data, ok
+}
+
+func (o *objectStore) list(bucket, prefix, delimiter string) *listing {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	resp := &listing{Name: bucket, Prefix: prefix, Delimiter: delimiter, MaxKeys: 1000}
+	prefixes := make(map[string]bool)
+
+	keys := make([]string, 0, len(o.objects))
+	for key := range o.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if i := strings.Index(key[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			common := key[:len(prefix)+i+1]
+			if !prefixes[common] {
+				prefixes[common] = true
+				resp.CommonPrefixes = append(resp.CommonPrefixes, struct{ Prefix string }{common})
+			}
+
+			continue
+		}
+
+		resp.Contents = append(resp.Contents, listed{
+			Key:          key,
+			Size:         len(o.objects[key]),
+			ETag:         `"etag"`,
+			LastModified: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+
+	resp.KeyCount = len(resp.Contents) + len(resp.CommonPrefixes)
+	return resp
+}
+
+func (o *objectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+
+	switch {
+	case (len(key) == 1 || key[1] == "") && r.URL.Query().Get("list-type") == "2":
+		query := r.URL.Query()
+
+		w.Header().Set("Content-Type", "application/xml")
+		_ = xml.NewEncoder(w).Encode(o.list(key[0], query.Get("prefix"), query.Get("delimiter")))
+
+	case len(key) == 1 || key[1] == "":
+		// bucket creation and existence checks
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+
+		o.mu.Lock()
+		o.objects[key[1]] = data
+		o.mu.Unlock()
+
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := o.object(key[1])
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(data)
+		}
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestLookupByDigest(t *testing.T) {
+	manifest := func(name string) []byte {
+		data, err := json.Marshal(&datasetv1.Dataset{
+			BlockSize: 4,
+			Files:     []*datasetv1.File{{Name: name}},
+		})
+		require.NoError(t, err)
+
+		return data
+	}
+
+	v1 := manifest("v1.txt")
+	v2 := manifest("v2.txt")
+
+	testCases := []struct {
+		name     string
+		dataset  string
+		objects  map[string][]byte
+		digest   string
+		code     codes.Code
+		manifest bool // the manifest is stored by its digest afterwards
+	}{
+		{
+			name:     "stored by digest",
+			dataset:  "ds",
+			objects:  map[string][]byte{"datasets/ds/v2": v2, "manifests/ds/" + dataset.Digest(v1): v1},
+			digest:   dataset.Digest(v1),
+			manifest: true,
+		},
+		{
+			name:     "published before manifests were stored by digest",
+			dataset:  "ds",
+			objects:  map[string][]byte{"datasets/ds/v1": v1, "datasets/ds/v2": v2},
+			digest:   dataset.Digest(v1),
+			manifest: true,
+		},
+		{
+			name:     "scoped",
+			dataset:  "@ml/model",
+			objects:  map[string][]byte{"datasets/@ml/model/v1": v1},
+			digest:   dataset.Digest(v1),
+			manifest: true,
+		},
+		{
+			name:    "unknown digest",
+			dataset: "ds",
+			objects: map[string][]byte{"datasets/ds/v2": v2},
+			digest:  dataset.Digest(v1),
+			code:    codes.NotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			store := &objectStore{objects: testCase.objects}
+
+			server := httptest.NewServer(store)
+			defer server.Close()
+
+			_, datasetAPI, _, err := s3.ObtainStores(ctx, s3.Config{
+				Endpoint: strings.TrimPrefix(server.URL, "http://"),
+				Region:   "us-east-1",
+				Bucket:   "aetherfs",
+			})
+			require.NoError(t, err)
+
+			resp, err := datasetAPI.Lookup(ctx, &datasetv1.LookupRequest{
+				Tag: &datasetv1.Tag{Name: testCase.dataset, Version: testCase.digest},
+			})
+			require.Equal(t, testCase.code, status.Code(err))
+
+			_, stored := store.object("manifests/" + testCase.dataset + "/" + testCase.digest)
+			require.Equal(t, testCase.manifest, stored)
+
+			if testCase.code != codes.OK {
+				return
+			}
+
+			require.Equal(t, testCase.digest, resp.Digest)
+			require.Equal(t, "v1.txt", resp.Dataset.Files[0].Name)
+		})
+	}
+}
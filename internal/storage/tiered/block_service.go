@@ -0,0 +1,178 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tiered
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+)
+
+type blockService struct {
+	blockv1.UnsafeBlockAPIServer
+
+	// ctx outlives any single request and is used to promote blocks in the background
+	ctx     context.Context
+	hot     blockv1.BlockAPIClient
+	cold    blockv1.BlockAPIClient
+	tracker *tracker
+	promote bool
+
+	promoting sync.Map
+}
+
+// read returns the entire block from the client, verifying its signature.
+func read(ctx context.Context, client blockv1.BlockAPIClient, signature string) ([]byte, error) {
+	stream, err := client.Download(ctx, &blockv1.DownloadRequest{Signature: signature})
+	if err != nil {
+		return nil, err
+	}
+
+	data := &bytes.Buffer{}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		data.Write(resp.Part)
+	}
+
+	err = blocks.VerifySignature(signature, data.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Bytes(), nil
+}
+
+// accessed records the read of the block. Failing to do so shouldn't fail the read, it only delays when it's demoted.
+func (b *blockService) accessed(ctx context.Context, signature string, cold bool) {
+	err := b.tracker.accessed(ctx, signature, cold)
+	if err != nil {
+		ctxzap.Extract(ctx).Error("failed to record block access", zap.String("signature", signature), zap.Error(err))
+	}
+}
+
+func (b *blockService) Lookup(ctx context.Context, request *blockv1.LookupRequest) (*blockv1.LookupResponse, error) {
+	resp, err := b.hot.Lookup(ctx, request)
+	if status.Code(err) != codes.NotFound {
+		return resp, err
+	}
+
+	return b.cold.Lookup(ctx, request)
+}
+
+func (b *blockService) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	resp, err := b.hot.LookupBatch(ctx, request)
+	if err != nil || len(resp.Missing) == 0 {
+		return resp, err
+	}
+
+	return b.cold.LookupBatch(ctx, &blockv1.LookupBatchRequest{Signatures: resp.Missing})
+}
+
+// Download reads the block from the hot tier, falling back to the cold tier for blocks that have been demoted. Blocks
+// read from the cold tier are copied back to the hot tier in the background when promotion is enabled.
+func (b *blockService) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
+	ctx := call.Context()
+
+	sent, err := inprocess.Forward(b.hot, request, call)
+	switch {
+	case err == nil:
+		b.accessed(ctx, request.Signature, false)
+		return nil
+	case ctx.Err() != nil:
+		return err
+	case status.Code(err) != codes.NotFound:
+		ctxzap.Extract(ctx).Warn("failed to download block from the hot tier, trying the cold tier",
+			zap.String("signature", request.Signature), zap.Error(err))
+	}
+
+	next := &blockv1.DownloadRequest{
+		Signature: request.Signature,
+		Offset:    request.Offset + sent,
+	}
+
+	if request.Size > 0 {
+		next.Size = request.Size - sent
+	}
+
+	_, err = inprocess.Forward(b.cold, next, call)
+	if err != nil {
+		return err
+	}
+
+	b.accessed(ctx, request.Signature, true)
+
+	if b.promote {
+		go b.promoteBlock(request.Signature)
+	}
+
+	return nil
+}
+
+// promoteBlock copies the block from the cold tier back to the hot tier. Blocks pinned to the cold tier are left there.
+func (b *blockService) promoteBlock(signature string) {
+	if _, loaded := b.promoting.LoadOrStore(signature, true); loaded {
+		return
+	}
+	defer b.promoting.Delete(signature)
+
+	logger := ctxzap.Extract(b.ctx).With(zap.String("signature", signature))
+
+	record, err := b.tracker.block(b.ctx, signature)
+	switch {
+	case err != nil:
+		logger.Error("failed to read block record", zap.Error(err))
+		return
+	case record != nil && (record.Pinned || !record.Cold):
+		return
+	}
+
+	data, err := read(b.ctx, b.cold, signature)
+	if err != nil {
+		logger.Error("failed to read block for promotion", zap.Error(err))
+		return
+	}
+
+	err = afs.Upload(b.ctx, b.hot, signature, data)
+	if err != nil {
+		logger.Error("failed to promote block", zap.Error(err))
+		return
+	}
+
+	err = b.tracker.accessed(b.ctx, signature, false)
+	if err != nil {
+		logger.Error("failed to record block promotion", zap.Error(err))
+		return
+	}
+
+	logger.Info("promoted block to the hot tier")
+}
+
+// Upload writes new blocks to the hot tier.
+func (b *blockService) Upload(call blockv1.BlockAPI_UploadServer) error {
+	signature, err := inprocess.ForwardUpload(b.hot, call)
+	if err == nil || status.Code(err) == codes.AlreadyExists {
+		b.accessed(call.Context(), signature, false)
+	}
+
+	return err
+}
+
+var _ blockv1.BlockAPIServer = &blockService{}
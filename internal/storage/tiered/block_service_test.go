@@ -0,0 +1,438 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tiered
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/headers"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+)
+
+// blockServer keeps blocks in memory. Downloads are sent a byte at a time so they can fail part way through.
+type blockServer struct {
+	blockv1.UnimplementedBlockAPIServer
+
+	mu     sync.Mutex
+	blocks map[string][]byte
+
+	// failAfter fails downloads once that many bytes have been sent, when positive
+	failAfter int
+	// uploading is called before an uploaded block is stored
+	uploading func(signature string)
+}
+
+func (b *blockServer) has(signature string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.blocks[signature]
+	return ok
+}
+
+func (b *blockServer) Lookup(ctx context.Context, request *blockv1.LookupRequest) (*blockv1.LookupResponse, error) {
+	if !b.has(request.Signature) {
+		return nil, status.Error(codes.NotFound, "block not found")
+	}
+
+	return &blockv1.LookupResponse{}, nil
+}
+
+func (b *blockServer) LookupBatch(ctx context.Context, request *blockv1.LookupBatchRequest) (*blockv1.LookupBatchResponse, error) {
+	resp := &blockv1.LookupBatchResponse{}
+	for _, signature := range request.Signatures {
+		if !b.has(signature) {
+			resp.Missing = append(resp.Missing, signature)
+		}
+	}
+
+	return resp, nil
+}
+
+func (b *blockServer) Download(request *blockv1.DownloadRequest, call blockv1.BlockAPI_DownloadServer) error {
+	b.mu.Lock()
+	data, ok := b.blocks[request.Signature]
+	failAfter := b.failAfter
+	b.mu.Unlock()
+
+	if !ok {
+		return status.Error(codes.NotFound, "block not found")
+	}
+
+	data = data[request.Offset:]
+	if request.Size > 0 && request.Size < int64(len(data)) {
+		data = data[:request.Size]
+	}
+
+	for i := range data {
+		if failAfter > 0 && i == failAfter {
+			return status.Error(codes.Unavailable, "connection lost")
+		}
+
+		if err := call.Send(&blockv1.DownloadResponse{Part: data[i : i+1]}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *blockServer) Upload(call blockv1.BlockAPI_UploadServer) error {
+	md, _ := metadata.FromIncomingContext(call.Context())
+	signature := md.Get(headers.AetherFSBlockSignature)[0]
+
+	data := &bytes.Buffer{}
+	for {
+		req, err := call.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		data.Write(req.Part)
+	}
+
+	if b.uploading != nil {
+		b.uploading(signature)
+	}
+
+	b.mu.Lock()
+	b.blocks[signature] = data.Bytes()
+	b.mu.Unlock()
+
+	return call.SendAndClose(&blockv1.UploadResponse{Signature: signature})
+}
+
+func (b *blockServer) Delete(ctx context.Context, signature string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.blocks, signature)
+	return nil
+}
+
+// datasetServer keeps manifests in memory, keyed by name:version.
+type datasetServer struct {
+	datasetv1.UnimplementedDatasetAPIServer
+
+	mu   sync.Mutex
+	tags map[string]*datasetv1.Dataset
+}
+
+func (d *datasetServer) List(ctx context.Context, request *datasetv1.ListRequest) (*datasetv1.ListResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := make(map[string]bool)
+	resp := &datasetv1.ListResponse{}
+
+	for tag := range d.tags {
+		name := strings.SplitN(tag, ":", 2)[0]
+		if !names[name] {
+			names[name] = true
+			resp.Datasets = append(resp.Datasets, &datasetv1.Tag{Name: name})
+		}
+	}
+
+	return resp, nil
+}
+
+func (d *datasetServer) ListTags(ctx context.Context, request *datasetv1.ListTagsRequest) (*datasetv1.ListTagsResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	resp := &datasetv1.ListTagsResponse{}
+	for tag := range d.tags {
+		parts := strings.SplitN(tag, ":", 2)
+		if parts[0] == request.Name {
+			resp.Tags = append(resp.Tags, &datasetv1.Tag{Name: parts[0], Version: parts[1]})
+		}
+	}
+
+	return resp, nil
+}
+
+func (d *datasetServer) Lookup(ctx context.Context, request *datasetv1.LookupRequest) (*datasetv1.LookupResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ds, ok := d.tags[request.Tag.Name+":"+request.Tag.Version]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "tag not found")
+	}
+
+	return &datasetv1.LookupResponse{Dataset: ds}, nil
+}
+
+func (d *datasetServer) Publish(ctx context.Context, request *datasetv1.PublishRequest) (*datasetv1.PublishResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, tag := range request.Tags {
+		d.tags[tag.Name+":"+tag.Version] = request.Dataset
+	}
+
+	return &datasetv1.PublishResponse{}, nil
+}
+
+// tiers wires the driver to in-memory hot and cold tiers the same way ObtainStores does, without starting the mover.
+type tiers struct {
+	clock    clockwork.FakeClock
+	hot      *blockServer
+	cold     *blockServer
+	blocks   *blockService
+	datasets *datasetService
+	mover    *mover
+
+	// client reads and writes blocks through the driver
+	client blockv1.BlockAPIClient
+}
+
+func newTiers(ctx context.Context, t *testing.T, cfg Config) *tiers {
+	db, err := local.Open(ctx, t.TempDir(), "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	hot := &blockServer{blocks: make(map[string][]byte)}
+	cold := &blockServer{blocks: make(map[string][]byte)}
+	hotDatasets := &datasetServer{tags: make(map[string]*datasetv1.Dataset)}
+
+	hotClient, err := inprocess.BlockAPI(ctx, hot)
+	require.NoError(t, err)
+
+	coldClient, err := inprocess.BlockAPI(ctx, cold)
+	require.NoError(t, err)
+
+	clock := clockwork.NewFakeClockAt(time.Date(2022, 1, 31, 0, 0, 0, 0, time.UTC))
+	tracker := &tracker{clock: clock, store: db.Tiers()}
+
+	blockSvc := &blockService{ctx: ctx, hot: hotClient, cold: coldClient, tracker: tracker, promote: cfg.Promote}
+
+	client, err := inprocess.BlockAPI(ctx, blockSvc)
+	require.NoError(t, err)
+
+	return &tiers{
+		clock:    clock,
+		hot:      hot,
+		cold:     cold,
+		blocks:   blockSvc,
+		datasets: &datasetService{hot: hotDatasets, blocks: blockSvc, tracker: tracker},
+		mover: &mover{
+			config:   cfg,
+			hot:      hotClient,
+			deleter:  hot,
+			cold:     coldClient,
+			datasets: hotDatasets,
+			tracker:  tracker,
+		},
+		client: client,
+	}
+}
+
+// download reads the requested range of the block in a single call, without retrying.
+func download(ctx context.Context, client blockv1.BlockAPIClient, request *blockv1.DownloadRequest) ([]byte, error) {
+	stream, err := client.Download(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &bytes.Buffer{}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return data.Bytes(), nil
+		} else if err != nil {
+			return data.Bytes(), err
+		}
+
+		data.Write(resp.Part)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	data := []byte("0123456789")
+	signature, err := blocks.ComputeSignature("sha256", data)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		hot       bool
+		cold      bool
+		failAfter int
+		offset    int64
+		size      int64
+		expected  []byte
+		code      codes.Code
+	}{
+		{
+			name:     "hot",
+			hot:      true,
+			offset:   2,
+			size:     4,
+			expected: data[2:6],
+		},
+		{
+			name:     "demoted",
+			cold:     true,
+			offset:   2,
+			size:     4,
+			expected: data[2:6],
+		},
+		{
+			name:      "hot fails part way through",
+			hot:       true,
+			cold:      true,
+			failAfter: 3,
+			offset:    1,
+			size:      6,
+			expected:  data[1:7],
+		},
+		{
+			name:      "hot fails part way through the remainder",
+			hot:       true,
+			cold:      true,
+			failAfter: 3,
+			offset:    4,
+			expected:  data[4:],
+		},
+		{
+			name: "missing",
+			code: codes.NotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			tiers := newTiers(ctx, t, Config{})
+			tiers.hot.failAfter = testCase.failAfter
+
+			if testCase.hot {
+				tiers.hot.blocks[signature] = data
+			}
+
+			if testCase.cold {
+				tiers.cold.blocks[signature] = data
+			}
+
+			got, err := download(ctx, tiers.client, &blockv1.DownloadRequest{
+				Signature: signature,
+				Offset:    testCase.offset,
+				Size:      testCase.size,
+			})
+
+			require.Equal(t, testCase.code, status.Code(err))
+			if testCase.code != codes.OK {
+				return
+			}
+
+			require.Equal(t, testCase.expected, got)
+
+			record, err := tiers.blocks.tracker.block(ctx, signature)
+			require.NoError(t, err)
+			require.NotNil(t, record)
+		})
+	}
+}
+
+func TestPromote(t *testing.T) {
+	data := []byte("0123456789")
+	signature, err := blocks.ComputeSignature("sha256", data)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		record   *block
+		promoted bool
+	}{
+		{
+			name:     "untracked",
+			promoted: true,
+		},
+		{
+			name:     "demoted",
+			record:   &block{Cold: true},
+			promoted: true,
+		},
+		{
+			name:   "pinned",
+			record: &block{Cold: true, Pinned: true},
+		},
+		{
+			name:   "already promoted",
+			record: &block{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			tiers := newTiers(ctx, t, Config{Promote: true})
+			tiers.cold.blocks[signature] = data
+
+			tracker := tiers.blocks.tracker
+			if testCase.record != nil {
+				require.NoError(t, tracker.setBlock(ctx, signature, testCase.record))
+			}
+
+			tiers.blocks.promoteBlock(signature)
+
+			require.Equal(t, testCase.promoted, tiers.hot.has(signature))
+			require.True(t, tiers.cold.has(signature))
+
+			if testCase.promoted {
+				record, err := tracker.block(ctx, signature)
+				require.NoError(t, err)
+				require.False(t, record.Cold)
+				require.Equal(t, tiers.clock.Now(), record.Accessed)
+			}
+		})
+	}
+
+	t.Run("read from the cold tier", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tiers := newTiers(ctx, t, Config{Promote: true})
+		require.NoError(t, afs.Upload(ctx, tiers.client, signature, data))
+
+		tiers.clock.Advance(31 * 24 * time.Hour)
+		tiers.mover.config.DemoteAfter = 30 * 24 * time.Hour
+
+		result, err := tiers.mover.Sync(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Demoted)
+		require.False(t, tiers.hot.has(signature))
+
+		got, err := download(ctx, tiers.client, &blockv1.DownloadRequest{Signature: signature})
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+
+		require.Eventually(t, func() bool {
+			return tiers.hot.has(signature)
+		}, time.Second, 10*time.Millisecond)
+	})
+}
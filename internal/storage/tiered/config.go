@@ -0,0 +1,16 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tiered
+
+import (
+	"time"
+)
+
+// Config controls when blocks are moved between the tiers.
+type Config struct {
+	DemoteAfter  time.Duration `json:"demote_after" usage:"how long a block can go unread before it's moved to the cold tier" default:"720h"`
+	OldTagsAfter time.Duration `json:"old_tags_after" usage:"move blocks that are only referenced by tags that haven't been published for this long to the cold tier (disabled when zero)"`
+	Interval     time.Duration `json:"interval" usage:"how often to look for blocks to move to the cold tier" default:"1h"`
+	Promote      bool          `json:"promote" usage:"move blocks back to the hot tier when they're read"`
+}
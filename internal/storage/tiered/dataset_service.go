@@ -0,0 +1,79 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tiered
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/dataset"
+)
+
+// datasetService keeps the manifests on the hot tier, recording when each tag is published so blocks only referenced
+// by old tags can be demoted.
+type datasetService struct {
+	datasetv1.UnsafeDatasetAPIServer
+
+	hot     datasetv1.DatasetAPIServer
+	blocks  *blockService
+	tracker *tracker
+}
+
+func (d *datasetService) List(ctx context.Context, request *datasetv1.ListRequest) (*datasetv1.ListResponse, error) {
+	return d.hot.List(ctx, request)
+}
+
+func (d *datasetService) ListTags(ctx context.Context, request *datasetv1.ListTagsRequest) (*datasetv1.ListTagsResponse, error) {
+	return d.hot.ListTags(ctx, request)
+}
+
+func (d *datasetService) Lookup(ctx context.Context, request *datasetv1.LookupRequest) (*datasetv1.LookupResponse, error) {
+	return d.hot.Lookup(ctx, request)
+}
+
+func (d *datasetService) Diff(ctx context.Context, request *datasetv1.DiffRequest) (*datasetv1.DiffResponse, error) {
+	return d.hot.Diff(ctx, request)
+}
+
+// Publish checks the blocks across both tiers since the hot tier no longer holds the ones that were demoted.
+func (d *datasetService) Publish(ctx context.Context, request *datasetv1.PublishRequest) (*datasetv1.PublishResponse, error) {
+	err := dataset.Validate(request.Dataset)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	missing, err := dataset.MissingBlocks(ctx, d.blocks, request.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		return nil, dataset.MissingBlocksError(missing)
+	}
+
+	resp, err := d.hot.Publish(dataset.WithBlocksVerified(ctx), request)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range request.Tags {
+		err = d.tracker.published(ctx, tag)
+		if err != nil {
+			ctxzap.Extract(ctx).Error("failed to record tag publish", zap.String("tag", tag.Name+":"+tag.Version), zap.Error(err))
+		}
+	}
+
+	return resp, nil
+}
+
+func (d *datasetService) Subscribe(call datasetv1.DatasetAPI_SubscribeServer) error {
+	return d.hot.Subscribe(call)
+}
+
+var _ datasetv1.DatasetAPIServer = &datasetService{}
@@ -0,0 +1,221 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tiered
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/mjpitz/myago/clocks"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+)
+
+// pass summarizes a pass over the blocks on the hot tier.
+type pass struct {
+	Demoted int
+	Bytes   int64
+	Failed  int
+}
+
+// Decide returns whether a block on the hot tier should be moved to the cold tier. Blocks that are only referenced by
+// old tags are pinned to the cold tier so reading them doesn't promote them again.
+func (c Config) Decide(accessed, now time.Time, referenced, recent bool) (demote, pinned bool) {
+	switch {
+	case c.OldTagsAfter > 0 && referenced && !recent:
+		return true, true
+	case c.DemoteAfter > 0 && now.Sub(accessed) >= c.DemoteAfter:
+		return true, false
+	}
+
+	return false, false
+}
+
+// mover moves blocks from the hot tier to the cold tier.
+type mover struct {
+	config   Config
+	hot      blockv1.BlockAPIClient
+	deleter  Deleter
+	cold     blockv1.BlockAPIClient
+	datasets datasetv1.DatasetAPIServer
+	tracker  *tracker
+}
+
+// referenced returns the blocks referenced by any tag and the blocks referenced by tags published since the provided
+// time.
+func (m *mover) referenced(ctx context.Context, since time.Time) (all, recent map[string]bool, err error) {
+	all = make(map[string]bool)
+	recent = make(map[string]bool)
+
+	datasets, err := m.datasets.List(ctx, &datasetv1.ListRequest{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list datasets: %w", err)
+	}
+
+	for _, ds := range datasets.Datasets {
+		tags, err := m.datasets.ListTags(ctx, &datasetv1.ListTagsRequest{Name: ds.Name})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list tags for %s: %w", ds.Name, err)
+		}
+
+		for _, tag := range tags.Tags {
+			resp, err := m.datasets.Lookup(ctx, &datasetv1.LookupRequest{Tag: tag})
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to lookup %s:%s: %w", tag.Name, tag.Version, err)
+			}
+
+			published, err := m.tracker.publishedAt(ctx, tag)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, signature := range resp.Dataset.Blocks {
+				all[signature] = true
+
+				if !published.Before(since) {
+					recent[signature] = true
+				}
+			}
+		}
+	}
+
+	return all, recent, nil
+}
+
+// demote copies the block to the cold tier before removing it from the hot tier. The block is left on the hot tier if
+// it was used since the provided count was taken.
+func (m *mover) demote(ctx context.Context, signature string, used uint64) (int64, error) {
+	data, err := read(ctx, m.hot, signature)
+	if status.Code(err) == codes.NotFound {
+		// already removed from the hot tier by a previous pass that failed to record it
+		_, err = m.cold.Lookup(ctx, &blockv1.LookupRequest{Signature: signature})
+		return 0, err
+	} else if err != nil {
+		return 0, err
+	}
+
+	err = afs.Upload(ctx, m.cold, signature, data)
+	if err != nil {
+		return 0, err
+	}
+
+	if m.tracker.used(signature) != used {
+		return 0, fmt.Errorf("block was used while being demoted")
+	}
+
+	err = m.deleter.Delete(ctx, signature)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(data)), nil
+}
+
+// Sync moves every block that's due to the cold tier. Blocks on the hot tier that haven't been seen before are treated
+// as if they were just read.
+func (m *mover) Sync(ctx context.Context) (*pass, error) {
+	logger := ctxzap.Extract(ctx)
+	now := m.tracker.clock.Now()
+
+	all, recent, err := m.referenced(ctx, now.Add(-m.config.OldTagsAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	for signature := range all {
+		record, err := m.tracker.block(ctx, signature)
+		if err == nil && record == nil {
+			err = m.tracker.setBlock(ctx, signature, &block{Accessed: now})
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signatures, err := m.tracker.blocks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &pass{}
+	for _, signature := range signatures {
+		// taken before the record is read so any use after deciding keeps the block on the hot tier
+		used := m.tracker.used(signature)
+
+		record, err := m.tracker.block(ctx, signature)
+		if err != nil || record == nil {
+			continue
+		}
+
+		if record.Cold {
+			// blocks referenced by a tag that was published again can be promoted when read
+			if record.Pinned && recent[signature] {
+				record.Pinned = false
+				_ = m.tracker.setBlock(ctx, signature, record)
+			}
+
+			continue
+		}
+
+		demote, pinned := m.config.Decide(record.Accessed, now, all[signature], recent[signature])
+		if !demote {
+			continue
+		}
+
+		n, err := m.demote(ctx, signature, used)
+		if err != nil {
+			logger.Warn("failed to demote block", zap.String("signature", signature), zap.Error(err))
+			result.Failed++
+			continue
+		}
+
+		record.Cold = true
+		record.Pinned = pinned
+
+		err = m.tracker.setBlock(ctx, signature, record)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Demoted++
+		result.Bytes += n
+	}
+
+	return result, nil
+}
+
+// Run periodically moves blocks to the cold tier until the context is cancelled.
+func (m *mover) Run(ctx context.Context, interval time.Duration) {
+	logger := ctxzap.Extract(ctx)
+	clock := clocks.Extract(ctx)
+
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+
+		result, err := m.Sync(ctx)
+		if err != nil {
+			logger.Error("failed to demote blocks", zap.Error(err))
+		} else {
+			logger.Info("demoted blocks",
+				zap.Int("demoted", result.Demoted),
+				zap.Int("failed", result.Failed),
+				zap.Int64("bytes", result.Bytes))
+		}
+	}
+}
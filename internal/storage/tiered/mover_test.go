@@ -0,0 +1,191 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tiered
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/afs"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+)
+
+func TestDecide(t *testing.T) {
+	now := time.Date(2022, 1, 31, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	testCases := []struct {
+		name       string
+		config     Config
+		accessed   time.Time
+		referenced bool
+		recent     bool
+		demote     bool
+		pinned     bool
+	}{
+		{
+			name:     "recently read",
+			config:   Config{DemoteAfter: 30 * day},
+			accessed: now.Add(-day),
+		},
+		{
+			name:     "not read",
+			config:   Config{DemoteAfter: 30 * day},
+			accessed: now.Add(-30 * day),
+			demote:   true,
+		},
+		{
+			name:       "old tags disabled",
+			config:     Config{DemoteAfter: 30 * day},
+			accessed:   now.Add(-day),
+			referenced: true,
+		},
+		{
+			name:       "only old tags",
+			config:     Config{DemoteAfter: 30 * day, OldTagsAfter: 7 * day},
+			accessed:   now.Add(-day),
+			referenced: true,
+			demote:     true,
+			pinned:     true,
+		},
+		{
+			name:       "recent tag",
+			config:     Config{DemoteAfter: 30 * day, OldTagsAfter: 7 * day},
+			accessed:   now.Add(-day),
+			referenced: true,
+			recent:     true,
+		},
+		{
+			name:     "unreferenced",
+			config:   Config{DemoteAfter: 30 * day, OldTagsAfter: 7 * day},
+			accessed: now.Add(-day),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			demote, pinned := testCase.config.Decide(testCase.accessed, now, testCase.referenced, testCase.recent)
+
+			require.Equal(t, testCase.demote, demote)
+			require.Equal(t, testCase.pinned, pinned)
+		})
+	}
+}
+
+func TestDemote(t *testing.T) {
+	day := 24 * time.Hour
+	data := []byte("0123456789")
+
+	signature, err := blocks.ComputeSignature("sha256", data)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		config  Config
+		advance time.Duration
+		publish bool // a tag references the block
+		removed bool // a previous pass removed the block from the hot tier without recording it
+		used    bool // the block is read while it's being copied to the cold tier
+
+		expected *pass
+		cold     bool
+		pinned   bool
+	}{
+		{
+			name:     "recently read",
+			config:   Config{DemoteAfter: 30 * day},
+			advance:  29 * day,
+			expected: &pass{},
+		},
+		{
+			name:     "not read",
+			config:   Config{DemoteAfter: 30 * day},
+			advance:  30 * day,
+			expected: &pass{Demoted: 1, Bytes: int64(len(data))},
+			cold:     true,
+		},
+		{
+			name:     "only old tags",
+			config:   Config{DemoteAfter: 30 * day, OldTagsAfter: 7 * day},
+			advance:  8 * day,
+			publish:  true,
+			expected: &pass{Demoted: 1, Bytes: int64(len(data))},
+			cold:     true,
+			pinned:   true,
+		},
+		{
+			name:     "already removed",
+			config:   Config{DemoteAfter: 30 * day},
+			advance:  30 * day,
+			removed:  true,
+			expected: &pass{Demoted: 1},
+			cold:     true,
+		},
+		{
+			// reads within the access resolution don't update the access time, only the use count
+			name:     "used while being demoted",
+			config:   Config{DemoteAfter: accessResolution / 2},
+			advance:  accessResolution / 2,
+			used:     true,
+			expected: &pass{Failed: 1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			tiers := newTiers(ctx, t, testCase.config)
+			require.NoError(t, afs.Upload(ctx, tiers.client, signature, data))
+
+			if testCase.publish {
+				_, err := tiers.datasets.Publish(ctx, &datasetv1.PublishRequest{
+					Dataset: &datasetv1.Dataset{
+						BlockSize: int32(len(data)),
+						Blocks:    []string{signature},
+						Files:     []*datasetv1.File{{Name: "data.bin", Size: int64(len(data))}},
+					},
+					Tags: []*datasetv1.Tag{{Name: "ds", Version: "v1"}},
+				})
+				require.NoError(t, err)
+			}
+
+			if testCase.removed {
+				tiers.cold.blocks[signature] = data
+				delete(tiers.hot.blocks, signature)
+			}
+
+			if testCase.used {
+				tiers.cold.uploading = func(signature string) {
+					_, err := download(ctx, tiers.client, &blockv1.DownloadRequest{Signature: signature})
+					require.NoError(t, err)
+				}
+			}
+
+			tiers.clock.Advance(testCase.advance)
+
+			result, err := tiers.mover.Sync(ctx)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, result)
+
+			record, err := tiers.mover.tracker.block(ctx, signature)
+			require.NoError(t, err)
+			require.Equal(t, testCase.cold, record.Cold)
+			require.Equal(t, testCase.pinned, record.Pinned)
+
+			require.Equal(t, !testCase.cold, tiers.hot.has(signature))
+
+			// the block can be read from either tier
+			got, err := download(ctx, tiers.client, &blockv1.DownloadRequest{Signature: signature})
+			require.NoError(t, err)
+			require.Equal(t, data, got)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tiered
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mjpitz/myago/clocks"
+
+	adminv1 "github.com/mjpitz/aetherfs/api/aetherfs/admin/v1"
+	blockv1 "github.com/mjpitz/aetherfs/api/aetherfs/block/v1"
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/storage/inprocess"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+)
+
+// Deleter is implemented by block stores that can remove blocks. The hot tier must support it so blocks can be moved
+// off of it.
+type Deleter interface {
+	Delete(ctx context.Context, signature string) error
+}
+
+// Tier is one of the drivers blocks are stored on.
+type Tier struct {
+	BlockAPIServer   blockv1.BlockAPIServer
+	DatasetAPIServer datasetv1.DatasetAPIServer
+}
+
+// ObtainStores writes blocks to the hot tier and moves them to the cold tier once they're no longer being read.
+// Manifests are always kept on the hot tier. The mover runs in the background until the context is cancelled.
+func ObtainStores(ctx context.Context, cfg Config, hot, cold Tier) (blockv1.BlockAPIServer, datasetv1.DatasetAPIServer, adminv1.AdminAPIServer, error) {
+	db := local.Extract(ctx)
+	deleter, ok := hot.BlockAPIServer.(Deleter)
+
+	switch {
+	case db == nil:
+		return nil, nil, nil, fmt.Errorf("tiered driver requires a local database")
+	case !ok:
+		return nil, nil, nil, fmt.Errorf("hot tier does not support deleting blocks")
+	case cfg.Interval <= 0:
+		return nil, nil, nil, fmt.Errorf("interval must be positive")
+	}
+
+	hotClient, err := inprocess.BlockAPI(ctx, hot.BlockAPIServer)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	coldClient, err := inprocess.BlockAPI(ctx, cold.BlockAPIServer)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tracker := &tracker{
+		clock: clocks.Extract(ctx),
+		store: db.Tiers(),
+	}
+
+	blockSvc := &blockService{
+		ctx:     ctx,
+		hot:     hotClient,
+		cold:    coldClient,
+		tracker: tracker,
+		promote: cfg.Promote,
+	}
+
+	datasetSvc := &datasetService{
+		hot:     hot.DatasetAPIServer,
+		blocks:  blockSvc,
+		tracker: tracker,
+	}
+
+	m := &mover{
+		config:   cfg,
+		hot:      hotClient,
+		deleter:  deleter,
+		cold:     coldClient,
+		datasets: hot.DatasetAPIServer,
+		tracker:  tracker,
+	}
+
+	go m.Run(ctx, cfg.Interval)
+
+	return blockSvc, datasetSvc, &adminService{}, nil
+}
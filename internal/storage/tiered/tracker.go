@@ -0,0 +1,140 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tiered
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/jonboulle/clockwork"
+
+	datasetv1 "github.com/mjpitz/aetherfs/api/aetherfs/dataset/v1"
+	"github.com/mjpitz/aetherfs/internal/storage/local"
+)
+
+// accessResolution limits how often reads of the same block are written to the database. Reads within it don't update
+// the access time, so uses are counted in memory to tell whether a block is being read right now.
+const accessResolution = time.Hour
+
+// block records when a block was last read and which tier it's on.
+type block struct {
+	Accessed time.Time `json:"accessed"`
+	Cold     bool      `json:"cold,omitempty"`
+
+	// Pinned blocks were moved to the cold tier because only old tags reference them. They aren't promoted when read.
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// tag records when a tag was last published.
+type tag struct {
+	Published time.Time `json:"published"`
+}
+
+// tracker persists the information used to decide which tier a block belongs on. Requests don't carry the clock, so
+// the one the driver was created with is used.
+type tracker struct {
+	clock clockwork.Clock
+	store *local.Store
+
+	mu   sync.Mutex
+	uses map[string]uint64
+}
+
+// used returns the number of times the block was read or written since the driver started.
+func (t *tracker) used(signature string) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.uses[signature]
+}
+
+func (t *tracker) block(ctx context.Context, signature string) (*block, error) {
+	record := &block{}
+
+	err := t.store.Get(ctx, "blocks/"+signature, record)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+
+	return record, err
+}
+
+func (t *tracker) setBlock(ctx context.Context, signature string, record *block) error {
+	return t.store.Put(ctx, "blocks/"+signature, record)
+}
+
+// blocks returns the signature of every block being tracked.
+func (t *tracker) blocks(ctx context.Context) ([]string, error) {
+	keys, err := t.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	signatures := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, "blocks/") {
+			signatures = append(signatures, strings.TrimPrefix(key, "blocks/"))
+		}
+	}
+
+	return signatures, nil
+}
+
+// accessed records that the block was just read or written on the provided tier.
+func (t *tracker) accessed(ctx context.Context, signature string, cold bool) error {
+	t.mu.Lock()
+	if t.uses == nil {
+		t.uses = make(map[string]uint64)
+	}
+	t.uses[signature]++
+	t.mu.Unlock()
+
+	now := t.clock.Now()
+
+	record, err := t.block(ctx, signature)
+	switch {
+	case err != nil:
+		return err
+	case record == nil:
+		record = &block{}
+	case record.Cold == cold && now.Sub(record.Accessed) < accessResolution:
+		return nil
+	}
+
+	record.Accessed = now
+	if !cold {
+		record.Cold = false
+		record.Pinned = false
+	}
+
+	return t.setBlock(ctx, signature, record)
+}
+
+// published records that the tag was just published.
+func (t *tracker) published(ctx context.Context, ref *datasetv1.Tag) error {
+	return t.store.Put(ctx, "tags/"+ref.Name+":"+ref.Version, &tag{
+		Published: t.clock.Now(),
+	})
+}
+
+// publishedAt returns when the tag was last published. Tags published before the driver started tracking them are
+// treated as if they were published now.
+func (t *tracker) publishedAt(ctx context.Context, ref *datasetv1.Tag) (time.Time, error) {
+	record := &tag{}
+
+	err := t.store.Get(ctx, "tags/"+ref.Name+":"+ref.Version, record)
+	switch {
+	case errors.Is(err, badger.ErrKeyNotFound):
+		record.Published = t.clock.Now()
+		return record.Published, t.store.Put(ctx, "tags/"+ref.Name+":"+ref.Version, record)
+	case err != nil:
+		return time.Time{}, err
+	}
+
+	return record.Published, nil
+}
@@ -0,0 +1,14 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tracker
+
+import (
+	"time"
+)
+
+// Config defines the options available for the hub's peer tracker.
+type Config struct {
+	Enable bool          `json:"enable" usage:"enable the tracker API, helping agents find peers to download blocks from"`
+	TTL    time.Duration `json:"ttl"    usage:"how long an agent's announcement is kept" default:"5m"`
+}
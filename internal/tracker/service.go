@@ -0,0 +1,162 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tracker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	trackerv1 "github.com/mjpitz/aetherfs/api/aetherfs/tracker/v1"
+	"github.com/mjpitz/myago/clocks"
+)
+
+// MaxSignatures is the largest number of signatures that can be sent in a single announcement or lookup. Signatures are
+// at most 104 characters, so along with MaxHolders this keeps every message well under gRPC's default 4 MiB limit.
+const MaxSignatures = 8192
+
+// MaxHolders is the largest number of peers returned for each block by a lookup.
+const MaxHolders = 4
+
+// DefaultTTL is used when the service isn't configured with a ttl.
+const DefaultTTL = 5 * time.Minute
+
+type peer struct {
+	expires    time.Time
+	signatures []string
+}
+
+// Service keeps track of which agents hold which blocks in memory. Since agents announce themselves periodically, the
+// state is rebuilt shortly after a restart.
+type Service struct {
+	trackerv1.UnsafeTrackerAPIServer
+
+	TTL time.Duration
+
+	mu      sync.Mutex
+	peers   map[string]*peer
+	holders map[string]map[string]bool
+}
+
+func (s *Service) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return DefaultTTL
+	}
+
+	return s.TTL
+}
+
+// forget removes the signatures advertised by the address. The caller must hold the lock.
+func (s *Service) forget(address string) {
+	p, ok := s.peers[address]
+	if !ok {
+		return
+	}
+
+	for _, signature := range p.signatures {
+		delete(s.holders[signature], address)
+
+		if len(s.holders[signature]) == 0 {
+			delete(s.holders, signature)
+		}
+	}
+
+	delete(s.peers, address)
+}
+
+// expire forgets every peer whose announcement has lapsed. The caller must hold the lock.
+func (s *Service) expire(now time.Time) {
+	for address, p := range s.peers {
+		if !now.Before(p.expires) {
+			s.forget(address)
+		}
+	}
+}
+
+func (s *Service) Announce(ctx context.Context, request *trackerv1.AnnounceRequest) (*trackerv1.AnnounceResponse, error) {
+	switch {
+	case request.Address == "":
+		return nil, status.Error(codes.InvalidArgument, "missing address")
+	case len(request.Signatures) > MaxSignatures:
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d signatures can be announced", MaxSignatures)
+	}
+
+	now := clocks.Extract(ctx).Now()
+	ttl := s.ttl()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.peers == nil {
+		s.peers = make(map[string]*peer)
+		s.holders = make(map[string]map[string]bool)
+	}
+
+	s.expire(now)
+	s.forget(request.Address)
+
+	s.peers[request.Address] = &peer{
+		expires:    now.Add(ttl),
+		signatures: request.Signatures,
+	}
+
+	for _, signature := range request.Signatures {
+		if s.holders[signature] == nil {
+			s.holders[signature] = make(map[string]bool)
+		}
+
+		s.holders[signature][request.Address] = true
+	}
+
+	return &trackerv1.AnnounceResponse{
+		TtlSeconds: int64(ttl / time.Second),
+	}, nil
+}
+
+func (s *Service) Locate(ctx context.Context, request *trackerv1.LocateRequest) (*trackerv1.LocateResponse, error) {
+	if len(request.Signatures) > MaxSignatures {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d signatures can be located", MaxSignatures)
+	}
+
+	now := clocks.Extract(ctx).Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(now)
+
+	resp := &trackerv1.LocateResponse{}
+
+	for _, signature := range request.Signatures {
+		holders := s.holders[signature]
+		if len(holders) == 0 {
+			continue
+		}
+
+		addresses := make([]string, 0, len(holders))
+		for address := range holders {
+			addresses = append(addresses, address)
+		}
+
+		// map iteration order is random, so blocks held by more peers than can be returned spread their load
+		if len(addresses) > MaxHolders {
+			addresses = addresses[:MaxHolders]
+		}
+
+		sort.Strings(addresses)
+
+		resp.Holders = append(resp.Holders, &trackerv1.Holders{
+			Signature: signature,
+			Addresses: addresses,
+		})
+	}
+
+	return resp, nil
+}
+
+var _ trackerv1.TrackerAPIServer = &Service{}
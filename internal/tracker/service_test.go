@@ -0,0 +1,108 @@
+// Copyright (C) The AetherFS Authors - All Rights Reserved
+// See LICENSE for more information.
+
+package tracker_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	trackerv1 "github.com/mjpitz/aetherfs/api/aetherfs/tracker/v1"
+	"github.com/mjpitz/aetherfs/internal/blocks"
+	"github.com/mjpitz/aetherfs/internal/tracker"
+	"github.com/mjpitz/myago/clocks"
+)
+
+func TestService(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	ctx := clocks.ToContext(context.Background(), clock)
+
+	svc := &tracker.Service{TTL: time.Minute}
+
+	locate := func(signatures ...string) []*trackerv1.Holders {
+		resp, err := svc.Locate(ctx, &trackerv1.LocateRequest{Signatures: signatures})
+		require.NoError(t, err)
+		return resp.Holders
+	}
+
+	_, err := svc.Announce(ctx, &trackerv1.AnnounceRequest{Signatures: []string{"a"}})
+	require.Error(t, err)
+
+	resp, err := svc.Announce(ctx, &trackerv1.AnnounceRequest{Address: "10.0.0.1:8080", Signatures: []string{"a", "b"}})
+	require.NoError(t, err)
+	require.Equal(t, int64(60), resp.TtlSeconds)
+
+	clock.Advance(30 * time.Second)
+
+	_, err = svc.Announce(ctx, &trackerv1.AnnounceRequest{Address: "10.0.0.2:8080", Signatures: []string{"b", "c"}})
+	require.NoError(t, err)
+
+	require.Equal(t, []*trackerv1.Holders{
+		{Signature: "a", Addresses: []string{"10.0.0.1:8080"}},
+		{Signature: "b", Addresses: []string{"10.0.0.1:8080", "10.0.0.2:8080"}},
+		{Signature: "c", Addresses: []string{"10.0.0.2:8080"}},
+	}, locate("a", "b", "c", "d"))
+
+	// announcements replace what was previously advertised
+	_, err = svc.Announce(ctx, &trackerv1.AnnounceRequest{Address: "10.0.0.2:8080", Signatures: []string{"c"}})
+	require.NoError(t, err)
+
+	require.Equal(t, []*trackerv1.Holders{
+		{Signature: "b", Addresses: []string{"10.0.0.1:8080"}},
+	}, locate("b"))
+
+	// the first peer hasn't announced again
+	clock.Advance(30 * time.Second)
+
+	require.Empty(t, locate("a", "b"))
+	require.Equal(t, []*trackerv1.Holders{
+		{Signature: "c", Addresses: []string{"10.0.0.2:8080"}},
+	}, locate("c"))
+}
+
+func TestMessageSize(t *testing.T) {
+	ctx := clocks.ToContext(context.Background(), clockwork.NewFakeClock())
+	svc := &tracker.Service{}
+
+	// grpc's default limit on received messages
+	const limit = 4 << 20
+
+	signatures := make([]string, tracker.MaxSignatures)
+	for i := range signatures {
+		signature, err := blocks.ComputeSignature("sha512", []byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+
+		signatures[i] = signature
+	}
+
+	for i := 0; i <= tracker.MaxHolders; i++ {
+		request := &trackerv1.AnnounceRequest{
+			Address:    fmt.Sprintf("%s-%d.example.com:8080", strings.Repeat("a", 40), i),
+			Signatures: signatures,
+		}
+
+		require.Less(t, proto.Size(request), limit)
+
+		_, err := svc.Announce(ctx, request)
+		require.NoError(t, err)
+	}
+
+	request := &trackerv1.LocateRequest{Signatures: signatures}
+	require.Less(t, proto.Size(request), limit)
+
+	resp, err := svc.Locate(ctx, request)
+	require.NoError(t, err)
+	require.Less(t, proto.Size(resp), limit)
+	require.Len(t, resp.Holders, tracker.MaxSignatures)
+
+	for _, holders := range resp.Holders {
+		require.Len(t, holders.Addresses, tracker.MaxHolders)
+	}
+}
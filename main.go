@@ -18,6 +18,7 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/mjpitz/aetherfs/internal/commands"
+	"github.com/mjpitz/aetherfs/internal/output"
 	"github.com/mjpitz/aetherfs/internal/storage/local"
 	"github.com/mjpitz/myago/authors"
 	"github.com/mjpitz/myago/dirset"
@@ -34,7 +35,8 @@ var commit = "none"
 var date = time.Now().Format(time.RFC3339)
 
 type GlobalConfig struct {
-	Log zaputil.Config `json:"log"`
+	Log    zaputil.Config `json:"log"`
+	Output string         `json:"output" usage:"how command results are written (text, json, or yaml)" default:"text"`
 }
 
 func main() {
@@ -51,13 +53,29 @@ func main() {
 		Version:   fmt.Sprintf("%s (%s)", version, commit),
 		Commands: []*cli.Command{
 			commands.Auth(),
+			commands.Cat(),
+			commands.Diff(),
+			commands.Du(),
+			commands.Exec(),
+			commands.Fsck(),
+			commands.Inspect(),
+			commands.Ls(),
+			commands.Mirror(),
+			commands.Prune(),
 			commands.Pull(),
 			commands.Push(),
 			commands.Run(),
+			commands.Verify(),
 			commands.Version(),
 		},
 		Flags: flagset.Extract(cfg),
 		Before: func(ctx *cli.Context) (err error) {
+			format, err := output.ParseFormat(cfg.Output)
+			if err != nil {
+				return err
+			}
+
+			ctx.Context = output.ToContext(ctx.Context, format)
 			ctx.Context = zaputil.Setup(ctx.Context, cfg.Log)
 			ctx.Context = lifecycle.Setup(ctx.Context)
 
@@ -84,6 +102,7 @@ func main() {
 
 			return nil
 		},
+		ExitErrHandler:       func(ctx *cli.Context, err error) {},
 		Compiled:             compiled,
 		Authors:              authors.Parse(authorsFileContents),
 		Copyright:            fmt.Sprintf("Copyright %d The AetherFS Authors - All Rights Reserved\n", compiled.Year()),
@@ -100,6 +119,13 @@ func main() {
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		fmt.Println(err)
+		if err.Error() != "" {
+			fmt.Println(err)
+		}
+
+		// exit codes are reported once After has had a chance to release resources
+		if exitCoder, ok := err.(cli.ExitCoder); ok {
+			os.Exit(exitCoder.ExitCode())
+		}
 	}
 }